@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"movie-ticket-booking/internal/config"
+	"movie-ticket-booking/internal/database"
+	"movie-ticket-booking/internal/migrate"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/migrations"
+)
+
+const usage = `Usage: migrate [-steps N] <command>
+
+Commands:
+  up        apply pending migrations (all unless -steps is set)
+  down      revert the last migration (or the last -steps migrations)
+  status    list migrations and when they were applied
+  version   print the current schema version
+  verify    check that the GORM models match the migrated schema
+`
+
+func main() {
+	steps := flag.Int("steps", 0, "number of migrations to apply or revert")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadDatabase()
+	if err != nil {
+		fail(err)
+	}
+
+	postgresDB, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer postgresDB.Close()
+
+	sqlDB, err := postgresDB.DB.DB()
+	if err != nil {
+		fail(err)
+	}
+	migrator, err := migrate.New(sqlDB, migrations.FS)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx, *steps)
+		for _, m := range applied {
+			fmt.Printf("applied %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fail(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fail(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no migrations to revert")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fail(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(version)
+	case "verify":
+		problems, err := migrate.Verify(ctx, postgresDB.DB, models.All()...)
+		if err != nil {
+			fail(err)
+		}
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "%d mismatches between models and schema\n", len(problems))
+			os.Exit(1)
+		}
+		fmt.Println("models match the schema")
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"movie-ticket-booking/internal/config"
+	"movie-ticket-booking/internal/database"
+	"movie-ticket-booking/internal/models"
+)
+
+func main() {
+	opts := options{}
+	flag.Uint64Var(&opts.Seed, "seed", 42, "random seed; the same seed produces the same data")
+	flag.IntVar(&opts.Movies, "movies", 20, "number of movies")
+	flag.IntVar(&opts.Halls, "halls", 5, "number of halls")
+	flag.IntVar(&opts.Days, "days", 7, "number of days to schedule showtimes for, starting tomorrow")
+	flag.IntVar(&opts.ShowsPerDay, "shows-per-day", 4, "maximum showtimes per hall and day")
+	flag.IntVar(&opts.Users, "users", 50, "number of users")
+	flag.IntVar(&opts.Bookings, "bookings", 200, "number of bookings")
+	flag.StringVar(&opts.Password, "password", "password123", "password of every generated user")
+	reset := flag.Bool("reset", false, "delete all existing data before seeding")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+	postgresDB, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer postgresDB.Close()
+
+	ctx := context.Background()
+	db := postgresDB.DB.WithContext(ctx)
+
+	if *reset {
+		if err := resetData(db); err != nil {
+			fail(err)
+		}
+	} else {
+		var count int64
+		if err := db.Model(&models.Movie{}).Count(&count).Error; err != nil {
+			fail(err)
+		}
+		if count > 0 {
+			fail(fmt.Errorf("database already contains data, run with -reset to replace it"))
+		}
+	}
+
+	// Showtimes start tomorrow in the cinema's timezone
+	now := time.Now().In(cfg.Location())
+	opts.Start = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, cfg.Location())
+	opts.TrailerDuration = cfg.Showtime.TrailerDuration
+	opts.CleaningBuffer = cfg.Showtime.CleaningBuffer
+
+	stats, err := newSeeder(db, opts).run()
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("seeded %d movies, %d halls, %d showtimes, %d seats, %d users (plus admin@example.com) and %d bookings\n",
+		stats.movies, stats.halls, stats.showtimes, stats.seats, stats.users, stats.bookings)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"movie-ticket-booking/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type options struct {
+	Seed        uint64
+	Movies      int
+	Halls       int
+	Days        int
+	ShowsPerDay int
+	Users       int
+	Bookings    int
+	Password    string
+	Start       time.Time
+
+	TrailerDuration time.Duration
+	CleaningBuffer  time.Duration
+}
+
+type stats struct {
+	movies, halls, showtimes, seats, users, bookings int
+}
+
+// seeder generates demo data. Every random choice comes from a single
+// generator seeded with options.Seed so that runs are reproducible.
+type seeder struct {
+	db    *gorm.DB
+	opts  options
+	rng   *rand.Rand
+	stats stats
+
+	movies    []models.Movie
+	halls     []models.Hall
+	showtimes []models.ShowTime
+	users     []models.User
+	// seats of each showtime indexed by row, used to pick bookable blocks
+	seats map[uint][][]*models.Seat
+}
+
+func newSeeder(db *gorm.DB, opts options) *seeder {
+	return &seeder{
+		db:    db,
+		opts:  opts,
+		rng:   rand.New(rand.NewPCG(opts.Seed, opts.Seed)),
+		seats: make(map[uint][][]*models.Seat),
+	}
+}
+
+func (s *seeder) run() (stats, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		steps := []func(tx *gorm.DB) error{
+			s.seedMovies,
+			s.seedHalls,
+			s.seedShowtimes,
+			s.seedUsers,
+			s.seedBookings,
+		}
+		for _, step := range steps {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return s.stats, err
+}
+
+var (
+	titleAdjectives = []string{"Silent", "Crimson", "Last", "Hidden", "Broken", "Golden", "Midnight", "Frozen", "Electric", "Forgotten", "Wild", "Distant"}
+	titleNouns      = []string{"Harbor", "Empire", "Horizon", "Garden", "Signal", "River", "Kingdom", "Mirror", "Voyage", "Orchard", "Frontier", "Echo"}
+	genres          = []string{"Action", "Comedy", "Drama", "Horror", "Sci-Fi", "Romance", "Thriller", "Animation", "Documentary"}
+	firstNames      = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Casey", "Riley", "Jamie", "Avery", "Quinn", "Linh", "Minh", "An", "Hoa"}
+	lastNames       = []string{"Nguyen", "Smith", "Tran", "Garcia", "Le", "Johnson", "Pham", "Brown", "Hoang", "Martin"}
+	prices          = []float64{8, 10, 12, 15}
+)
+
+func (s *seeder) seedMovies(tx *gorm.DB) error {
+	used := make(map[string]bool)
+	for i := 0; i < s.opts.Movies; i++ {
+		title := fmt.Sprintf("The %s %s", pick(s.rng, titleAdjectives), pick(s.rng, titleNouns))
+		if used[title] {
+			title = fmt.Sprintf("%s %d", title, i+1)
+		}
+		used[title] = true
+
+		genre := pick(s.rng, genres)
+		movie := models.Movie{
+			Title:       title,
+			Description: fmt.Sprintf("A %s film about %s.", strings.ToLower(genre), strings.ToLower(title)),
+			Duration:    85 + s.rng.IntN(96),
+			Genre:       genre,
+			ReleaseDate: s.opts.Start.AddDate(0, 0, -s.rng.IntN(730)),
+			PosterURL:   fmt.Sprintf("https://example.com/posters/%s.jpg", strings.ToLower(strings.ReplaceAll(title, " ", "-"))),
+		}
+		if err := tx.Create(&movie).Error; err != nil {
+			return fmt.Errorf("failed to create movie: %w", err)
+		}
+		s.movies = append(s.movies, movie)
+	}
+	s.stats.movies = len(s.movies)
+	return nil
+}
+
+func (s *seeder) seedHalls(tx *gorm.DB) error {
+	for i := 0; i < s.opts.Halls; i++ {
+		rows := 8 + s.rng.IntN(7)
+		seatsPerRow := 10 + s.rng.IntN(11)
+
+		hall := models.Hall{
+			Name:     fmt.Sprintf("Hall %c", 'A'+i%26),
+			Capacity: rows * seatsPerRow,
+		}
+		for r := 0; r < rows; r++ {
+			for n := 1; n <= seatsPerRow; n++ {
+				hall.Layout = append(hall.Layout, models.HallSeat{
+					RowNumber:  rowName(r),
+					SeatNumber: n,
+					Kind:       seatKind(r == rows-1, n, seatsPerRow),
+				})
+			}
+		}
+		if err := tx.Create(&hall).Error; err != nil {
+			return fmt.Errorf("failed to create hall: %w", err)
+		}
+		s.halls = append(s.halls, hall)
+	}
+	s.stats.halls = len(s.halls)
+	return nil
+}
+
+// seatKind places a wheelchair space with a companion seat beside it at both
+// ends of the back row
+func seatKind(backRow bool, n, seatsPerRow int) string {
+	switch {
+	case !backRow:
+		return models.SeatKindStandard
+	case n == 1 || n == seatsPerRow:
+		return models.SeatKindWheelchair
+	case n == 2 || n == seatsPerRow-1:
+		return models.SeatKindCompanion
+	}
+	return models.SeatKindStandard
+}
+
+// seedShowtimes schedules movies back to back in every hall from 10:00. Like
+// scheduled showtimes, the end time includes trailers and the cleaning buffer;
+// the next show starts at the following quarter hour.
+func (s *seeder) seedShowtimes(tx *gorm.DB) error {
+	if len(s.movies) == 0 {
+		return nil
+	}
+	for day := 0; day < s.opts.Days; day++ {
+		opening := s.opts.Start.AddDate(0, 0, day).Add(10 * time.Hour)
+		for _, hall := range s.halls {
+			start := opening
+			for show := 0; show < s.opts.ShowsPerDay; show++ {
+				movie := s.movies[s.rng.IntN(len(s.movies))]
+				end := start.Add(s.opts.TrailerDuration + time.Duration(movie.Duration)*time.Minute + s.opts.CleaningBuffer)
+
+				price := pick(s.rng, prices)
+				if start.Hour() >= 18 {
+					price += 3
+				}
+
+				showtime := models.ShowTime{
+					MovieID:   movie.ID,
+					HallID:    hall.ID,
+					StartTime: start,
+					EndTime:   end,
+					Price:     price,
+					Status:    models.ShowTimeStatusScheduled,
+				}
+				if err := tx.Create(&showtime).Error; err != nil {
+					return fmt.Errorf("failed to create showtime: %w", err)
+				}
+				if err := s.copyLayout(tx, showtime, hall); err != nil {
+					return err
+				}
+				s.showtimes = append(s.showtimes, showtime)
+
+				start = end.Truncate(15 * time.Minute).Add(15 * time.Minute)
+			}
+		}
+	}
+	s.stats.showtimes = len(s.showtimes)
+	return nil
+}
+
+// copyLayout creates the seats of a showtime from the hall layout
+func (s *seeder) copyLayout(tx *gorm.DB, showtime models.ShowTime, hall models.Hall) error {
+	seats := make([]*models.Seat, len(hall.Layout))
+	for i, hallSeat := range hall.Layout {
+		seats[i] = &models.Seat{
+			HallID:     hall.ID,
+			ShowTimeID: showtime.ID,
+			RowNumber:  hallSeat.RowNumber,
+			SeatNumber: hallSeat.SeatNumber,
+			Kind:       hallSeat.Kind,
+			Status:     models.SeatStatusAvailable,
+		}
+	}
+	if err := tx.CreateInBatches(seats, 500).Error; err != nil {
+		return fmt.Errorf("failed to create seats: %w", err)
+	}
+
+	var rows [][]*models.Seat
+	for _, seat := range seats {
+		if len(rows) == 0 || rows[len(rows)-1][0].RowNumber != seat.RowNumber {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], seat)
+	}
+	s.seats[showtime.ID] = rows
+	s.stats.seats += len(seats)
+	return nil
+}
+
+func (s *seeder) seedUsers(tx *gorm.DB) error {
+	// Hashing once keeps seeding fast; every user shares the same password
+	hash, err := bcrypt.GenerateFromPassword([]byte(s.opts.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	admin := models.User{
+		Email:    "admin@example.com",
+		Password: string(hash),
+		Name:     "Admin",
+		Phone:    "+84900000000",
+		Role:     models.RoleAdmin,
+	}
+	if err := tx.Create(&admin).Error; err != nil {
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	for i := 1; i <= s.opts.Users; i++ {
+		user := models.User{
+			Email:    fmt.Sprintf("user%03d@example.com", i),
+			Password: string(hash),
+			Name:     fmt.Sprintf("%s %s", pick(s.rng, firstNames), pick(s.rng, lastNames)),
+			Phone:    fmt.Sprintf("+8490%07d", s.rng.IntN(10_000_000)),
+			Role:     models.RoleCustomer,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		s.users = append(s.users, user)
+	}
+	s.stats.users = len(s.users)
+	return nil
+}
+
+// seedBookings books blocks of adjacent seats for random users. Attempts that
+// find no free block are skipped so that full showtimes don't stall seeding.
+func (s *seeder) seedBookings(tx *gorm.DB) error {
+	if len(s.showtimes) == 0 || len(s.users) == 0 {
+		return nil
+	}
+	for attempt := 0; attempt < s.opts.Bookings*3 && s.stats.bookings < s.opts.Bookings; attempt++ {
+		showtime := s.showtimes[s.rng.IntN(len(s.showtimes))]
+		user := s.users[s.rng.IntN(len(s.users))]
+
+		seats := s.freeBlock(showtime.ID, 1+s.rng.IntN(4))
+		if seats == nil {
+			continue
+		}
+
+		booking := models.Booking{
+			UserID:      user.ID,
+			ShowTimeID:  showtime.ID,
+			TotalAmount: float64(len(seats)) * showtime.Price,
+			Status:      models.BookingStatusConfirmed,
+			BookedAt:    showtime.StartTime.Add(-time.Duration(1+s.rng.IntN(72)) * time.Hour),
+		}
+		for _, seat := range seats {
+			booking.Seats = append(booking.Seats, models.BookingSeat{SeatID: seat.ID, Price: showtime.Price})
+		}
+		if err := tx.Create(&booking).Error; err != nil {
+			return fmt.Errorf("failed to create booking: %w", err)
+		}
+
+		tickets := make([]models.Ticket, len(booking.Seats))
+		for i, bookingSeat := range booking.Seats {
+			tickets[i] = models.Ticket{
+				UserID:      user.ID,
+				BookingID:   &booking.ID,
+				ShowTimeID:  showtime.ID,
+				SeatID:      bookingSeat.SeatID,
+				Status:      models.TicketStatusPaid,
+				BookingCode: fmt.Sprintf("SEED%08d%02d", booking.ID, i+1),
+				Price:       bookingSeat.Price,
+			}
+		}
+		if err := tx.Create(&tickets).Error; err != nil {
+			return fmt.Errorf("failed to issue tickets: %w", err)
+		}
+
+		ids := make([]uint, len(seats))
+		for i, seat := range seats {
+			seat.Status = models.SeatStatusBooked
+			ids[i] = seat.ID
+		}
+		if err := tx.Model(&models.Seat{}).Where("id IN ?", ids).Update("status", models.SeatStatusBooked).Error; err != nil {
+			return fmt.Errorf("failed to book seats: %w", err)
+		}
+		s.stats.bookings++
+	}
+	return nil
+}
+
+// freeBlock returns count adjacent available seats in a random row, or nil
+func (s *seeder) freeBlock(showtimeID uint, count int) []*models.Seat {
+	rows := s.seats[showtimeID]
+	if len(rows) == 0 {
+		return nil
+	}
+	row := rows[s.rng.IntN(len(rows))]
+	if len(row) < count {
+		return nil
+	}
+
+	first := s.rng.IntN(len(row) - count + 1)
+	for i := 0; i < len(row); i++ {
+		start := (first + i) % (len(row) - count + 1)
+		block := row[start : start+count]
+		free := true
+		for _, seat := range block {
+			if seat.Status != models.SeatStatusAvailable {
+				free = false
+				break
+			}
+		}
+		if free {
+			return block
+		}
+	}
+	return nil
+}
+
+// resetData deletes everything except the migration history
+func resetData(db *gorm.DB) error {
+	return db.Exec("TRUNCATE users, movies, halls, oidc_login_states, jobs, outbox_events, webhook_subscriptions, invoice_sequences RESTART IDENTITY CASCADE").Error
+}
+
+func rowName(index int) string {
+	if index < 26 {
+		return string(rune('A' + index))
+	}
+	return string(rune('A'+index/26-1)) + string(rune('A'+index%26))
+}
+
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.IntN(len(items))]
+}
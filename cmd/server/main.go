@@ -1,9 +1,16 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
@@ -11,48 +18,300 @@ import (
 	"movie-ticket-booking/graph/generated"
 	"movie-ticket-booking/internal/config"
 	"movie-ticket-booking/internal/database"
+	"movie-ticket-booking/internal/documents"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/handlers"
+	"movie-ticket-booking/internal/health"
+	"movie-ticket-booking/internal/jobs"
+	"movie-ticket-booking/internal/logging"
+	"movie-ticket-booking/internal/metrics"
 	"movie-ticket-booking/internal/middleware"
+	"movie-ticket-booking/internal/migrate"
+	"movie-ticket-booking/internal/notification"
+	"movie-ticket-booking/internal/payment"
 	"movie-ticket-booking/internal/services"
+	"movie-ticket-booking/internal/tracing"
+	"movie-ticket-booking/migrations"
 )
 
-const defaultPort = "8080"
-
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	// Load configuration from the environment and optional config file
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Structured logging is configured before anything else is logged
+	if err := logging.Setup(os.Stdout, cfg.Log.Format, cfg.Log.Level); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging: %v\n", err)
+		os.Exit(1)
 	}
+	slog.Info("loaded configuration", "config", fmt.Sprintf("%+v", cfg.Redacted()))
+
+	// Timestamps are rendered in the cinema's timezone
+	time.Local = cfg.Location()
 
-	// Initialize configuration
-	cfg := config.NewConfig()
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
 
 	// Initialize database connection
 	postgresDB, err := database.NewPostgresDB(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
 
 	// Initialize Redis client
 	redisClient, err := database.NewRedisClient(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		fatal("failed to connect to Redis", err)
+	}
+
+	// Instrument dependencies for Prometheus
+	sqlDB, err := postgresDB.DB.DB()
+	if err != nil {
+		fatal("failed to get database instance", err)
+	}
+	migrator, err := migrate.New(sqlDB, migrations.FS)
+	if err != nil {
+		fatal("failed to load migrations", err)
 	}
-	defer redisClient.Close()
+	metrics.RegisterDBStats(sqlDB, cfg.Database.DBName)
+	metrics.RegisterShowtimeOccupancy(postgresDB.DB)
+	metrics.InstrumentRedis(redisClient.Client)
+
+	// Trace database and Redis calls made within traced requests
+	if err := tracing.InstrumentGORM(postgresDB.DB); err != nil {
+		fatal("failed to instrument database", err)
+	}
+	tracing.InstrumentRedis(redisClient.Client)
 
 	// Initialize services
-	authService := services.NewAuthService(postgresDB.DB, "your-jwt-secret-key") // Replace with actual secret from config
+	authService := services.NewAuthService(postgresDB.DB, cfg.Auth.JWTSecret, cfg.Auth.TokenTTL, cfg.Auth.OIDCStateTTL)
+	for _, provider := range cfg.OIDC.Providers {
+		authService.RegisterOIDCProvider(services.OIDCProvider{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		})
+	}
+	notifier := notification.NewLogSender()
+	userService := services.NewUserService(postgresDB.DB, notifier, cfg.Auth.EmailChangeTTL, cfg.Auth.ReauthWindow)
 	movieService := services.NewMovieService(postgresDB.DB)
-	bookingService := services.NewBookingService(postgresDB.DB, redisClient.Client)
+	notificationService := services.NewNotificationService(postgresDB.DB, notifier)
+	scheduler := jobs.NewScheduler(postgresDB.DB, cfg.Jobs.MaxAttempts, cfg.Jobs.RetryDelay)
+	reminderService := services.NewReminderService(scheduler, notificationService, cfg.Notification.ReminderBefore, cfg.Notification.RatingDelay, cfg.Notification.RatingURL)
+	renderer, err := notification.NewRenderer(cfg.Notification.DefaultLocale)
+	if err != nil {
+		fatal("failed to load email templates", err)
+	}
+	receiptService := services.NewReceiptService(scheduler, notificationService, renderer)
+	bookingService := services.NewBookingService(postgresDB.DB, redisClient.Client, cfg.Booking.SeatLockTTL, payment.NewLogGateway(), notificationService, reminderService, receiptService, services.CancellationPolicy{
+		FullRefundBefore:     cfg.Booking.FullRefundBefore,
+		PartialRefundBefore:  cfg.Booking.PartialRefundBefore,
+		PartialRefundPercent: cfg.Booking.PartialRefundPercent,
+	}, cfg.Booking.ExchangeURL, cfg.Booking.PreventOrphanSeats, cfg.Booking.AccessibleReleaseBefore, cfg.Booking.WaitlistHoldTTL)
+	exportService := services.NewExportService(postgresDB.DB, bookingService, notifier, cfg.Export.Dir, cfg.Export.TTL, cfg.Export.DownloadURL)
+	showtimeService := services.NewShowtimeService(postgresDB.DB, bookingService, cfg.Showtime.TrailerDuration, cfg.Showtime.CleaningBuffer)
+	scheduleService := services.NewScheduleService(postgresDB.DB, showtimeService, cfg.Location())
+	documentService := services.NewDocumentService(postgresDB.DB, services.InvoiceSettings{
+		Company: documents.Company{
+			Name:    cfg.Invoice.CompanyName,
+			Address: cfg.Invoice.CompanyAddress,
+			VATID:   cfg.Invoice.VATID,
+		},
+		TaxPercent:       cfg.Invoice.TaxPercent,
+		Currency:         cfg.Invoice.Currency,
+		Prefix:           cfg.Invoice.Prefix,
+		CreditNotePrefix: cfg.Invoice.CreditNotePrefix,
+	})
+	webhookService := services.NewWebhookService(postgresDB.DB, cfg.Webhooks.Timeout, cfg.Webhooks.MaxAttempts, cfg.Webhooks.RetryDelay)
 
 	// Create resolver with services
-	resolver := graph.NewResolver(authService, movieService, bookingService)
+	resolver := graph.NewResolver(authService, userService, movieService, bookingService, exportService, showtimeService, scheduleService, webhookService, documentService)
 
 	// Create GraphQL server
 	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	srv.Use(metrics.GraphQLExtension{})
+	srv.Use(tracing.GraphQLExtension{})
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	srv.SetRecoverFunc(graph.RecoverFunc)
+
+	// Health checks for liveness and readiness probes
+	checker := health.NewChecker()
+	checker.AddCheck("postgres", func(ctx context.Context) (string, error) {
+		return "", postgresDB.Ping(ctx)
+	})
+	checker.AddCheck("redis", func(ctx context.Context) (string, error) {
+		return "", redisClient.Ping(ctx)
+	})
+	checker.AddCheck("migrations", func(ctx context.Context) (string, error) {
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("version %d", version)
+		if version < migrator.Latest() {
+			return detail, fmt.Errorf("schema is at version %d, expected %d; run the migrate command", version, migrator.Latest())
+		}
+		return detail, nil
+	})
+
+	// Cancelled when shutdown starts to close websocket subscriptions
+	shutdownCtx, startShutdown := context.WithCancel(context.Background())
+	defer startShutdown()
+
+	// Background workers deliver queued emails, run scheduled jobs such as
+	// reminders and resume interrupted showtime cancellations and refunds.
+	// Shutdown waits for them before closing the connections they use.
+	var workers sync.WaitGroup
+	notificationBeat := checker.RegisterWorker("notifications", 3*cfg.Notification.DeliveryInterval)
+	startWorker(&workers, func() { notificationService.Run(shutdownCtx, cfg.Notification.DeliveryInterval, notificationBeat.Beat) })
+	jobsBeat := checker.RegisterWorker("jobs", 3*cfg.Jobs.PollInterval)
+	startWorker(&workers, func() { scheduler.Run(shutdownCtx, cfg.Jobs.PollInterval, jobsBeat.Beat) })
+
+	// Domain events recorded in the outbox issue invoices and are published to
+	// partner webhooks and the configured sinks
+	sinks := []events.Sink{documentService, webhookService}
+	if cfg.Events.RedisStream != "" {
+		sinks = append(sinks, events.NewRedisStreamSink(redisClient.Client, cfg.Events.RedisStream))
+	}
+	if cfg.Events.WebhookURL != "" {
+		sinks = append(sinks, events.NewWebhookSink(cfg.Events.WebhookURL, cfg.Events.WebhookTimeout))
+	}
+	relay := events.NewRelay(postgresDB.DB, cfg.Events.MaxAttempts, cfg.Events.RetryDelay, sinks...)
+	// A round may spend a while waiting for a slow webhook
+	relayBeat := checker.RegisterWorker("event_relay", 3*cfg.Events.RelayInterval+time.Minute)
+	startWorker(&workers, func() { relay.Run(shutdownCtx, cfg.Events.RelayInterval, relayBeat.Beat) })
+	webhooksBeat := checker.RegisterWorker("webhooks", 3*cfg.Webhooks.DeliveryInterval+time.Minute)
+	startWorker(&workers, func() { webhookService.Run(shutdownCtx, cfg.Webhooks.DeliveryInterval, webhooksBeat.Beat) })
+	exportsBeat := checker.RegisterWorker("data_exports", 3*services.ExportRecoveryInterval+time.Minute)
+	startWorker(&workers, func() { exportService.RunRecovery(shutdownCtx, services.ExportRecoveryInterval, exportsBeat.Beat) })
+	recoveryBeat := checker.RegisterWorker("booking_recovery", 3*cfg.Booking.RecoveryInterval)
+	startWorker(&workers, func() { bookingService.RunRecovery(shutdownCtx, cfg.Booking.RecoveryInterval, recoveryBeat.Beat) })
+
+	mux := http.NewServeMux()
+	mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
+	mux.Handle("/query", middleware.CancelOnShutdown(shutdownCtx)(middleware.AuthMiddleware(authService)(srv)))
+	mux.Handle("/exports/download", handlers.ExportDownloadHandler(exportService))
+	authenticated := middleware.AuthMiddleware(authService)
+	mux.Handle("/bookings/tickets", authenticated(handlers.BookingTicketsHandler(documentService)))
+	mux.Handle("/bookings/invoice", authenticated(handlers.BookingInvoiceHandler(documentService)))
+	mux.Handle("/invoices/download", authenticated(handlers.InvoiceDownloadHandler(documentService)))
+	if cfg.Notification.PreviewEnabled {
+		mux.Handle("/emails/preview", handlers.EmailPreviewHandler(receiptService))
+	}
+	mux.Handle("/healthz", checker.LivenessHandler())
+	mux.Handle("/readyz", checker.ReadinessHandler())
+	mux.Handle("/metrics", metrics.Handler())
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           tracing.Middleware(middleware.RequestIDMiddleware(middleware.CORSMiddleware(cfg.Server.CORSOrigins)(mux))),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server started", "port", cfg.Server.Port, "playground", fmt.Sprintf("http://localhost:%d/", cfg.Server.Port))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP server failed", "error", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	}
+	stop()
+
+	shutdown(cfg, server, checker, startShutdown, &workers, bookingService, redisClient, postgresDB)
+
+	// Flush spans that are still buffered
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+}
+
+// shutdown stops accepting requests, drains in-flight bookings and
+// subscriptions, waits for the background workers, then closes Redis and
+// Postgres
+func shutdown(cfg *config.Config, server *http.Server, checker *health.Checker, startShutdown context.CancelFunc, workers *sync.WaitGroup, bookingService *services.BookingService, redisClient *database.RedisClient, postgresDB *database.PostgresDB) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Fail readiness first so load balancers stop routing new traffic here
+	checker.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	// Close subscriptions and reject new bookings right away
+	startShutdown()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		graceCtx, cancel := context.WithTimeout(ctx, cfg.Booking.ShutdownGracePeriod)
+		defer cancel()
+		if err := bookingService.Drain(graceCtx); err != nil {
+			slog.Error("failed to drain bookings", "error", err)
+		}
+	}()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("failed to shut down HTTP server gracefully", "error", err)
+	}
+	<-drained
+
+	// Let a job or relay batch in flight finish before its pool is closed
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		workers.Wait()
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Error("background workers did not stop before the shutdown timeout")
+	}
+
+	if err := redisClient.Close(); err != nil {
+		slog.Error("failed to close Redis", "error", err)
+	}
+	if err := postgresDB.Close(); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+
+	slog.Info("server stopped")
+}
 
-	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", middleware.AuthMiddleware(authService)(srv))
+// startWorker runs a background worker in its own goroutine, tracked by workers
+func startWorker(workers *sync.WaitGroup, run func()) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		run()
+	}()
+}
 
-	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
-}
\ No newline at end of file
+// fatal logs an unrecoverable startup error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,19 @@
+package graph
+
+import (
+	"context"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/middleware"
+	"movie-ticket-booking/internal/models"
+)
+
+// requireAdmin checks that the authenticated user is an administrator
+func requireAdmin(ctx context.Context) error {
+	if _, ok := middleware.GetUserID(ctx); !ok {
+		return apperrors.Unauthenticated("authentication required")
+	}
+	if middleware.GetUserRole(ctx) != models.RoleAdmin {
+		return apperrors.Forbidden("admin access required")
+	}
+	return nil
+}
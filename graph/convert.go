@@ -0,0 +1,387 @@
+package graph
+
+import (
+	"movie-ticket-booking/graph/model"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/seating"
+	"movie-ticket-booking/internal/services"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// toUser converts a user to its GraphQL model
+func toUser(user *models.User) *model.User {
+	return &model.User{
+		ID:          strconv.FormatUint(uint64(user.ID), 10),
+		Email:       user.Email,
+		Name:        user.Name,
+		Phone:       user.Phone,
+		Locale:      user.Locale,
+		HasPassword: services.HasPassword(user),
+		Bookings:    []*model.Booking{},
+	}
+}
+
+// toBooking converts a booking to its GraphQL model
+func toBooking(booking *models.Booking) *model.Booking {
+	result := &model.Booking{
+		ID:          strconv.FormatUint(uint64(booking.ID), 10),
+		TotalAmount: booking.TotalAmount,
+		Status:      model.BookingStatus(booking.Status),
+		CreatedAt:   booking.CreatedAt.Format(time.RFC3339),
+	}
+	if booking.ExchangedFromID != nil {
+		exchangedFromID := strconv.FormatUint(uint64(*booking.ExchangedFromID), 10)
+		result.ExchangedFromID = &exchangedFromID
+	}
+	return result
+}
+
+// toMovie converts a movie to its GraphQL model
+func toMovie(movie *models.Movie) *model.Movie {
+	return &model.Movie{
+		ID:          strconv.FormatUint(uint64(movie.ID), 10),
+		Title:       movie.Title,
+		Description: movie.Description,
+		Duration:    movie.Duration,
+		Genre:       movie.Genre,
+		ReleaseDate: movie.ReleaseDate.Format("2006-01-02"),
+		PosterURL:   &movie.PosterURL,
+		Showtimes:   []*model.Showtime{},
+	}
+}
+
+// toSeat converts a seat to its GraphQL model
+func toSeat(seat *models.Seat) *model.Seat {
+	return &model.Seat{
+		ID:     strconv.FormatUint(uint64(seat.ID), 10),
+		Row:    seat.RowNumber,
+		Number: seat.SeatNumber,
+		Status: model.SeatStatus(seat.Status),
+		Kind:   model.SeatKind(seat.Kind),
+	}
+}
+
+// toShowtime converts a showtime with its movie, hall and seats preloaded
+func toShowtime(showtime *models.ShowTime) *model.Showtime {
+	result := &model.Showtime{
+		ID:             strconv.FormatUint(uint64(showtime.ID), 10),
+		Movie:          toMovie(&showtime.Movie),
+		StartTime:      showtime.StartTime.Format(time.RFC3339),
+		EndTime:        showtime.EndTime.Format(time.RFC3339),
+		Hall:           toHall(&showtime.Hall),
+		Price:          showtime.Price,
+		Status:         model.ShowtimeStatus(showtime.Status),
+		AvailableSeats: []*model.Seat{},
+	}
+	if showtime.CancellationReason != "" {
+		result.CancellationReason = &showtime.CancellationReason
+	}
+	for i := range showtime.Seats {
+		seat := toSeat(&showtime.Seats[i])
+		result.Hall.Seats = append(result.Hall.Seats, seat)
+		if showtime.Seats[i].Status == models.SeatStatusAvailable {
+			result.AvailableSeats = append(result.AvailableSeats, seat)
+		}
+	}
+	return result
+}
+
+// toHall converts a hall to its GraphQL model without seats
+func toHall(hall *models.Hall) *model.Hall {
+	return &model.Hall{
+		ID:               strconv.FormatUint(uint64(hall.ID), 10),
+		Name:             hall.Name,
+		Capacity:         hall.Capacity,
+		AllowOrphanSeats: hall.AllowOrphanSeats,
+		Seats:            []*model.Seat{},
+	}
+}
+
+// toShowtimes converts a list of showtimes to their GraphQL models
+func toShowtimes(showtimes []*models.ShowTime) []*model.Showtime {
+	result := make([]*model.Showtime, len(showtimes))
+	for i, showtime := range showtimes {
+		result[i] = toShowtime(showtime)
+	}
+	return result
+}
+
+// parseID converts a GraphQL ID to a database ID
+func parseID(id, name string) (uint, error) {
+	value, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || value == 0 {
+		return 0, apperrors.Validation("invalid %s ID: %s", name, id)
+	}
+	return uint(value), nil
+}
+
+// parseIDs converts a list of GraphQL IDs to database IDs
+func parseIDs(ids []string, name string) ([]uint, error) {
+	result := make([]uint, len(ids))
+	for i, id := range ids {
+		value, err := parseID(id, name)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// parseTime parses an RFC 3339 timestamp argument
+func parseTime(value, field string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apperrors.Validation("%s must be an RFC 3339 timestamp", field)
+	}
+	return t, nil
+}
+
+var weekdays = map[model.Weekday]time.Weekday{
+	model.WeekdayMonday:    time.Monday,
+	model.WeekdayTuesday:   time.Tuesday,
+	model.WeekdayWednesday: time.Wednesday,
+	model.WeekdayThursday:  time.Thursday,
+	model.WeekdayFriday:    time.Friday,
+	model.WeekdaySaturday:  time.Saturday,
+	model.WeekdaySunday:    time.Sunday,
+}
+
+// toScheduleRule parses a schedule input with its dates in loc
+func toScheduleRule(input model.ScheduleInput, loc *time.Location) (services.ScheduleRule, error) {
+	rule := services.ScheduleRule{Times: input.Times, Price: input.Price}
+
+	var err error
+	if rule.MovieID, err = parseID(input.MovieID, "movie"); err != nil {
+		return rule, err
+	}
+	if rule.HallID, err = parseID(input.HallID, "hall"); err != nil {
+		return rule, err
+	}
+	if rule.StartDate, err = parseDate(input.StartDate, "startDate", loc); err != nil {
+		return rule, err
+	}
+	if rule.EndDate, err = parseDate(input.EndDate, "endDate", loc); err != nil {
+		return rule, err
+	}
+	for _, day := range input.Weekdays {
+		rule.Weekdays = append(rule.Weekdays, weekdays[day])
+	}
+	return rule, nil
+}
+
+// toSchedule converts a schedule with its movie and hall preloaded
+func toSchedule(schedule *models.ShowtimeSchedule) *model.ShowtimeSchedule {
+	result := &model.ShowtimeSchedule{
+		ID:        strconv.FormatUint(uint64(schedule.ID), 10),
+		Movie:     toMovie(&schedule.Movie),
+		Hall:      toHall(&schedule.Hall),
+		StartDate: schedule.StartDate.Format(time.DateOnly),
+		EndDate:   schedule.EndDate.Format(time.DateOnly),
+		Weekdays:  []model.Weekday{},
+		Times:     strings.Split(schedule.Times, ","),
+		Price:     schedule.Price,
+		Status:    model.ScheduleStatus(schedule.Status),
+	}
+	for _, day := range services.ScheduleWeekdays(schedule) {
+		for name, weekday := range weekdays {
+			if weekday == day {
+				result.Weekdays = append(result.Weekdays, name)
+			}
+		}
+	}
+	return result
+}
+
+// toOccurrences converts planned schedule occurrences
+func toOccurrences(occurrences []services.ScheduleOccurrence) []*model.ScheduleOccurrence {
+	result := make([]*model.ScheduleOccurrence, len(occurrences))
+	for i, occurrence := range occurrences {
+		result[i] = &model.ScheduleOccurrence{
+			StartTime: occurrence.StartTime.Format(time.RFC3339),
+			EndTime:   occurrence.EndTime.Format(time.RFC3339),
+		}
+		if occurrence.Conflict != "" {
+			conflict := occurrence.Conflict
+			result[i].Conflict = &conflict
+		}
+	}
+	return result
+}
+
+// toScheduleResult converts the outcome of a schedule change
+func toScheduleResult(result *services.ScheduleResult) *model.ScheduleResult {
+	return &model.ScheduleResult{
+		Schedule: toSchedule(result.Schedule),
+		Created:  toShowtimes(result.Created),
+		Kept:     toShowtimes(result.Kept),
+		Removed:  result.Removed,
+		Skipped:  toOccurrences(result.Skipped),
+	}
+}
+
+// parseDate parses a YYYY-MM-DD date argument in the cinema's timezone
+func parseDate(value, field string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, apperrors.Validation("%s must be a date formatted as YYYY-MM-DD", field)
+	}
+	return t, nil
+}
+
+// toWaitlistEntry converts a waitlist place to its GraphQL model
+func toWaitlistEntry(place *services.WaitlistPlace) *model.WaitlistEntry {
+	entry := place.Entry
+	result := &model.WaitlistEntry{
+		ID:         strconv.FormatUint(uint64(entry.ID), 10),
+		ShowtimeID: strconv.FormatUint(uint64(entry.ShowTimeID), 10),
+		SeatCount:  entry.SeatCount,
+		Status:     model.WaitlistStatus(entry.Status),
+		HeldSeats:  make([]*model.Seat, len(entry.HeldSeats)),
+	}
+	if place.Position > 0 {
+		result.Position = &place.Position
+	}
+	for i := range entry.HeldSeats {
+		result.HeldSeats[i] = toSeat(&entry.HeldSeats[i])
+	}
+	if entry.Status == models.WaitlistStatusOffered && entry.OfferExpiresAt != nil {
+		expiresAt := entry.OfferExpiresAt.Format(time.RFC3339)
+		result.OfferExpiresAt = &expiresAt
+	}
+	return result
+}
+
+// toInvoice converts an invoice or credit note to its GraphQL model
+func toInvoice(invoice *models.Invoice) *model.Invoice {
+	result := &model.Invoice{
+		ID:         strconv.FormatUint(uint64(invoice.ID), 10),
+		Number:     invoice.Number,
+		Kind:       model.InvoiceKind(invoice.Kind),
+		BookingID:  strconv.FormatUint(uint64(invoice.BookingID), 10),
+		Gross:      invoice.Gross,
+		Net:        invoice.Net,
+		Tax:        invoice.Tax,
+		TaxPercent: invoice.TaxPercent,
+		Currency:   invoice.Currency,
+		IssuedAt:   invoice.IssuedAt.Format(time.RFC3339),
+	}
+	if invoice.CorrectsID != nil {
+		correctsID := strconv.FormatUint(uint64(*invoice.CorrectsID), 10)
+		result.CorrectsID = &correctsID
+	}
+	return result
+}
+
+// domainEventTypes maps GraphQL event types to domain event types
+var domainEventTypes = map[model.DomainEventType]string{
+	model.DomainEventTypeBookingCreated:    events.TypeBookingCreated,
+	model.DomainEventTypeBookingCancelled:  events.TypeBookingCancelled,
+	model.DomainEventTypeSeatsReleased:     events.TypeSeatsReleased,
+	model.DomainEventTypeShowtimeCancelled: events.TypeShowtimeCancelled,
+	model.DomainEventTypePaymentSucceeded:  events.TypePaymentSucceeded,
+}
+
+// toDomainEventTypes converts GraphQL event types to domain event types
+func toDomainEventTypes(types []model.DomainEventType) []string {
+	result := make([]string, len(types))
+	for i, t := range types {
+		result[i] = domainEventTypes[t]
+	}
+	return result
+}
+
+// toDomainEventType converts a domain event type to its GraphQL enum
+func toDomainEventType(eventType string) model.DomainEventType {
+	for graphqlType, domainType := range domainEventTypes {
+		if domainType == eventType {
+			return graphqlType
+		}
+	}
+	return model.DomainEventType(eventType)
+}
+
+// toWebhookSubscription converts a webhook subscription to its GraphQL model.
+// The secret is only included right after it was generated or chosen.
+func toWebhookSubscription(subscription *models.WebhookSubscription, withSecret bool) *model.WebhookSubscription {
+	eventTypes := services.SubscriptionEventTypes(subscription)
+	result := &model.WebhookSubscription{
+		ID:         strconv.FormatUint(uint64(subscription.ID), 10),
+		URL:        subscription.URL,
+		EventTypes: make([]model.DomainEventType, len(eventTypes)),
+		Active:     subscription.Active,
+		CreatedAt:  subscription.CreatedAt.Format(time.RFC3339),
+	}
+	for i, eventType := range eventTypes {
+		result.EventTypes[i] = toDomainEventType(eventType)
+	}
+	if withSecret {
+		result.Secret = &subscription.Secret
+	}
+	return result
+}
+
+// toWebhookDelivery converts a webhook delivery to its GraphQL model
+func toWebhookDelivery(delivery *models.WebhookDelivery) *model.WebhookDelivery {
+	result := &model.WebhookDelivery{
+		ID:             strconv.FormatUint(uint64(delivery.ID), 10),
+		SubscriptionID: strconv.FormatUint(uint64(delivery.SubscriptionID), 10),
+		EventType:      toDomainEventType(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         model.WebhookDeliveryStatus(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		CreatedAt:      delivery.CreatedAt.Format(time.RFC3339),
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt.Format(time.RFC3339)
+		result.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.LastError != "" {
+		result.LastError = &delivery.LastError
+	}
+	if delivery.DeliveredAt != nil {
+		deliveredAt := delivery.DeliveredAt.Format(time.RFC3339)
+		result.DeliveredAt = &deliveredAt
+	}
+	if delivery.ReplayOfID != nil {
+		replayOfID := strconv.FormatUint(uint64(*delivery.ReplayOfID), 10)
+		result.ReplayOfID = &replayOfID
+	}
+	return result
+}
+
+// toSeatPreferences converts seat preferences, which may be nil, to the seating package
+func toSeatPreferences(input *model.SeatPreferences) seating.Preferences {
+	prefs := seating.Preferences{Row: seating.RowMiddle}
+	if input == nil {
+		return prefs
+	}
+	if input.Row != nil {
+		prefs.Row = seating.RowPreference(*input.Row)
+	}
+	if input.AllowSplit != nil {
+		prefs.AllowSplit = *input.AllowSplit
+	}
+	return prefs
+}
+
+// toSeatSuggestions converts seat suggestions to their GraphQL models
+func toSeatSuggestions(suggestions []services.SeatSuggestion) []*model.SeatSuggestion {
+	result := make([]*model.SeatSuggestion, len(suggestions))
+	for i, suggestion := range suggestions {
+		result[i] = &model.SeatSuggestion{
+			Seats:    make([]*model.Seat, len(suggestion.Seats)),
+			Score:    suggestion.Score,
+			Together: suggestion.Together,
+		}
+		for j, seat := range suggestion.Seats {
+			result[i].Seats[j] = toSeat(seat)
+		}
+	}
+	return result
+}
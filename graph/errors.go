@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/logging"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorPresenter maps domain errors to GraphQL errors with extensions.code.
+// Any other error is logged and reported to the client as an internal error
+// so that no implementation details leak.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	presented := &gqlerror.Error{
+		Path:       graphql.GetPath(ctx),
+		Extensions: map[string]interface{}{},
+	}
+
+	if appErr, ok := apperrors.As(err); ok {
+		presented.Message = appErr.Message
+		presented.Extensions["code"] = string(appErr.Code)
+		if appErr.Code == apperrors.CodeInternal || appErr.Code == apperrors.CodeUnavailable || appErr.Code == apperrors.CodePaymentFailed {
+			logging.FromContext(ctx).Error("request failed", "path", presented.Path.String(), "error", err)
+		}
+		return presented
+	}
+
+	// Parsing, validation and input coercion errors produced by gqlgen itself
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+
+	logging.FromContext(ctx).Error("unexpected error", "path", presented.Path.String(), "error", err)
+	presented.Message = "internal server error"
+	presented.Extensions["code"] = string(apperrors.CodeInternal)
+	return presented
+}
+
+// RecoverFunc turns panics in resolvers into internal errors
+func RecoverFunc(ctx context.Context, p interface{}) error {
+	logging.FromContext(ctx).Error("panic in resolver", "panic", fmt.Sprint(p))
+	return apperrors.New(apperrors.CodeInternal, "internal server error")
+}
@@ -8,26 +8,78 @@ import (
 	"strconv"
 )
 
+type AutoAssignInput struct {
+	Count       int              `json:"count"`
+	Preferences *SeatPreferences `json:"preferences,omitempty"`
+}
+
 type Booking struct {
-	ID          string        `json:"id"`
-	User        *User         `json:"user"`
-	Showtime    *Showtime     `json:"showtime"`
-	Seats       []*Seat       `json:"seats"`
-	TotalAmount float64       `json:"totalAmount"`
-	Status      BookingStatus `json:"status"`
-	CreatedAt   string        `json:"createdAt"`
+	ID              string        `json:"id"`
+	User            *User         `json:"user"`
+	Showtime        *Showtime     `json:"showtime"`
+	Seats           []*Seat       `json:"seats"`
+	TotalAmount     float64       `json:"totalAmount"`
+	Status          BookingStatus `json:"status"`
+	ExchangedFromID *string       `json:"exchangedFromId,omitempty"`
+	CreatedAt       string        `json:"createdAt"`
 }
 
 type BookingInput struct {
-	ShowtimeID string   `json:"showtimeId"`
-	SeatIds    []string `json:"seatIds"`
+	ShowtimeID    string           `json:"showtimeId"`
+	SeatIds       []string         `json:"seatIds,omitempty"`
+	AutoAssign    *AutoAssignInput `json:"autoAssign,omitempty"`
+	Accessibility *bool            `json:"accessibility,omitempty"`
+}
+
+type ChangeEmailInput struct {
+	NewEmail string  `json:"newEmail"`
+	Password *string `json:"password,omitempty"`
+}
+
+type ChangePasswordInput struct {
+	OldPassword *string `json:"oldPassword,omitempty"`
+	NewPassword string  `json:"newPassword"`
+}
+
+type CreateShowtimeInput struct {
+	MovieID   string  `json:"movieId"`
+	HallID    string  `json:"hallId"`
+	StartTime string  `json:"startTime"`
+	Price     float64 `json:"price"`
+}
+
+type CreateWebhookSubscriptionInput struct {
+	URL        string            `json:"url"`
+	EventTypes []DomainEventType `json:"eventTypes"`
+	Secret     *string           `json:"secret,omitempty"`
+}
+
+type DataExport struct {
+	ID        string           `json:"id"`
+	Status    DataExportStatus `json:"status"`
+	ExpiresAt string           `json:"expiresAt"`
 }
 
 type Hall struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Capacity int     `json:"capacity"`
-	Seats    []*Seat `json:"seats"`
+	ID               string  `json:"id"`
+	Name             string  `json:"name"`
+	Capacity         int     `json:"capacity"`
+	AllowOrphanSeats bool    `json:"allowOrphanSeats"`
+	Seats            []*Seat `json:"seats"`
+}
+
+type Invoice struct {
+	ID         string      `json:"id"`
+	Number     string      `json:"number"`
+	Kind       InvoiceKind `json:"kind"`
+	BookingID  string      `json:"bookingId"`
+	Gross      float64     `json:"gross"`
+	Net        float64     `json:"net"`
+	Tax        float64     `json:"tax"`
+	TaxPercent float64     `json:"taxPercent"`
+	Currency   string      `json:"currency"`
+	CorrectsID *string     `json:"correctsId,omitempty"`
+	IssuedAt   string      `json:"issuedAt"`
 }
 
 type LoginInput struct {
@@ -59,6 +111,17 @@ type MoviesResponse struct {
 type Mutation struct {
 }
 
+type OidcCallbackInput struct {
+	Provider string `json:"provider"`
+	Code     string `json:"code"`
+	State    string `json:"state"`
+}
+
+type OidcLoginStart struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
 type Query struct {
 }
 
@@ -73,32 +136,143 @@ type RegisterResponse struct {
 	User *User `json:"user"`
 }
 
+type ScheduleInput struct {
+	MovieID   string    `json:"movieId"`
+	HallID    string    `json:"hallId"`
+	StartDate string    `json:"startDate"`
+	EndDate   string    `json:"endDate"`
+	Weekdays  []Weekday `json:"weekdays"`
+	Times     []string  `json:"times"`
+	Price     float64   `json:"price"`
+}
+
+type ScheduleOccurrence struct {
+	StartTime string  `json:"startTime"`
+	EndTime   string  `json:"endTime"`
+	Conflict  *string `json:"conflict,omitempty"`
+}
+
+type ScheduleResult struct {
+	Schedule *ShowtimeSchedule     `json:"schedule"`
+	Created  []*Showtime           `json:"created"`
+	Kept     []*Showtime           `json:"kept"`
+	Removed  int                   `json:"removed"`
+	Skipped  []*ScheduleOccurrence `json:"skipped"`
+}
+
 type Seat struct {
 	ID     string     `json:"id"`
 	Row    string     `json:"row"`
 	Number int        `json:"number"`
 	Status SeatStatus `json:"status"`
+	Kind   SeatKind   `json:"kind"`
+}
+
+type SeatPositionInput struct {
+	Row    string `json:"row"`
+	Number int    `json:"number"`
+}
+
+type SeatPreferences struct {
+	Row        *RowPreference `json:"row,omitempty"`
+	AllowSplit *bool          `json:"allowSplit,omitempty"`
+}
+
+type SeatSuggestion struct {
+	Seats    []*Seat `json:"seats"`
+	Score    float64 `json:"score"`
+	Together bool    `json:"together"`
 }
 
 type Showtime struct {
-	ID             string  `json:"id"`
-	Movie          *Movie  `json:"movie"`
-	StartTime      string  `json:"startTime"`
-	EndTime        string  `json:"endTime"`
-	Hall           *Hall   `json:"hall"`
-	Price          float64 `json:"price"`
-	AvailableSeats []*Seat `json:"availableSeats"`
+	ID                 string         `json:"id"`
+	Movie              *Movie         `json:"movie"`
+	StartTime          string         `json:"startTime"`
+	EndTime            string         `json:"endTime"`
+	Hall               *Hall          `json:"hall"`
+	Price              float64        `json:"price"`
+	Status             ShowtimeStatus `json:"status"`
+	CancellationReason *string        `json:"cancellationReason,omitempty"`
+	AvailableSeats     []*Seat        `json:"availableSeats"`
+}
+
+type ShowtimeSchedule struct {
+	ID        string         `json:"id"`
+	Movie     *Movie         `json:"movie"`
+	Hall      *Hall          `json:"hall"`
+	StartDate string         `json:"startDate"`
+	EndDate   string         `json:"endDate"`
+	Weekdays  []Weekday      `json:"weekdays"`
+	Times     []string       `json:"times"`
+	Price     float64        `json:"price"`
+	Status    ScheduleStatus `json:"status"`
 }
 
 type Subscription struct {
 }
 
+type UpdateProfileInput struct {
+	Name   string  `json:"name"`
+	Phone  string  `json:"phone"`
+	Locale *string `json:"locale,omitempty"`
+}
+
+type UpdateShowtimeInput struct {
+	MovieID   *string  `json:"movieId,omitempty"`
+	HallID    *string  `json:"hallId,omitempty"`
+	StartTime *string  `json:"startTime,omitempty"`
+	Price     *float64 `json:"price,omitempty"`
+}
+
+type UpdateWebhookSubscriptionInput struct {
+	URL          *string           `json:"url,omitempty"`
+	EventTypes   []DomainEventType `json:"eventTypes,omitempty"`
+	Active       *bool             `json:"active,omitempty"`
+	RotateSecret *bool             `json:"rotateSecret,omitempty"`
+}
+
 type User struct {
-	ID       string     `json:"id"`
-	Email    string     `json:"email"`
-	Name     string     `json:"name"`
-	Phone    string     `json:"phone"`
-	Bookings []*Booking `json:"bookings"`
+	ID          string     `json:"id"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	Phone       string     `json:"phone"`
+	Locale      string     `json:"locale"`
+	HasPassword bool       `json:"hasPassword"`
+	Bookings    []*Booking `json:"bookings"`
+}
+
+type WaitlistEntry struct {
+	ID             string         `json:"id"`
+	ShowtimeID     string         `json:"showtimeId"`
+	SeatCount      int            `json:"seatCount"`
+	Status         WaitlistStatus `json:"status"`
+	Position       *int           `json:"position,omitempty"`
+	HeldSeats      []*Seat        `json:"heldSeats"`
+	OfferExpiresAt *string        `json:"offerExpiresAt,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"subscriptionId"`
+	EventType      DomainEventType       `json:"eventType"`
+	Payload        string                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  *string               `json:"nextAttemptAt,omitempty"`
+	ResponseStatus *int                  `json:"responseStatus,omitempty"`
+	LastError      *string               `json:"lastError,omitempty"`
+	DeliveredAt    *string               `json:"deliveredAt,omitempty"`
+	ReplayOfID     *string               `json:"replayOfId,omitempty"`
+	CreatedAt      string                `json:"createdAt"`
+}
+
+type WebhookSubscription struct {
+	ID         string            `json:"id"`
+	URL        string            `json:"url"`
+	EventTypes []DomainEventType `json:"eventTypes"`
+	Secret     *string           `json:"secret,omitempty"`
+	Active     bool              `json:"active"`
+	CreatedAt  string            `json:"createdAt"`
 }
 
 type BookingStatus string
@@ -106,16 +280,18 @@ type BookingStatus string
 const (
 	BookingStatusConfirmed BookingStatus = "CONFIRMED"
 	BookingStatusCancelled BookingStatus = "CANCELLED"
+	BookingStatusExchanged BookingStatus = "EXCHANGED"
 )
 
 var AllBookingStatus = []BookingStatus{
 	BookingStatusConfirmed,
 	BookingStatusCancelled,
+	BookingStatusExchanged,
 }
 
 func (e BookingStatus) IsValid() bool {
 	switch e {
-	case BookingStatusConfirmed, BookingStatusCancelled:
+	case BookingStatusConfirmed, BookingStatusCancelled, BookingStatusExchanged:
 		return true
 	}
 	return false
@@ -142,6 +318,264 @@ func (e BookingStatus) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
+type DataExportStatus string
+
+const (
+	DataExportStatusPending DataExportStatus = "PENDING"
+	DataExportStatusReady   DataExportStatus = "READY"
+	DataExportStatusFailed  DataExportStatus = "FAILED"
+)
+
+var AllDataExportStatus = []DataExportStatus{
+	DataExportStatusPending,
+	DataExportStatusReady,
+	DataExportStatusFailed,
+}
+
+func (e DataExportStatus) IsValid() bool {
+	switch e {
+	case DataExportStatusPending, DataExportStatusReady, DataExportStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e DataExportStatus) String() string {
+	return string(e)
+}
+
+func (e *DataExportStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DataExportStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DataExportStatus", str)
+	}
+	return nil
+}
+
+func (e DataExportStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type DomainEventType string
+
+const (
+	DomainEventTypeBookingCreated    DomainEventType = "BOOKING_CREATED"
+	DomainEventTypeBookingCancelled  DomainEventType = "BOOKING_CANCELLED"
+	DomainEventTypeSeatsReleased     DomainEventType = "SEATS_RELEASED"
+	DomainEventTypeShowtimeCancelled DomainEventType = "SHOWTIME_CANCELLED"
+	DomainEventTypePaymentSucceeded  DomainEventType = "PAYMENT_SUCCEEDED"
+)
+
+var AllDomainEventType = []DomainEventType{
+	DomainEventTypeBookingCreated,
+	DomainEventTypeBookingCancelled,
+	DomainEventTypeSeatsReleased,
+	DomainEventTypeShowtimeCancelled,
+	DomainEventTypePaymentSucceeded,
+}
+
+func (e DomainEventType) IsValid() bool {
+	switch e {
+	case DomainEventTypeBookingCreated, DomainEventTypeBookingCancelled, DomainEventTypeSeatsReleased, DomainEventTypeShowtimeCancelled, DomainEventTypePaymentSucceeded:
+		return true
+	}
+	return false
+}
+
+func (e DomainEventType) String() string {
+	return string(e)
+}
+
+func (e *DomainEventType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DomainEventType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DomainEventType", str)
+	}
+	return nil
+}
+
+func (e DomainEventType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type InvoiceKind string
+
+const (
+	InvoiceKindInvoice    InvoiceKind = "INVOICE"
+	InvoiceKindCreditNote InvoiceKind = "CREDIT_NOTE"
+)
+
+var AllInvoiceKind = []InvoiceKind{
+	InvoiceKindInvoice,
+	InvoiceKindCreditNote,
+}
+
+func (e InvoiceKind) IsValid() bool {
+	switch e {
+	case InvoiceKindInvoice, InvoiceKindCreditNote:
+		return true
+	}
+	return false
+}
+
+func (e InvoiceKind) String() string {
+	return string(e)
+}
+
+func (e *InvoiceKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = InvoiceKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid InvoiceKind", str)
+	}
+	return nil
+}
+
+func (e InvoiceKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RowPreference string
+
+const (
+	RowPreferenceFront  RowPreference = "FRONT"
+	RowPreferenceMiddle RowPreference = "MIDDLE"
+	RowPreferenceBack   RowPreference = "BACK"
+)
+
+var AllRowPreference = []RowPreference{
+	RowPreferenceFront,
+	RowPreferenceMiddle,
+	RowPreferenceBack,
+}
+
+func (e RowPreference) IsValid() bool {
+	switch e {
+	case RowPreferenceFront, RowPreferenceMiddle, RowPreferenceBack:
+		return true
+	}
+	return false
+}
+
+func (e RowPreference) String() string {
+	return string(e)
+}
+
+func (e *RowPreference) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RowPreference(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RowPreference", str)
+	}
+	return nil
+}
+
+func (e RowPreference) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive  ScheduleStatus = "ACTIVE"
+	ScheduleStatusRemoved ScheduleStatus = "REMOVED"
+)
+
+var AllScheduleStatus = []ScheduleStatus{
+	ScheduleStatusActive,
+	ScheduleStatusRemoved,
+}
+
+func (e ScheduleStatus) IsValid() bool {
+	switch e {
+	case ScheduleStatusActive, ScheduleStatusRemoved:
+		return true
+	}
+	return false
+}
+
+func (e ScheduleStatus) String() string {
+	return string(e)
+}
+
+func (e *ScheduleStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScheduleStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScheduleStatus", str)
+	}
+	return nil
+}
+
+func (e ScheduleStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SeatKind string
+
+const (
+	SeatKindStandard   SeatKind = "STANDARD"
+	SeatKindWheelchair SeatKind = "WHEELCHAIR"
+	SeatKindCompanion  SeatKind = "COMPANION"
+)
+
+var AllSeatKind = []SeatKind{
+	SeatKindStandard,
+	SeatKindWheelchair,
+	SeatKindCompanion,
+}
+
+func (e SeatKind) IsValid() bool {
+	switch e {
+	case SeatKindStandard, SeatKindWheelchair, SeatKindCompanion:
+		return true
+	}
+	return false
+}
+
+func (e SeatKind) String() string {
+	return string(e)
+}
+
+func (e *SeatKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SeatKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SeatKind", str)
+	}
+	return nil
+}
+
+func (e SeatKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type SeatStatus string
 
 const (
@@ -184,3 +618,185 @@ func (e *SeatStatus) UnmarshalGQL(v any) error {
 func (e SeatStatus) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
+
+type ShowtimeStatus string
+
+const (
+	ShowtimeStatusScheduled ShowtimeStatus = "SCHEDULED"
+	ShowtimeStatusCancelled ShowtimeStatus = "CANCELLED"
+)
+
+var AllShowtimeStatus = []ShowtimeStatus{
+	ShowtimeStatusScheduled,
+	ShowtimeStatusCancelled,
+}
+
+func (e ShowtimeStatus) IsValid() bool {
+	switch e {
+	case ShowtimeStatusScheduled, ShowtimeStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e ShowtimeStatus) String() string {
+	return string(e)
+}
+
+func (e *ShowtimeStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ShowtimeStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ShowtimeStatus", str)
+	}
+	return nil
+}
+
+func (e ShowtimeStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting WaitlistStatus = "WAITING"
+	WaitlistStatusOffered WaitlistStatus = "OFFERED"
+	WaitlistStatusBooked  WaitlistStatus = "BOOKED"
+	WaitlistStatusExpired WaitlistStatus = "EXPIRED"
+	WaitlistStatusLeft    WaitlistStatus = "LEFT"
+)
+
+var AllWaitlistStatus = []WaitlistStatus{
+	WaitlistStatusWaiting,
+	WaitlistStatusOffered,
+	WaitlistStatusBooked,
+	WaitlistStatusExpired,
+	WaitlistStatusLeft,
+}
+
+func (e WaitlistStatus) IsValid() bool {
+	switch e {
+	case WaitlistStatusWaiting, WaitlistStatusOffered, WaitlistStatusBooked, WaitlistStatusExpired, WaitlistStatusLeft:
+		return true
+	}
+	return false
+}
+
+func (e WaitlistStatus) String() string {
+	return string(e)
+}
+
+func (e *WaitlistStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WaitlistStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WaitlistStatus", str)
+	}
+	return nil
+}
+
+func (e WaitlistStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusSucceeded,
+	WebhookDeliveryStatusFailed,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusSucceeded, WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Weekday string
+
+const (
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+	WeekdaySunday    Weekday = "SUNDAY"
+)
+
+var AllWeekday = []Weekday{
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+	WeekdaySunday,
+}
+
+func (e Weekday) IsValid() bool {
+	switch e {
+	case WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday, WeekdaySaturday, WeekdaySunday:
+		return true
+	}
+	return false
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+func (e *Weekday) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
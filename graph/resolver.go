@@ -9,15 +9,27 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	authService    *services.AuthService
-	movieService  *services.MovieService
-	bookingService *services.BookingService
+	authService     *services.AuthService
+	userService     *services.UserService
+	movieService    *services.MovieService
+	bookingService  *services.BookingService
+	exportService   *services.ExportService
+	showtimeService *services.ShowtimeService
+	scheduleService *services.ScheduleService
+	webhookService  *services.WebhookService
+	documentService *services.DocumentService
 }
 
-func NewResolver(authService *services.AuthService, movieService *services.MovieService, bookingService *services.BookingService) *Resolver {
+func NewResolver(authService *services.AuthService, userService *services.UserService, movieService *services.MovieService, bookingService *services.BookingService, exportService *services.ExportService, showtimeService *services.ShowtimeService, scheduleService *services.ScheduleService, webhookService *services.WebhookService, documentService *services.DocumentService) *Resolver {
 	return &Resolver{
-		authService:    authService,
-		movieService:   movieService,
-		bookingService: bookingService,
+		authService:     authService,
+		userService:     userService,
+		movieService:    movieService,
+		bookingService:  bookingService,
+		exportService:   exportService,
+		showtimeService: showtimeService,
+		scheduleService: scheduleService,
+		webhookService:  webhookService,
+		documentService: documentService,
 	}
 }
@@ -9,7 +9,10 @@ import (
 	"fmt"
 	"movie-ticket-booking/graph/generated"
 	"movie-ticket-booking/graph/model"
+	"movie-ticket-booking/internal/apperrors"
 	"movie-ticket-booking/internal/middleware"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/services"
 	"strconv"
 	"time"
 )
@@ -43,33 +46,171 @@ func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*
 	}, nil
 }
 
+// BeginOidcLogin is the resolver for the beginOidcLogin field.
+func (r *mutationResolver) BeginOidcLogin(ctx context.Context, provider string) (*model.OidcLoginStart, error) {
+	// Signed-in users link the provider account to their own
+	var linkUserID *uint
+	if userID, ok := middleware.GetUserID(ctx); ok {
+		linkUserID = &userID
+	}
+
+	authorizationURL, state, err := r.authService.BeginOIDCLogin(ctx, provider, linkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OidcLoginStart{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	}, nil
+}
+
+// CompleteOidcLogin is the resolver for the completeOidcLogin field.
+func (r *mutationResolver) CompleteOidcLogin(ctx context.Context, input model.OidcCallbackInput) (*model.LoginResponse, error) {
+	token, err := r.authService.CompleteOIDCLogin(ctx, input.Provider, input.Code, input.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{
+		Token: token,
+	}, nil
+}
+
+// UpdateProfile is the resolver for the updateProfile field.
+func (r *mutationResolver) UpdateProfile(ctx context.Context, input model.UpdateProfileInput) (*model.User, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	user, err := r.userService.UpdateProfile(userID, input.Name, input.Phone, input.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUser(user), nil
+}
+
+// ChangePassword is the resolver for the changePassword field.
+func (r *mutationResolver) ChangePassword(ctx context.Context, input model.ChangePasswordInput) (bool, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return false, apperrors.Unauthenticated("authentication required")
+	}
+
+	if err := r.userService.ChangePassword(userID, input.OldPassword, input.NewPassword, middleware.GetAuthTime(ctx)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ChangeEmail is the resolver for the changeEmail field.
+func (r *mutationResolver) ChangeEmail(ctx context.Context, input model.ChangeEmailInput) (bool, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return false, apperrors.Unauthenticated("authentication required")
+	}
+
+	if err := r.userService.RequestEmailChange(ctx, userID, input.NewEmail, input.Password, middleware.GetAuthTime(ctx)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ConfirmEmailChange is the resolver for the confirmEmailChange field.
+func (r *mutationResolver) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	user, err := r.userService.ConfirmEmailChange(userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUser(user), nil
+}
+
+// DeleteAccount is the resolver for the deleteAccount field.
+func (r *mutationResolver) DeleteAccount(ctx context.Context, password *string) (bool, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return false, apperrors.Unauthenticated("authentication required")
+	}
+
+	if err := r.userService.DeleteAccount(userID, password, middleware.GetAuthTime(ctx)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RequestDataExport is the resolver for the requestDataExport field.
+func (r *mutationResolver) RequestDataExport(ctx context.Context) (*model.DataExport, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	export, err := r.exportService.RequestDataExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.DataExport{
+		ID:        strconv.FormatUint(uint64(export.ID), 10),
+		Status:    model.DataExportStatus(export.Status),
+		ExpiresAt: export.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
 // CreateBooking is the resolver for the createBooking field.
 func (r *mutationResolver) CreateBooking(ctx context.Context, input model.BookingInput) (*model.Booking, error) {
 	// Get user ID from context
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		return nil, fmt.Errorf("unauthorized")
+		return nil, apperrors.Unauthenticated("authentication required")
 	}
 
 	// Convert string IDs to uint
 	showtimeID, err := strconv.ParseUint(input.ShowtimeID, 10, 64)
 	if err != nil || showtimeID == 0 {
-		return nil, fmt.Errorf("invalid showtime ID: %s", input.ShowtimeID)
+		return nil, apperrors.Validation("invalid showtime ID: %s", input.ShowtimeID)
 	}
 
-	var seatIDs []uint
-	for _, id := range input.SeatIds {
-		seatID, err := strconv.ParseUint(id, 10, 64)
-		if err != nil || seatID == 0 {
-			return nil, fmt.Errorf("invalid seat ID: %s", id)
+	accessible := input.Accessibility != nil && *input.Accessibility
+
+	var booking *models.Booking
+	switch {
+	case input.AutoAssign != nil && len(input.SeatIds) > 0:
+		return nil, apperrors.Validation("choose either seatIds or autoAssign")
+	case input.AutoAssign != nil:
+		// Book the best free seats
+		booking, err = r.bookingService.BookBestSeats(ctx, userID, uint(showtimeID), input.AutoAssign.Count, toSeatPreferences(input.AutoAssign.Preferences), accessible)
+		if err != nil {
+			return nil, err
+		}
+	case len(input.SeatIds) == 0:
+		return nil, apperrors.Validation("seatIds or autoAssign is required")
+	default:
+		var seatIDs []uint
+		for _, id := range input.SeatIds {
+			seatID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil || seatID == 0 {
+				return nil, apperrors.Validation("invalid seat ID: %s", id)
+			}
+			seatIDs = append(seatIDs, uint(seatID))
 		}
-		seatIDs = append(seatIDs, uint(seatID))
-	}
 
-	// Create booking
-	booking, err := r.bookingService.CreateBooking(ctx, userID, uint(showtimeID), seatIDs)
-	if err != nil {
-		return nil, err
+		// Create booking
+		booking, err = r.bookingService.CreateBooking(ctx, userID, uint(showtimeID), seatIDs, accessible)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Convert to GraphQL model
@@ -86,13 +227,13 @@ func (r *mutationResolver) CancelBooking(ctx context.Context, id string) (bool,
 	// Get user ID from context using middleware function
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		return false, fmt.Errorf("unauthorized")
+		return false, apperrors.Unauthenticated("authentication required")
 	}
 
 	// Convert booking ID to uint
 	bookingID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return false, fmt.Errorf("invalid booking ID")
+		return false, apperrors.Validation("invalid booking ID")
 	}
 
 	// Cancel booking
@@ -104,11 +245,385 @@ func (r *mutationResolver) CancelBooking(ctx context.Context, id string) (bool,
 	return true, nil
 }
 
+// CancelBookingSeats is the resolver for the cancelBookingSeats field.
+func (r *mutationResolver) CancelBookingSeats(ctx context.Context, bookingID string, seatIds []string) (*model.Booking, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	id, err := parseID(bookingID, "booking")
+	if err != nil {
+		return nil, err
+	}
+	ids, err := parseIDs(seatIds, "seat")
+	if err != nil {
+		return nil, err
+	}
+
+	booking, err := r.bookingService.CancelBookingSeats(ctx, id, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return toBooking(booking), nil
+}
+
+// ExchangeBooking is the resolver for the exchangeBooking field.
+func (r *mutationResolver) ExchangeBooking(ctx context.Context, bookingID string, newShowtimeID string, newSeatIds []string) (*model.Booking, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	id, err := parseID(bookingID, "booking")
+	if err != nil {
+		return nil, err
+	}
+	showtimeID, err := parseID(newShowtimeID, "showtime")
+	if err != nil {
+		return nil, err
+	}
+	seatIDs, err := parseIDs(newSeatIds, "seat")
+	if err != nil {
+		return nil, err
+	}
+
+	booking, err := r.bookingService.ExchangeBooking(ctx, id, userID, showtimeID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+	return toBooking(booking), nil
+}
+
+// JoinWaitlist is the resolver for the joinWaitlist field.
+func (r *mutationResolver) JoinWaitlist(ctx context.Context, showtimeID string, seatCount int) (*model.WaitlistEntry, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	id, err := parseID(showtimeID, "showtime")
+	if err != nil {
+		return nil, err
+	}
+
+	place, err := r.bookingService.JoinWaitlist(ctx, userID, id, seatCount)
+	if err != nil {
+		return nil, err
+	}
+	return toWaitlistEntry(place), nil
+}
+
+// LeaveWaitlist is the resolver for the leaveWaitlist field.
+func (r *mutationResolver) LeaveWaitlist(ctx context.Context, showtimeID string) (bool, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return false, apperrors.Unauthenticated("authentication required")
+	}
+
+	id, err := parseID(showtimeID, "showtime")
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.bookingService.LeaveWaitlist(ctx, userID, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateShowtime is the resolver for the createShowtime field.
+func (r *mutationResolver) CreateShowtime(ctx context.Context, input model.CreateShowtimeInput) (*model.Showtime, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	movieID, err := parseID(input.MovieID, "movie")
+	if err != nil {
+		return nil, err
+	}
+	hallID, err := parseID(input.HallID, "hall")
+	if err != nil {
+		return nil, err
+	}
+	startTime, err := parseTime(input.StartTime, "startTime")
+	if err != nil {
+		return nil, err
+	}
+
+	showtime, err := r.showtimeService.CreateShowtime(ctx, movieID, hallID, startTime, input.Price)
+	if err != nil {
+		return nil, err
+	}
+	return toShowtime(showtime), nil
+}
+
+// UpdateShowtime is the resolver for the updateShowtime field.
+func (r *mutationResolver) UpdateShowtime(ctx context.Context, id string, input model.UpdateShowtimeInput) (*model.Showtime, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	showtimeID, err := parseID(id, "showtime")
+	if err != nil {
+		return nil, err
+	}
+
+	changes := services.ShowtimeChanges{Price: input.Price}
+	if input.MovieID != nil {
+		movieID, err := parseID(*input.MovieID, "movie")
+		if err != nil {
+			return nil, err
+		}
+		changes.MovieID = &movieID
+	}
+	if input.HallID != nil {
+		hallID, err := parseID(*input.HallID, "hall")
+		if err != nil {
+			return nil, err
+		}
+		changes.HallID = &hallID
+	}
+	if input.StartTime != nil {
+		startTime, err := parseTime(*input.StartTime, "startTime")
+		if err != nil {
+			return nil, err
+		}
+		changes.StartTime = &startTime
+	}
+
+	showtime, err := r.showtimeService.UpdateShowtime(ctx, showtimeID, changes)
+	if err != nil {
+		return nil, err
+	}
+	return toShowtime(showtime), nil
+}
+
+// SetHallOrphanSeats is the resolver for the setHallOrphanSeats field.
+func (r *mutationResolver) SetHallOrphanSeats(ctx context.Context, hallID string, allow bool) (*model.Hall, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(hallID, "hall")
+	if err != nil {
+		return nil, err
+	}
+
+	hall, err := r.showtimeService.SetAllowOrphanSeats(ctx, id, allow)
+	if err != nil {
+		return nil, err
+	}
+	return toHall(hall), nil
+}
+
+// SetHallSeatKind is the resolver for the setHallSeatKind field.
+func (r *mutationResolver) SetHallSeatKind(ctx context.Context, hallID string, seats []*model.SeatPositionInput, kind model.SeatKind) (*model.Hall, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(hallID, "hall")
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]services.SeatPosition, len(seats))
+	for i, seat := range seats {
+		positions[i] = services.SeatPosition{Row: seat.Row, Number: seat.Number}
+	}
+
+	hall, err := r.showtimeService.SetHallSeatKind(ctx, id, positions, string(kind))
+	if err != nil {
+		return nil, err
+	}
+	return toHall(hall), nil
+}
+
+// CancelShowtime is the resolver for the cancelShowtime field.
+func (r *mutationResolver) CancelShowtime(ctx context.Context, id string, reason string) (*model.Showtime, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	showtimeID, err := parseID(id, "showtime")
+	if err != nil {
+		return nil, err
+	}
+
+	showtime, err := r.showtimeService.CancelShowtime(ctx, showtimeID, reason)
+	if err != nil {
+		return nil, err
+	}
+	return toShowtime(showtime), nil
+}
+
+// CreateSchedule is the resolver for the createSchedule field.
+func (r *mutationResolver) CreateSchedule(ctx context.Context, input model.ScheduleInput, skipConflicts *bool) (*model.ScheduleResult, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	rule, err := toScheduleRule(input, r.scheduleService.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.scheduleService.CreateSchedule(ctx, rule, skipConflicts != nil && *skipConflicts)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResult(result), nil
+}
+
+// UpdateSchedule is the resolver for the updateSchedule field.
+func (r *mutationResolver) UpdateSchedule(ctx context.Context, id string, input model.ScheduleInput, skipConflicts *bool) (*model.ScheduleResult, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	scheduleID, err := parseID(id, "schedule")
+	if err != nil {
+		return nil, err
+	}
+	rule, err := toScheduleRule(input, r.scheduleService.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.scheduleService.UpdateSchedule(ctx, scheduleID, rule, skipConflicts != nil && *skipConflicts)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResult(result), nil
+}
+
+// RemoveSchedule is the resolver for the removeSchedule field.
+func (r *mutationResolver) RemoveSchedule(ctx context.Context, id string) (*model.ScheduleResult, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	scheduleID, err := parseID(id, "schedule")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.scheduleService.RemoveSchedule(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResult(result), nil
+}
+
+// CreateWebhookSubscription is the resolver for the createWebhookSubscription field.
+func (r *mutationResolver) CreateWebhookSubscription(ctx context.Context, input model.CreateWebhookSubscriptionInput) (*model.WebhookSubscription, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var secret string
+	if input.Secret != nil {
+		secret = *input.Secret
+	}
+	subscription, err := r.webhookService.CreateSubscription(ctx, input.URL, toDomainEventTypes(input.EventTypes), secret)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookSubscription(subscription, true), nil
+}
+
+// UpdateWebhookSubscription is the resolver for the updateWebhookSubscription field.
+func (r *mutationResolver) UpdateWebhookSubscription(ctx context.Context, id string, input model.UpdateWebhookSubscriptionInput) (*model.WebhookSubscription, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	subscriptionID, err := parseID(id, "webhook subscription")
+	if err != nil {
+		return nil, err
+	}
+
+	changes := services.WebhookSubscriptionChanges{
+		URL:          input.URL,
+		Active:       input.Active,
+		RotateSecret: input.RotateSecret != nil && *input.RotateSecret,
+	}
+	if input.EventTypes != nil {
+		changes.EventTypes = toDomainEventTypes(input.EventTypes)
+	}
+	subscription, err := r.webhookService.UpdateSubscription(ctx, subscriptionID, changes)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookSubscription(subscription, changes.RotateSecret), nil
+}
+
+// DeleteWebhookSubscription is the resolver for the deleteWebhookSubscription field.
+func (r *mutationResolver) DeleteWebhookSubscription(ctx context.Context, id string) (bool, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return false, err
+	}
+
+	subscriptionID, err := parseID(id, "webhook subscription")
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.webhookService.DeleteSubscription(ctx, subscriptionID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReplayWebhookDelivery is the resolver for the replayWebhookDelivery field.
+func (r *mutationResolver) ReplayWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	deliveryID, err := parseID(id, "webhook delivery")
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := r.webhookService.ReplayDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookDelivery(delivery), nil
+}
+
 // Ping is the resolver for the ping field.
 func (r *queryResolver) Ping(ctx context.Context) (string, error) {
 	return "pong", nil
 }
 
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	user, err := r.userService.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := r.bookingService.GetUserBookings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := toUser(user)
+	for _, booking := range bookings {
+		result.Bookings = append(result.Bookings, toBooking(booking))
+	}
+
+	return result, nil
+}
+
 // Movies is the resolver for the movies field.
 func (r *queryResolver) Movies(ctx context.Context, page *int, limit *int) (*model.MoviesResponse, error) {
 	// Set default values if not provided
@@ -157,7 +672,7 @@ func (r *queryResolver) Movies(ctx context.Context, page *int, limit *int) (*mod
 func (r *queryResolver) Movie(ctx context.Context, id string) (*model.Movie, error) {
 	movieID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("invalid movie ID")
+		return nil, apperrors.Validation("invalid movie ID")
 	}
 
 	movie, err := r.movieService.GetMovieByID(uint(movieID))
@@ -177,12 +692,59 @@ func (r *queryResolver) Movie(ctx context.Context, id string) (*model.Movie, err
 
 // Showtimes is the resolver for the showtimes field.
 func (r *queryResolver) Showtimes(ctx context.Context) ([]*model.Showtime, error) {
-	panic(fmt.Errorf("not implemented: Showtimes - showtimes"))
+	showtimes, err := r.showtimeService.GetShowtimes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toShowtimes(showtimes), nil
 }
 
 // MovieShowtimes is the resolver for the movieShowtimes field.
 func (r *queryResolver) MovieShowtimes(ctx context.Context, movieID string) ([]*model.Showtime, error) {
-	panic(fmt.Errorf("not implemented: MovieShowtimes - movieShowtimes"))
+	id, err := parseID(movieID, "movie")
+	if err != nil {
+		return nil, err
+	}
+
+	showtimes, err := r.showtimeService.GetMovieShowtimes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toShowtimes(showtimes), nil
+}
+
+// SuggestSeats is the resolver for the suggestSeats field.
+func (r *queryResolver) SuggestSeats(ctx context.Context, showtimeID string, count int, preferences *model.SeatPreferences, accessibility *bool) ([]*model.SeatSuggestion, error) {
+	id, err := parseID(showtimeID, "showtime")
+	if err != nil {
+		return nil, err
+	}
+
+	accessible := accessibility != nil && *accessibility
+	suggestions, err := r.bookingService.SuggestSeats(ctx, id, count, 3, toSeatPreferences(preferences), accessible)
+	if err != nil {
+		return nil, err
+	}
+	return toSeatSuggestions(suggestions), nil
+}
+
+// WaitlistPosition is the resolver for the waitlistPosition field.
+func (r *queryResolver) WaitlistPosition(ctx context.Context, showtimeID string) (*model.WaitlistEntry, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	id, err := parseID(showtimeID, "showtime")
+	if err != nil {
+		return nil, err
+	}
+
+	place, err := r.bookingService.GetWaitlistPlace(ctx, userID, id)
+	if err != nil || place == nil {
+		return nil, err
+	}
+	return toWaitlistEntry(place), nil
 }
 
 // Booking is the resolver for the booking field.
@@ -190,13 +752,13 @@ func (r *queryResolver) Booking(ctx context.Context, id string) (*model.Booking,
 	// Get user ID from context using middleware function
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		return nil, fmt.Errorf("unauthorized")
+		return nil, apperrors.Unauthenticated("authentication required")
 	}
 
 	// Convert string ID to uint
 	bookingID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("invalid booking ID")
+		return nil, apperrors.Validation("invalid booking ID")
 	}
 
 	// Get booking using booking service
@@ -207,7 +769,7 @@ func (r *queryResolver) Booking(ctx context.Context, id string) (*model.Booking,
 
 	// Check if the booking belongs to the authenticated user
 	if booking.UserID != userID {
-		return nil, fmt.Errorf("unauthorized: booking does not belong to user")
+		return nil, apperrors.Forbidden("booking does not belong to user")
 	}
 
 	// Convert to GraphQL model
@@ -224,7 +786,7 @@ func (r *queryResolver) MyBookings(ctx context.Context) ([]*model.Booking, error
 	// Get user ID from context using middleware function
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		return nil, fmt.Errorf("unauthorized")
+		return nil, apperrors.Unauthenticated("authentication required")
 	}
 
 	// Get user's bookings
@@ -247,6 +809,111 @@ func (r *queryResolver) MyBookings(ctx context.Context) ([]*model.Booking, error
 	return result, nil
 }
 
+// Invoices is the resolver for the invoices field.
+func (r *queryResolver) Invoices(ctx context.Context, bookingID string) ([]*model.Invoice, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, apperrors.Unauthenticated("authentication required")
+	}
+
+	id, err := parseID(bookingID, "booking")
+	if err != nil {
+		return nil, err
+	}
+
+	invoices, err := r.documentService.GetInvoices(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Invoice, len(invoices))
+	for i, invoice := range invoices {
+		result[i] = toInvoice(invoice)
+	}
+	return result, nil
+}
+
+// Schedules is the resolver for the schedules field.
+func (r *queryResolver) Schedules(ctx context.Context) ([]*model.ShowtimeSchedule, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	schedules, err := r.scheduleService.GetSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.ShowtimeSchedule, len(schedules))
+	for i, schedule := range schedules {
+		result[i] = toSchedule(schedule)
+	}
+	return result, nil
+}
+
+// PreviewSchedule is the resolver for the previewSchedule field.
+func (r *queryResolver) PreviewSchedule(ctx context.Context, input model.ScheduleInput) ([]*model.ScheduleOccurrence, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	rule, err := toScheduleRule(input, r.scheduleService.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences, err := r.scheduleService.PreviewSchedule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	return toOccurrences(occurrences), nil
+}
+
+// WebhookSubscriptions is the resolver for the webhookSubscriptions field.
+func (r *queryResolver) WebhookSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := r.webhookService.GetSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.WebhookSubscription, len(subscriptions))
+	for i, subscription := range subscriptions {
+		result[i] = toWebhookSubscription(subscription, false)
+	}
+	return result, nil
+}
+
+// WebhookDeliveries is the resolver for the webhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, subscriptionID string, limit *int) ([]*model.WebhookDelivery, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(subscriptionID, "webhook subscription")
+	if err != nil {
+		return nil, err
+	}
+	limitNum := 50
+	if limit != nil {
+		limitNum = *limit
+	}
+
+	deliveries, err := r.webhookService.GetDeliveries(ctx, id, limitNum)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = toWebhookDelivery(delivery)
+	}
+	return result, nil
+}
+
 // SeatUpdates is the resolver for the seatUpdates field.
 func (r *subscriptionResolver) SeatUpdates(ctx context.Context, showtimeID string) (<-chan []*model.Seat, error) {
 	panic(fmt.Errorf("not implemented: SeatUpdates - seatUpdates"))
@@ -1,41 +1,280 @@
 package config
 
+import "time"
+
 type Config struct {
-	Database DatabaseConfig
-	Redis    RedisConfig
+	Server       ServerConfig       `yaml:"server"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Redis        RedisConfig        `yaml:"redis"`
+	Auth         AuthConfig         `yaml:"auth"`
+	Booking      BookingConfig      `yaml:"booking"`
+	Showtime     ShowtimeConfig     `yaml:"showtime"`
+	Notification NotificationConfig `yaml:"notification"`
+	Jobs         JobsConfig         `yaml:"jobs"`
+	Events       EventsConfig       `yaml:"events"`
+	Webhooks     WebhooksConfig     `yaml:"webhooks"`
+	Invoice      InvoiceConfig      `yaml:"invoice"`
+	OIDC         OIDCConfig         `yaml:"oidc"`
+	Export       ExportConfig       `yaml:"export"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Log          LogConfig          `yaml:"log"`
+
+	location *time.Location
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port"`
+	CORSOrigins       []string      `yaml:"cors_origins"`
+	Timezone          string        `yaml:"timezone"` // IANA name, e.g., "Asia/Ho_Chi_Minh"
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"` // time allowed for in-flight requests on shutdown
+	DrainDelay        time.Duration `yaml:"drain_delay"`      // time readiness fails before the listener is closed
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	DBName          string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 }
 
 type RedisConfig struct {
-	Host     string
-	Port     int
-	Password string
-	DB       int
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	PoolSize int    `yaml:"pool_size"`
+}
+
+type AuthConfig struct {
+	JWTSecret      string        `yaml:"jwt_secret"`
+	TokenTTL       time.Duration `yaml:"token_ttl"`        // lifetime of issued JWTs
+	OIDCStateTTL   time.Duration `yaml:"oidc_state_ttl"`   // time allowed to finish a social login
+	EmailChangeTTL time.Duration `yaml:"email_change_ttl"` // lifetime of email confirmation codes
+	ReauthWindow   time.Duration `yaml:"reauth_window"`    // how recent a sign-in confirms changes to accounts without a password
+}
+
+type BookingConfig struct {
+	SeatLockTTL         time.Duration `yaml:"seat_lock_ttl"`
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period"` // time booking transactions get to finish on shutdown
+	RecoveryInterval    time.Duration `yaml:"recovery_interval"`     // how often interrupted cancellations and refunds are resumed
+	ExchangeURL         string        `yaml:"exchange_url"`          // page offering a booking exchange, linked from cancellation emails
+
+	// Refunds for cancellations by the customer
+	FullRefundBefore     time.Duration `yaml:"full_refund_before"`     // full refund when cancelling at least this long before the show
+	PartialRefundBefore  time.Duration `yaml:"partial_refund_before"`  // partial refund when cancelling at least this long before the show
+	PartialRefundPercent float64       `yaml:"partial_refund_percent"` // share refunded in the partial refund window
+
+	// Reject seat selections that leave a single empty seat; halls can opt out
+	PreventOrphanSeats bool `yaml:"prevent_orphan_seats"`
+
+	// Unsold wheelchair spaces and companion seats go on general sale this long before the show
+	AccessibleReleaseBefore time.Duration `yaml:"accessible_release_before"`
+
+	// How long released seats are held for the next customer on the waitlist
+	WaitlistHoldTTL time.Duration `yaml:"waitlist_hold_ttl"`
+}
+
+type NotificationConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval"` // how often queued emails are sent
+	ReminderBefore   time.Duration `yaml:"reminder_before"`   // how long before the show booking holders are reminded
+	RatingDelay      time.Duration `yaml:"rating_delay"`      // how long after the show customers are asked to rate the movie
+	RatingURL        string        `yaml:"rating_url"`        // page for rating a movie, linked from the rating email
+	DefaultLocale    string        `yaml:"default_locale"`    // language of emails for customers without a translation in theirs
+	PreviewEnabled   bool          `yaml:"preview_enabled"`   // serve email template previews at /emails/preview
+}
+
+type EventsConfig struct {
+	RelayInterval  time.Duration `yaml:"relay_interval"`  // how often recorded domain events are published
+	RedisStream    string        `yaml:"redis_stream"`    // Redis stream events are appended to; empty to disable
+	WebhookURL     string        `yaml:"webhook_url"`     // URL events are posted to; empty to disable
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"` // time allowed for a webhook delivery
+	MaxAttempts    int           `yaml:"max_attempts"`    // publish attempts before an event is set aside
+	RetryDelay     time.Duration `yaml:"retry_delay"`     // wait before the first retry, doubling after every attempt
+}
+
+type WebhooksConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval"` // how often due partner webhook deliveries are sent
+	Timeout          time.Duration `yaml:"timeout"`           // time allowed for a partner to respond
+	MaxAttempts      int           `yaml:"max_attempts"`      // deliveries of an event before giving up
+	RetryDelay       time.Duration `yaml:"retry_delay"`       // wait before the first retry, doubling after every attempt
+}
+
+type InvoiceConfig struct {
+	CompanyName      string  `yaml:"company_name"`       // seller named on invoices
+	CompanyAddress   string  `yaml:"company_address"`    // postal address of the seller, lines separated by newlines
+	VATID            string  `yaml:"vat_id"`             // VAT identification number of the seller
+	TaxPercent       float64 `yaml:"tax_percent"`        // VAT included in ticket prices
+	Currency         string  `yaml:"currency"`           // ISO 4217 code of ticket prices
+	Prefix           string  `yaml:"prefix"`             // invoice numbers look like INV-2026-000001
+	CreditNotePrefix string  `yaml:"credit_note_prefix"` // credit note numbers look like CN-2026-000001
+}
+
+type JobsConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // how often due background jobs are looked for
+	MaxAttempts  int           `yaml:"max_attempts"`  // runs of a failing job before giving up
+	RetryDelay   time.Duration `yaml:"retry_delay"`   // wait before the first retry, doubling after every attempt
+}
+
+type ShowtimeConfig struct {
+	TrailerDuration time.Duration `yaml:"trailer_duration"` // ads and trailers before the movie starts
+	CleaningBuffer  time.Duration `yaml:"cleaning_buffer"`  // time to clean the hall after the movie ends
+}
+
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig `yaml:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name"` // e.g., "google", "apple"
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
 }
 
+type ExportConfig struct {
+	Dir         string        `yaml:"dir"` // where export archives are stored
+	TTL         time.Duration `yaml:"ttl"` // how long download links stay valid
+	DownloadURL string        `yaml:"download_url"`
+}
+
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter"` // none, stdout or otlp
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	ServiceName  string  `yaml:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // json or text
+}
+
+// NewConfig returns the default configuration
 func NewConfig() *Config {
 	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			CORSOrigins:       []string{},
+			Timezone:          "UTC",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
 		Database: DatabaseConfig{
-			Host:     "localhost",
-			Port:     5432,
-			User:     "postgres",
-			Password: "postgres",
-			DBName:   "movie_ticket_booking",
-			SSLMode:  "disable",
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "postgres",
+			DBName:          "movie_ticket_booking",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
 		},
 		Redis: RedisConfig{
 			Host:     "localhost",
 			Port:     6379,
 			Password: "",
 			DB:       0,
+			PoolSize: 10,
+		},
+		Auth: AuthConfig{
+			TokenTTL:       24 * time.Hour,
+			OIDCStateTTL:   10 * time.Minute,
+			EmailChangeTTL: 24 * time.Hour,
+			ReauthWindow:   5 * time.Minute,
+		},
+		Booking: BookingConfig{
+			SeatLockTTL:         5 * time.Minute,
+			ShutdownGracePeriod: 10 * time.Second,
+			RecoveryInterval:    time.Minute,
+			ExchangeURL:         "http://localhost:3000/bookings/exchange",
+
+			FullRefundBefore:     24 * time.Hour,
+			PartialRefundBefore:  2 * time.Hour,
+			PartialRefundPercent: 50,
+
+			PreventOrphanSeats: true,
+
+			AccessibleReleaseBefore: time.Hour,
+
+			WaitlistHoldTTL: 15 * time.Minute,
+		},
+		Notification: NotificationConfig{
+			DeliveryInterval: 10 * time.Second,
+			ReminderBefore:   3 * time.Hour,
+			RatingDelay:      time.Hour,
+			RatingURL:        "http://localhost:3000/rate",
+			DefaultLocale:    "en",
+		},
+		Events: EventsConfig{
+			RelayInterval:  time.Second,
+			RedisStream:    "booking-events",
+			WebhookTimeout: 5 * time.Second,
+			MaxAttempts:    12,
+			RetryDelay:     time.Second,
+		},
+		Webhooks: WebhooksConfig{
+			DeliveryInterval: 5 * time.Second,
+			Timeout:          10 * time.Second,
+			MaxAttempts:      8,
+			RetryDelay:       30 * time.Second,
+		},
+		Invoice: InvoiceConfig{
+			CompanyName:      "Movie Ticket Booking",
+			TaxPercent:       19,
+			Currency:         "EUR",
+			Prefix:           "INV",
+			CreditNotePrefix: "CN",
+		},
+		Jobs: JobsConfig{
+			PollInterval: 5 * time.Second,
+			MaxAttempts:  10,
+			RetryDelay:   30 * time.Second,
+		},
+		Showtime: ShowtimeConfig{
+			TrailerDuration: 15 * time.Minute,
+			CleaningBuffer:  15 * time.Minute,
+		},
+		OIDC: OIDCConfig{
+			Providers: []OIDCProviderConfig{},
+		},
+		Export: ExportConfig{
+			Dir:         "data/exports",
+			TTL:         7 * 24 * time.Hour,
+			DownloadURL: "http://localhost:8080/exports/download",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318/v1/traces",
+			ServiceName:  "movie-ticket-booking",
+			SampleRatio:  1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
 		},
 	}
-}
\ No newline at end of file
+}
+
+// Location returns the cinema's timezone, UTC until the configuration is validated
+func (c *Config) Location() *time.Location {
+	if c.location == nil {
+		return time.UTC
+	}
+	return c.location
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from the defaults, an optional YAML file named
+// by CONFIG_FILE and environment variables, in increasing order of precedence.
+// The result is validated and every problem found is reported at once.
+func Load() (*Config, error) {
+	return load((*Config).Validate)
+}
+
+// LoadDatabase is like Load but only validates the database settings, so the
+// migrate command runs without the secrets the server needs
+func LoadDatabase() (*Config, error) {
+	return load((*Config).ValidateDatabase)
+}
+
+func load(validate func(*Config) error) (*Config, error) {
+	cfg := NewConfig()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Report malformed variables together with validation problems
+	envErr := cfg.loadEnv()
+	if err := errors.Join(envErr, validate(cfg)); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	env := &envLoader{}
+
+	env.int("PORT", &c.Server.Port)
+	env.list("CORS_ALLOWED_ORIGINS", &c.Server.CORSOrigins)
+	env.string("TIMEZONE", &c.Server.Timezone)
+	env.duration("HTTP_READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.duration("HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	env.duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	env.duration("SHUTDOWN_DRAIN_DELAY", &c.Server.DrainDelay)
+
+	env.string("DB_HOST", &c.Database.Host)
+	env.int("DB_PORT", &c.Database.Port)
+	env.string("DB_USER", &c.Database.User)
+	env.string("DB_PASSWORD", &c.Database.Password)
+	env.string("DB_NAME", &c.Database.DBName)
+	env.string("DB_SSLMODE", &c.Database.SSLMode)
+	env.int("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+
+	env.string("REDIS_HOST", &c.Redis.Host)
+	env.int("REDIS_PORT", &c.Redis.Port)
+	env.string("REDIS_PASSWORD", &c.Redis.Password)
+	env.int("REDIS_DB", &c.Redis.DB)
+	env.int("REDIS_POOL_SIZE", &c.Redis.PoolSize)
+
+	env.string("JWT_SECRET", &c.Auth.JWTSecret)
+	env.duration("JWT_TOKEN_TTL", &c.Auth.TokenTTL)
+	env.duration("OIDC_STATE_TTL", &c.Auth.OIDCStateTTL)
+	env.duration("EMAIL_CHANGE_TTL", &c.Auth.EmailChangeTTL)
+	env.duration("AUTH_REAUTH_WINDOW", &c.Auth.ReauthWindow)
+
+	env.duration("SEAT_LOCK_TTL", &c.Booking.SeatLockTTL)
+	env.duration("BOOKING_SHUTDOWN_GRACE_PERIOD", &c.Booking.ShutdownGracePeriod)
+	env.duration("BOOKING_RECOVERY_INTERVAL", &c.Booking.RecoveryInterval)
+	env.string("BOOKING_EXCHANGE_URL", &c.Booking.ExchangeURL)
+	env.duration("BOOKING_FULL_REFUND_BEFORE", &c.Booking.FullRefundBefore)
+	env.duration("BOOKING_PARTIAL_REFUND_BEFORE", &c.Booking.PartialRefundBefore)
+	env.float("BOOKING_PARTIAL_REFUND_PERCENT", &c.Booking.PartialRefundPercent)
+	env.bool("BOOKING_PREVENT_ORPHAN_SEATS", &c.Booking.PreventOrphanSeats)
+	env.duration("BOOKING_ACCESSIBLE_RELEASE_BEFORE", &c.Booking.AccessibleReleaseBefore)
+	env.duration("BOOKING_WAITLIST_HOLD_TTL", &c.Booking.WaitlistHoldTTL)
+
+	env.duration("NOTIFICATION_DELIVERY_INTERVAL", &c.Notification.DeliveryInterval)
+	env.duration("NOTIFICATION_REMINDER_BEFORE", &c.Notification.ReminderBefore)
+	env.duration("NOTIFICATION_RATING_DELAY", &c.Notification.RatingDelay)
+	env.string("NOTIFICATION_RATING_URL", &c.Notification.RatingURL)
+	env.string("NOTIFICATION_DEFAULT_LOCALE", &c.Notification.DefaultLocale)
+	env.bool("NOTIFICATION_PREVIEW_ENABLED", &c.Notification.PreviewEnabled)
+
+	env.duration("JOBS_POLL_INTERVAL", &c.Jobs.PollInterval)
+	env.int("JOBS_MAX_ATTEMPTS", &c.Jobs.MaxAttempts)
+	env.duration("JOBS_RETRY_DELAY", &c.Jobs.RetryDelay)
+
+	env.duration("EVENTS_RELAY_INTERVAL", &c.Events.RelayInterval)
+	env.string("EVENTS_REDIS_STREAM", &c.Events.RedisStream)
+	env.string("EVENTS_WEBHOOK_URL", &c.Events.WebhookURL)
+	env.duration("EVENTS_WEBHOOK_TIMEOUT", &c.Events.WebhookTimeout)
+	env.int("EVENTS_MAX_ATTEMPTS", &c.Events.MaxAttempts)
+	env.duration("EVENTS_RETRY_DELAY", &c.Events.RetryDelay)
+
+	env.duration("WEBHOOKS_DELIVERY_INTERVAL", &c.Webhooks.DeliveryInterval)
+	env.duration("WEBHOOKS_TIMEOUT", &c.Webhooks.Timeout)
+	env.int("WEBHOOKS_MAX_ATTEMPTS", &c.Webhooks.MaxAttempts)
+	env.duration("WEBHOOKS_RETRY_DELAY", &c.Webhooks.RetryDelay)
+
+	env.string("INVOICE_COMPANY_NAME", &c.Invoice.CompanyName)
+	env.string("INVOICE_COMPANY_ADDRESS", &c.Invoice.CompanyAddress)
+	env.string("INVOICE_VAT_ID", &c.Invoice.VATID)
+	env.float("INVOICE_TAX_PERCENT", &c.Invoice.TaxPercent)
+	env.string("INVOICE_CURRENCY", &c.Invoice.Currency)
+	env.string("INVOICE_PREFIX", &c.Invoice.Prefix)
+	env.string("INVOICE_CREDIT_NOTE_PREFIX", &c.Invoice.CreditNotePrefix)
+
+	env.duration("SHOWTIME_TRAILER_DURATION", &c.Showtime.TrailerDuration)
+	env.duration("SHOWTIME_CLEANING_BUFFER", &c.Showtime.CleaningBuffer)
+
+	env.string("EXPORT_DIR", &c.Export.Dir)
+	env.duration("EXPORT_TTL", &c.Export.TTL)
+	env.string("EXPORT_DOWNLOAD_URL", &c.Export.DownloadURL)
+
+	env.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	env.string("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", &c.Tracing.OTLPEndpoint)
+	env.string("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	env.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	env.string("LOG_LEVEL", &c.Log.Level)
+	env.string("LOG_FORMAT", &c.Log.Format)
+
+	return errors.Join(env.errs...)
+}
+
+// envLoader overrides configuration values with the environment variables that
+// are set, collecting parse errors instead of stopping at the first one
+type envLoader struct {
+	errs []error
+}
+
+func (l *envLoader) string(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+func (l *envLoader) int(key string, dst *int) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+		return
+	}
+	*dst = n
+}
+
+func (l *envLoader) float(key string, dst *float64) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid number %q", key, v))
+		return
+	}
+	*dst = f
+}
+
+func (l *envLoader) bool(key string, dst *bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid boolean %q", key, v))
+		return
+	}
+	*dst = b
+}
+
+func (l *envLoader) duration(key string, dst *time.Duration) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid duration %q", key, v))
+		return
+	}
+	*dst = d
+}
+
+func (l *envLoader) list(key string, dst *[]string) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	items := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	minJWTSecretLength = 32
+	redacted           = "[REDACTED]"
+)
+
+// Validate checks the configuration and returns all problems found
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("server.port must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("server.cors_origins: invalid origin %q", origin)
+		}
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.ReadHeaderTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		fail("server read, read header, write and idle timeouts must be positive")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		fail("server.drain_delay must not be negative")
+	}
+	if loc, err := time.LoadLocation(c.Server.Timezone); err != nil {
+		fail("server.timezone: unknown timezone %q", c.Server.Timezone)
+	} else {
+		c.location = loc
+	}
+
+	errs = append(errs, c.ValidateDatabase())
+
+	if c.Redis.Host == "" {
+		fail("redis.host is required")
+	}
+	if c.Redis.Port < 1 || c.Redis.Port > 65535 {
+		fail("redis.port must be between 1 and 65535, got %d", c.Redis.Port)
+	}
+	if c.Redis.DB < 0 {
+		fail("redis.db must not be negative, got %d", c.Redis.DB)
+	}
+	if c.Redis.PoolSize < 1 {
+		fail("redis.pool_size must be positive, got %d", c.Redis.PoolSize)
+	}
+
+	if len(c.Auth.JWTSecret) < minJWTSecretLength {
+		fail("auth.jwt_secret must be at least %d characters", minJWTSecretLength)
+	}
+	if c.Auth.TokenTTL <= 0 {
+		fail("auth.token_ttl must be positive")
+	}
+	if c.Auth.OIDCStateTTL <= 0 {
+		fail("auth.oidc_state_ttl must be positive")
+	}
+	if c.Auth.EmailChangeTTL <= 0 {
+		fail("auth.email_change_ttl must be positive")
+	}
+	if c.Auth.ReauthWindow <= 0 {
+		fail("auth.reauth_window must be positive")
+	}
+
+	if c.Booking.SeatLockTTL <= 0 {
+		fail("booking.seat_lock_ttl must be positive")
+	}
+	if c.Booking.ShutdownGracePeriod <= 0 || c.Booking.ShutdownGracePeriod > c.Server.ShutdownTimeout {
+		fail("booking.shutdown_grace_period must be positive and not exceed server.shutdown_timeout")
+	}
+	if c.Booking.RecoveryInterval <= 0 {
+		fail("booking.recovery_interval must be positive")
+	}
+	if c.Booking.ExchangeURL == "" {
+		fail("booking.exchange_url is required")
+	}
+	if c.Booking.PartialRefundBefore < 0 || c.Booking.FullRefundBefore < c.Booking.PartialRefundBefore {
+		fail("booking.full_refund_before must not be less than booking.partial_refund_before, which must not be negative")
+	}
+	if c.Booking.PartialRefundPercent < 0 || c.Booking.PartialRefundPercent > 100 {
+		fail("booking.partial_refund_percent must be between 0 and 100")
+	}
+	if c.Booking.AccessibleReleaseBefore < 0 {
+		fail("booking.accessible_release_before must not be negative")
+	}
+	if c.Booking.WaitlistHoldTTL <= 0 {
+		fail("booking.waitlist_hold_ttl must be positive")
+	}
+	if c.Notification.DeliveryInterval <= 0 {
+		fail("notification.delivery_interval must be positive")
+	}
+	if c.Notification.ReminderBefore <= 0 {
+		fail("notification.reminder_before must be positive")
+	}
+	if c.Notification.RatingDelay < 0 {
+		fail("notification.rating_delay must not be negative")
+	}
+	if c.Notification.RatingURL == "" {
+		fail("notification.rating_url is required")
+	}
+	if c.Notification.DefaultLocale == "" {
+		fail("notification.default_locale is required")
+	}
+	if c.Jobs.PollInterval <= 0 {
+		fail("jobs.poll_interval must be positive")
+	}
+	if c.Jobs.MaxAttempts < 1 || c.Jobs.MaxAttempts > 20 {
+		fail("jobs.max_attempts must be between 1 and 20")
+	}
+	if c.Jobs.RetryDelay <= 0 {
+		fail("jobs.retry_delay must be positive")
+	}
+	if c.Events.RelayInterval <= 0 {
+		fail("events.relay_interval must be positive")
+	}
+	if c.Events.WebhookURL != "" {
+		if u, err := url.Parse(c.Events.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("events.webhook_url must be an absolute URL")
+		}
+		if c.Events.WebhookTimeout <= 0 {
+			fail("events.webhook_timeout must be positive")
+		}
+	}
+	if c.Events.MaxAttempts < 1 || c.Events.MaxAttempts > 20 {
+		fail("events.max_attempts must be between 1 and 20")
+	}
+	if c.Events.RetryDelay <= 0 {
+		fail("events.retry_delay must be positive")
+	}
+	if c.Webhooks.DeliveryInterval <= 0 {
+		fail("webhooks.delivery_interval must be positive")
+	}
+	if c.Webhooks.Timeout <= 0 {
+		fail("webhooks.timeout must be positive")
+	}
+	if c.Webhooks.MaxAttempts < 1 || c.Webhooks.MaxAttempts > 20 {
+		fail("webhooks.max_attempts must be between 1 and 20")
+	}
+	if c.Webhooks.RetryDelay <= 0 {
+		fail("webhooks.retry_delay must be positive")
+	}
+	if c.Invoice.CompanyName == "" {
+		fail("invoice.company_name is required")
+	}
+	if c.Invoice.TaxPercent < 0 || c.Invoice.TaxPercent >= 100 {
+		fail("invoice.tax_percent must be between 0 and 100")
+	}
+	if len(c.Invoice.Currency) != 3 {
+		fail("invoice.currency must be a three-letter currency code")
+	}
+	if c.Invoice.Prefix == "" || c.Invoice.CreditNotePrefix == "" {
+		fail("invoice.prefix and invoice.credit_note_prefix are required")
+	} else if c.Invoice.Prefix == c.Invoice.CreditNotePrefix {
+		fail("invoice.prefix and invoice.credit_note_prefix must differ")
+	}
+
+	if c.Showtime.TrailerDuration < 0 {
+		fail("showtime.trailer_duration must not be negative")
+	}
+	if c.Showtime.CleaningBuffer < 0 {
+		fail("showtime.cleaning_buffer must not be negative")
+	}
+
+	names := make(map[string]bool)
+	for i, provider := range c.OIDC.Providers {
+		if provider.Name == "" {
+			fail("oidc.providers[%d].name is required", i)
+		} else if names[provider.Name] {
+			fail("oidc.providers[%d]: duplicate provider %q", i, provider.Name)
+		}
+		names[provider.Name] = true
+		if u, err := url.Parse(provider.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("oidc.providers[%d].issuer must be an absolute URL", i)
+		}
+		if provider.ClientID == "" {
+			fail("oidc.providers[%d].client_id is required", i)
+		}
+		if provider.RedirectURL == "" {
+			fail("oidc.providers[%d].redirect_url is required", i)
+		}
+	}
+
+	if c.Export.Dir == "" {
+		fail("export.dir is required")
+	}
+	if c.Export.TTL <= 0 {
+		fail("export.ttl must be positive")
+	}
+	if u, err := url.Parse(c.Export.DownloadURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("export.download_url must be an absolute URL")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("tracing.otlp_endpoint must be an absolute URL")
+		}
+	default:
+		fail("tracing.exporter must be one of none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.ServiceName == "" {
+		fail("tracing.service_name is required")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("log.level must be one of debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		fail("log.format must be json or text, got %q", c.Log.Format)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateDatabase checks only the database settings, for tools such as the
+// migrate command that connect to nothing else
+func (c *Config) ValidateDatabase() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Database.Host == "" {
+		fail("database.host is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		fail("database.port must be between 1 and 65535, got %d", c.Database.Port)
+	}
+	if c.Database.User == "" {
+		fail("database.user is required")
+	}
+	if c.Database.DBName == "" {
+		fail("database.name is required")
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		fail("database.sslmode: invalid mode %q", c.Database.SSLMode)
+	}
+	if c.Database.MaxOpenConns < 1 {
+		fail("database.max_open_conns must be positive, got %d", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns must be between 0 and max_open_conns, got %d", c.Database.MaxIdleConns)
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		fail("database.conn_max_lifetime must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration with secrets masked, safe for logging
+func (c *Config) Redacted() Config {
+	r := *c
+	r.Database.Password = redactSecret(r.Database.Password)
+	r.Redis.Password = redactSecret(r.Redis.Password)
+	r.Auth.JWTSecret = redactSecret(r.Auth.JWTSecret)
+
+	r.OIDC.Providers = make([]OIDCProviderConfig, len(c.OIDC.Providers))
+	for i, provider := range c.OIDC.Providers {
+		provider.ClientSecret = redactSecret(provider.ClientSecret)
+		r.OIDC.Providers[i] = provider
+	}
+	return r
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
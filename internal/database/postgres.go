@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"movie-ticket-booking/internal/config"
 
@@ -28,9 +29,26 @@ func NewPostgresDB(cfg *config.Config) (*PostgresDB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+
 	return &PostgresDB{DB: db}, nil
 }
 
+// Ping checks that the database is reachable
+func (p *PostgresDB) Ping(ctx context.Context) error {
+	db, err := p.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+	return db.PingContext(ctx)
+}
+
 func (p *PostgresDB) Close() error {
 	db, err := p.DB.DB()
 	if err != nil {
@@ -1,8 +1,10 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"movie-ticket-booking/internal/config"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -16,11 +18,24 @@ func NewRedisClient(cfg *config.Config) (*RedisClient, error) {
 		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
 		Password: cfg.Redis.Password,
 		DB:       cfg.Redis.DB,
+		PoolSize: cfg.Redis.PoolSize,
 	})
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
 	return &RedisClient{Client: client}, nil
 }
 
+// Ping checks that Redis is reachable
+func (r *RedisClient) Ping(ctx context.Context) error {
+	return r.Client.Ping(ctx).Err()
+}
+
 func (r *RedisClient) Close() error {
 	return r.Client.Close()
 }
\ No newline at end of file
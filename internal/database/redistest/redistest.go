@@ -0,0 +1,40 @@
+// Package redistest provides Redis clients for tests. Tests using it are
+// skipped unless TEST_REDIS_URL names a Redis database reserved for tests,
+// e.g. redis://localhost:6379/15. The database is flushed before and after
+// every test, so tests using it must not run in parallel.
+package redistest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// New returns a client of the empty test database
+func New(t testing.TB) *redis.Client {
+	t.Helper()
+
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL is not set")
+	}
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("invalid TEST_REDIS_URL: %v", err)
+	}
+
+	client := redis.NewClient(options)
+	if err := client.FlushDB(context.Background()).Err(); err != nil {
+		client.Close()
+		t.Fatalf("failed to flush test Redis database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := client.FlushDB(context.Background()).Err(); err != nil {
+			t.Errorf("failed to flush test Redis database: %v", err)
+		}
+		client.Close()
+	})
+	return client
+}
@@ -0,0 +1,207 @@
+// Package documents renders printable PDF tickets and invoices. Rendering is
+// pure Go, so no external binaries are needed.
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Company is the seller named on invoices
+type Company struct {
+	Name    string
+	Address string // lines separated by newlines
+	VATID   string
+}
+
+// Ticket is one seat of a booking, printed on its own page
+type Ticket struct {
+	BookingID uint
+	Customer  string
+	Movie     string
+	Hall      string
+	StartTime time.Time
+	Seat      string
+	Code      string
+	Price     float64
+	Currency  string
+}
+
+// Invoice is an invoice or a credit note. Amounts are positive; credit notes
+// print them as negative.
+type Invoice struct {
+	Company    Company
+	CreditNote bool
+	Number     string
+	IssuedAt   time.Time
+	// Number of the invoice a credit note corrects
+	CorrectsNumber string
+	BookingID      uint
+	BuyerName      string
+	BuyerEmail     string
+	Lines          []InvoiceLine
+	Net            float64
+	Tax            float64
+	Gross          float64
+	TaxPercent     float64
+	Currency       string
+}
+
+// InvoiceLine is an item of an invoice, priced including tax
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unitPrice"`
+}
+
+// WriteTickets renders tickets as a PDF with one page per seat
+func WriteTickets(w io.Writer, tickets []Ticket) error {
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetTitle("Tickets", true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, ticket := range tickets {
+		pdf.AddPage()
+		pageWidth, _ := pdf.GetPageSize()
+		left, _, right, _ := pdf.GetMargins()
+		width := pageWidth - left - right
+
+		pdf.SetFont("Helvetica", "B", 20)
+		pdf.MultiCell(width, 9, tr(ticket.Movie), "", "L", false)
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 12)
+		field(pdf, tr, "Date", ticket.StartTime.Format("Monday 2 January 2006"))
+		field(pdf, tr, "Time", ticket.StartTime.Format("15:04"))
+		field(pdf, tr, "Hall", ticket.Hall)
+		field(pdf, tr, "Seat", ticket.Seat)
+		field(pdf, tr, "Price", money(ticket.Price, ticket.Currency))
+		field(pdf, tr, "Booking", fmt.Sprintf("#%d", ticket.BookingID))
+		field(pdf, tr, "Name", ticket.Customer)
+
+		png, err := qrcode.Encode(ticket.Code, qrcode.Medium, 512)
+		if err != nil {
+			return fmt.Errorf("failed to encode QR code: %w", err)
+		}
+		name := fmt.Sprintf("qr-%d", i)
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(png))
+		size := 70.0
+		y := pdf.GetY() + 6
+		pdf.ImageOptions(name, left+(width-size)/2, y, size, size, false, options, 0, "")
+		pdf.SetY(y + size + 2)
+		pdf.SetFont("Courier", "", 12)
+		pdf.CellFormat(width, 6, ticket.Code, "", 1, "C", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(width, 5, tr("Show this code at the entrance. Valid for one admission."), "", 1, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	return pdf.Output(w)
+}
+
+// WriteInvoice renders an invoice or credit note as a PDF
+func WriteInvoice(w io.Writer, invoice Invoice) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	title := "Invoice"
+	sign := 1.0
+	if invoice.CreditNote {
+		title = "Credit note"
+		sign = -1
+	}
+	pdf.SetTitle(fmt.Sprintf("%s %s", title, invoice.Number), true)
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	// Seller
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(width, 7, tr(invoice.Company.Name), "", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range strings.Split(invoice.Company.Address, "\n") {
+		pdf.CellFormat(width, 5, tr(line), "", 1, "R", false, 0, "")
+	}
+	if invoice.Company.VATID != "" {
+		pdf.CellFormat(width, 5, tr("VAT ID: "+invoice.Company.VATID), "", 1, "R", false, 0, "")
+	}
+
+	// Buyer
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.CellFormat(width, 6, tr(invoice.BuyerName), "", 1, "L", false, 0, "")
+	pdf.CellFormat(width, 6, tr(invoice.BuyerEmail), "", 1, "L", false, 0, "")
+
+	// Header
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(width, 10, tr(title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	field(pdf, tr, "Number", invoice.Number)
+	field(pdf, tr, "Date", invoice.IssuedAt.Format("2 January 2006"))
+	field(pdf, tr, "Booking", fmt.Sprintf("#%d", invoice.BookingID))
+	if invoice.CorrectsNumber != "" {
+		field(pdf, tr, "Corrects", "Invoice "+invoice.CorrectsNumber)
+	}
+
+	// Items, priced including tax
+	pdf.Ln(8)
+	columns := []float64{width - 90, 20, 35, 35}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	for i, heading := range []string{"Description", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(columns[i], 7, tr(heading), "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range invoice.Lines {
+		pdf.CellFormat(columns[0], 7, tr(line.Description), "", 0, "L", false, 0, "")
+		pdf.CellFormat(columns[1], 7, fmt.Sprintf("%d", line.Quantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(columns[2], 7, tr(money(sign*line.UnitPrice, invoice.Currency)), "", 0, "R", false, 0, "")
+		pdf.CellFormat(columns[3], 7, tr(money(sign*line.UnitPrice*float64(line.Quantity), invoice.Currency)), "", 1, "R", false, 0, "")
+	}
+
+	// Tax breakdown
+	pdf.Ln(4)
+	labelWidth := columns[0] + columns[1] + columns[2]
+	total := func(label string, amount float64, style string) {
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(labelWidth, 7, tr(label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(columns[3], 7, tr(money(sign*amount, invoice.Currency)), "", 1, "R", false, 0, "")
+	}
+	total("Net amount", invoice.Net, "")
+	total(fmt.Sprintf("VAT %s%%", strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", invoice.TaxPercent), "0"), ".")), invoice.Tax, "")
+	total("Total", invoice.Gross, "B")
+
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(100, 100, 100)
+	note := "Paid in full. Thank you for your purchase."
+	if invoice.CreditNote {
+		note = "The amount has been refunded to the original payment method."
+	}
+	pdf.MultiCell(width, 5, tr(note), "", "L", false)
+	return pdf.Output(w)
+}
+
+// field writes a label and value on one line
+func field(pdf *fpdf.Fpdf, tr func(string) string, label, value string) {
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(30, 6, tr(label), "", 0, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 6, tr(value), "", 1, "L", false, 0, "")
+}
+
+func money(amount float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
@@ -0,0 +1,83 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// pageObject matches the page objects of a PDF but not the page tree
+var pageObject = regexp.MustCompile(`/Type\s*/Page\b[^s]`)
+
+func checkPDF(t *testing.T, pdf []byte, wantPages int) {
+	t.Helper()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Fatalf("output starts with %q, want a PDF header", pdf[:min(len(pdf), 8)])
+	}
+	if !bytes.Contains(pdf[max(0, len(pdf)-1024):], []byte("%%EOF")) {
+		t.Error("PDF has no end-of-file marker")
+	}
+	if pages := len(pageObject.FindAll(pdf, -1)); pages != wantPages {
+		t.Errorf("PDF has %d pages, want %d", pages, wantPages)
+	}
+}
+
+func TestWriteTicketsPrintsOnePagePerTicket(t *testing.T) {
+	start := time.Date(2026, 10, 20, 19, 30, 0, 0, time.UTC)
+	for _, seats := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d seats", seats), func(t *testing.T) {
+			tickets := make([]Ticket, seats)
+			for i := range tickets {
+				tickets[i] = Ticket{
+					BookingID: 42,
+					Customer:  "Jürgen Groß",
+					Movie:     "Metropolis",
+					Hall:      "Hall 1",
+					StartTime: start,
+					Seat:      fmt.Sprintf("A%d", i+1),
+					Code:      fmt.Sprintf("CODE%d", i+1),
+					Price:     12.5,
+					Currency:  "EUR",
+				}
+			}
+			var buf bytes.Buffer
+			if err := WriteTickets(&buf, tickets); err != nil {
+				t.Fatal(err)
+			}
+			checkPDF(t, buf.Bytes(), seats)
+		})
+	}
+}
+
+func TestWriteInvoice(t *testing.T) {
+	for _, creditNote := range []bool{false, true} {
+		invoice := Invoice{
+			Company:    Company{Name: "Kino am Markt", Address: "Marktplatz 1\n10115 Berlin", VATID: "DE123456789"},
+			CreditNote: creditNote,
+			Number:     "INV-2026-000001",
+			IssuedAt:   time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			BookingID:  42,
+			BuyerName:  "Jürgen Groß",
+			BuyerEmail: "juergen@example.com",
+			Lines: []InvoiceLine{
+				{Description: "Ticket Metropolis, 20 Oct 2026 19:30, seat A1", Quantity: 1, UnitPrice: 12.5},
+				{Description: "Ticket Metropolis, 20 Oct 2026 19:30, seat A2", Quantity: 1, UnitPrice: 12.5},
+			},
+			Net:        21.01,
+			Tax:        3.99,
+			Gross:      25,
+			TaxPercent: 19,
+			Currency:   "EUR",
+		}
+		if creditNote {
+			invoice.Number, invoice.CorrectsNumber = "CN-2026-000001", "INV-2026-000001"
+		}
+		var buf bytes.Buffer
+		if err := WriteInvoice(&buf, invoice); err != nil {
+			t.Fatalf("credit note %v: %v", creditNote, err)
+		}
+		checkPDF(t, buf.Bytes(), 1)
+	}
+}
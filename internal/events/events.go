@@ -0,0 +1,118 @@
+// Package events records domain events in a transactional outbox and relays
+// them to sinks such as Redis streams and webhooks. An event is only published
+// if the transaction that recorded it commits, and events of one aggregate are
+// published in the order they were recorded.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"movie-ticket-booking/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types of domain events
+const (
+	TypeBookingCreated    = "BookingCreated"
+	TypeBookingCancelled  = "BookingCancelled"
+	TypeSeatsReleased     = "SeatsReleased"
+	TypeShowtimeCancelled = "ShowtimeCancelled"
+	TypePaymentSucceeded  = "PaymentSucceeded"
+)
+
+// Types of aggregates events belong to
+const (
+	AggregateBooking  = "booking"
+	AggregateShowtime = "showtime"
+)
+
+// Event is a published domain event. Events may be delivered more than once;
+// consumers can use the ID to ignore duplicates.
+type Event struct {
+	ID            uint            `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateID   uint            `json:"aggregateId"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// Payload is the data of a domain event
+type Payload interface {
+	// EventType returns the type of the event
+	EventType() string
+	// Aggregate returns the type and ID of the aggregate the event belongs to
+	Aggregate() (string, uint)
+}
+
+// Record writes an event to the outbox within tx
+func Record(tx *gorm.DB, payload Payload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", payload.EventType(), err)
+	}
+	aggregateType, aggregateID := payload.Aggregate()
+	return tx.Create(&models.OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          payload.EventType(),
+		Payload:       string(data),
+	}).Error
+}
+
+// BookingCreated is recorded when a booking is confirmed, including bookings
+// made by exchanging another one
+type BookingCreated struct {
+	BookingID       uint    `json:"bookingId"`
+	UserID          uint    `json:"userId"`
+	ShowtimeID      uint    `json:"showtimeId"`
+	SeatIDs         []uint  `json:"seatIds"`
+	TotalAmount     float64 `json:"totalAmount"`
+	ExchangedFromID *uint   `json:"exchangedFromId,omitempty"`
+}
+
+func (e BookingCreated) EventType() string         { return TypeBookingCreated }
+func (e BookingCreated) Aggregate() (string, uint) { return AggregateBooking, e.BookingID }
+
+// BookingCancelled is recorded when a whole booking is cancelled
+type BookingCancelled struct {
+	BookingID  uint   `json:"bookingId"`
+	UserID     uint   `json:"userId"`
+	ShowtimeID uint   `json:"showtimeId"`
+	Reason     string `json:"reason"`
+}
+
+func (e BookingCancelled) EventType() string         { return TypeBookingCancelled }
+func (e BookingCancelled) Aggregate() (string, uint) { return AggregateBooking, e.BookingID }
+
+// SeatsReleased is recorded when seats of a showtime become available again
+type SeatsReleased struct {
+	ShowtimeID uint   `json:"showtimeId"`
+	SeatIDs    []uint `json:"seatIds"`
+}
+
+func (e SeatsReleased) EventType() string         { return TypeSeatsReleased }
+func (e SeatsReleased) Aggregate() (string, uint) { return AggregateShowtime, e.ShowtimeID }
+
+// ShowtimeCancelled is recorded when a showtime is taken off the programme
+type ShowtimeCancelled struct {
+	ShowtimeID uint   `json:"showtimeId"`
+	Reason     string `json:"reason"`
+}
+
+func (e ShowtimeCancelled) EventType() string         { return TypeShowtimeCancelled }
+func (e ShowtimeCancelled) Aggregate() (string, uint) { return AggregateShowtime, e.ShowtimeID }
+
+// PaymentSucceeded is recorded when a charge or refund of a booking goes through
+type PaymentSucceeded struct {
+	PaymentID uint    `json:"paymentId"`
+	BookingID uint    `json:"bookingId"`
+	Kind      string  `json:"kind"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
+}
+
+func (e PaymentSucceeded) EventType() string         { return TypePaymentSucceeded }
+func (e PaymentSucceeded) Aggregate() (string, uint) { return AggregateBooking, e.BookingID }
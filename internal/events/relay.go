@@ -0,0 +1,197 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// relayLockKey is the Postgres advisory lock held by the replica relaying
+// events, so that events are published by one replica at a time, in order
+const relayLockKey = 7_047_001
+
+// relayBatchSize is how many events are published per transaction
+const relayBatchSize = 100
+
+// Relay publishes the events in the outbox to every sink. Failed events are
+// retried with exponential backoff and set aside after maxAttempts; a retry
+// only goes to the sinks that haven't received the event yet.
+type Relay struct {
+	db          *gorm.DB
+	maxAttempts int
+	retryDelay  time.Duration
+	sinks       []Sink
+}
+
+func NewRelay(db *gorm.DB, maxAttempts int, retryDelay time.Duration, sinks ...Sink) *Relay {
+	return &Relay{db: db, maxAttempts: maxAttempts, retryDelay: retryDelay, sinks: sinks}
+}
+
+// Run publishes recorded events every interval until ctx is cancelled. beat
+// is called after every round to signal the worker is alive.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.publishPending(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to relay events", "error", err)
+		}
+		beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishPending publishes unpublished events in batches until none are left
+// or every remaining one is waiting for a retry. The relay holds a session
+// advisory lock on one connection meanwhile instead of a transaction, so no
+// row locks are held while the sinks are called.
+func (r *Relay) publishPending(ctx context.Context) error {
+	return r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", relayLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			// Another replica is relaying
+			return nil
+		}
+		defer func() {
+			// The connection goes back to the pool, so the lock must be released even if ctx was cancelled
+			unlock := conn.WithContext(context.WithoutCancel(ctx))
+			if err := unlock.Exec("SELECT pg_advisory_unlock(?)", relayLockKey).Error; err != nil {
+				slog.ErrorContext(ctx, "failed to release the event relay lock", "error", err)
+			}
+		}()
+
+		for ctx.Err() == nil {
+			attempted, err := r.publishBatch(ctx, conn)
+			if err != nil || attempted == 0 {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// publishBatch tries to publish the oldest due events and returns how many it
+// tried. When an event can't be published, later events of its aggregate wait
+// until it has been or it is set aside; they are left out of the query, so
+// other aggregates are not held up behind them. conn must hold the relay lock.
+func (r *Relay) publishBatch(ctx context.Context, conn *gorm.DB) (int, error) {
+	var pending []models.OutboxEvent
+	if err := conn.
+		Where("published_at IS NULL AND dead_at IS NULL").
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", time.Now()).
+		Where(`NOT EXISTS (SELECT 1 FROM outbox_events failed
+WHERE failed.aggregate_type = outbox_events.aggregate_type AND failed.aggregate_id = outbox_events.aggregate_id
+  AND failed.id < outbox_events.id AND failed.published_at IS NULL AND failed.dead_at IS NULL
+  AND failed.attempts > 0 AND failed.deleted_at IS NULL)`).
+		Order("id").Limit(relayBatchSize).Find(&pending).Error; err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	// Sinks that received events on an earlier attempt
+	ids := make([]uint, len(pending))
+	for i, row := range pending {
+		ids[i] = row.ID
+	}
+	var deliveries []models.OutboxDelivery
+	if err := conn.Where("event_id IN ?", ids).Find(&deliveries).Error; err != nil {
+		return 0, err
+	}
+	delivered := make(map[uint]map[string]bool)
+	for _, delivery := range deliveries {
+		if delivered[delivery.EventID] == nil {
+			delivered[delivery.EventID] = make(map[string]bool)
+		}
+		delivered[delivery.EventID][delivery.Sink] = true
+	}
+
+	var attempted int
+	blocked := make(map[string]bool)
+	for i := range pending {
+		row := &pending[i]
+		aggregate := fmt.Sprintf("%s:%d", row.AggregateType, row.AggregateID)
+		if blocked[aggregate] {
+			continue
+		}
+		attempted++
+		if err := r.publish(ctx, conn, row, delivered[row.ID]); err != nil {
+			blocked[aggregate] = true
+			if err := r.fail(ctx, conn, row, err); err != nil {
+				return attempted, err
+			}
+			continue
+		}
+
+		metrics.EventsPublished.WithLabelValues(row.Type, "published").Inc()
+		if err := conn.Model(row).Updates(map[string]interface{}{
+			"attempts":     row.Attempts + 1,
+			"last_error":   "",
+			"published_at": time.Now(),
+		}).Error; err != nil {
+			return attempted, err
+		}
+	}
+	return attempted, nil
+}
+
+// fail records a failed attempt to publish an event. After maxAttempts the
+// event is set aside so that later events of its aggregate can be published.
+func (r *Relay) fail(ctx context.Context, db *gorm.DB, row *models.OutboxEvent, err error) error {
+	attempt := row.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempt,
+		"last_error": err.Error(),
+	}
+	if attempt >= r.maxAttempts {
+		slog.ErrorContext(ctx, "giving up publishing event", "event_id", row.ID, "type", row.Type, "attempts", attempt, "error", err)
+		metrics.EventsPublished.WithLabelValues(row.Type, "dead").Inc()
+		updates["dead_at"] = time.Now()
+	} else {
+		slog.WarnContext(ctx, "failed to publish event, will retry", "event_id", row.ID, "type", row.Type, "attempt", attempt, "error", err)
+		metrics.EventsPublished.WithLabelValues(row.Type, "retry").Inc()
+		updates["next_attempt_at"] = time.Now().Add(r.retryDelay << (attempt - 1))
+	}
+	return db.Model(row).Updates(updates).Error
+}
+
+// publish hands an event to every sink that hasn't received it yet. Every
+// sink that succeeds is recorded right away, so that it doesn't get the event
+// again when a later sink fails.
+func (r *Relay) publish(ctx context.Context, db *gorm.DB, row *models.OutboxEvent, delivered map[string]bool) error {
+	event := Event{
+		ID:            row.ID,
+		Type:          row.Type,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		OccurredAt:    row.CreatedAt,
+		Payload:       json.RawMessage(row.Payload),
+	}
+	for _, sink := range r.sinks {
+		if delivered[sink.Name()] {
+			continue
+		}
+		if err := sink.Publish(ctx, event); err != nil {
+			return fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+		if err := db.Create(&models.OutboxDelivery{EventID: row.ID, Sink: sink.Name()}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
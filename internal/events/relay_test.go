@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// flakySink fails the events whose IDs are in failing, then behaves like next
+type flakySink struct {
+	mu      sync.Mutex
+	failing map[uint]bool
+	next    Sink
+}
+
+func (s *flakySink) Name() string {
+	return "flaky"
+}
+
+func (s *flakySink) Publish(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	fail := s.failing[event.ID]
+	s.mu.Unlock()
+	if fail {
+		return errors.New("sink unavailable")
+	}
+	return s.next.Publish(ctx, event)
+}
+
+func (s *flakySink) recover(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failing, id)
+}
+
+// record writes an event for a booking and returns its ID
+func record(t *testing.T, db *gorm.DB, bookingID uint) uint {
+	t.Helper()
+	if err := Record(db, BookingCreated{BookingID: bookingID}); err != nil {
+		t.Fatal(err)
+	}
+	var row models.OutboxEvent
+	if err := db.Order("id DESC").First(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	return row.ID
+}
+
+// published returns the IDs of the published events of a booking, in publishing order
+func published(sink *MemorySink, bookingID uint) []uint {
+	var ids []uint
+	for _, event := range sink.Events() {
+		if event.AggregateID == bookingID {
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRelayKeepsAggregateOrderWithoutBlockingOthers(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+
+	// More events of the first booking than fit in a batch, the first one failing
+	var first []uint
+	for i := 0; i < relayBatchSize+5; i++ {
+		first = append(first, record(t, db, 1))
+	}
+	second := []uint{record(t, db, 2), record(t, db, 2)}
+
+	memory := NewMemorySink()
+	sink := &flakySink{failing: map[uint]bool{first[0]: true}, next: memory}
+	relay := NewRelay(db, 5, time.Hour, sink)
+
+	if err := relay.publishPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := published(memory, 1); len(got) != 0 {
+		t.Errorf("published %d events behind the failed one", len(got))
+	}
+	if got := published(memory, 2); !equalIDs(got, second) {
+		t.Errorf("events of the other booking: got %v, want %v", got, second)
+	}
+
+	var failed models.OutboxEvent
+	if err := db.First(&failed, first[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if failed.Attempts != 1 || failed.LastError == "" || failed.NextAttemptAt == nil || failed.PublishedAt != nil {
+		t.Errorf("failed event: attempts %d, last error %q, next attempt %v, published %v",
+			failed.Attempts, failed.LastError, failed.NextAttemptAt, failed.PublishedAt)
+	}
+
+	// Once the sink recovers and the retry is due, the rest follows in order
+	sink.recover(first[0])
+	if err := db.Model(&failed).Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.publishPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := published(memory, 1); !equalIDs(got, first) {
+		t.Errorf("events of the first booking: got %v, want %v", got, first)
+	}
+}
+
+func TestRelaySetsAsideEventsAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+
+	dead := record(t, db, 1)
+	next := record(t, db, 1)
+
+	memory := NewMemorySink()
+	relay := NewRelay(db, 2, time.Microsecond, &flakySink{failing: map[uint]bool{dead: true}, next: memory})
+
+	// Retries are due almost at once
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond)
+		if err := relay.publishPending(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var row models.OutboxEvent
+	if err := db.First(&row, dead).Error; err != nil {
+		t.Fatal(err)
+	}
+	if row.Attempts != 2 || row.DeadAt == nil || row.PublishedAt != nil {
+		t.Errorf("dead event: attempts %d, dead at %v, published at %v", row.Attempts, row.DeadAt, row.PublishedAt)
+	}
+	if got := published(memory, 1); !equalIDs(got, []uint{next}) {
+		t.Errorf("published %v, want the event after the dead one %v", got, []uint{next})
+	}
+}
+
+func TestRelayRetriesOnlyTheFailedSink(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	id := record(t, db, 1)
+
+	healthy := NewMemorySink()
+	failing := NewMemorySink()
+	flaky := &flakySink{failing: map[uint]bool{id: true}, next: failing}
+	relay := NewRelay(db, 5, time.Hour, healthy, flaky)
+
+	if err := relay.publishPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := published(healthy, 1); !equalIDs(got, []uint{id}) {
+		t.Fatalf("healthy sink received %v, want %v", got, []uint{id})
+	}
+
+	flaky.recover(id)
+	if err := db.Model(&models.OutboxEvent{}).Where("id = ?", id).Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.publishPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := published(failing, 1); !equalIDs(got, []uint{id}) {
+		t.Errorf("failed sink received %v on the retry, want %v", got, []uint{id})
+	}
+	if got := published(healthy, 1); !equalIDs(got, []uint{id}) {
+		t.Errorf("healthy sink received %v, want the event only once", got)
+	}
+
+	var row models.OutboxEvent
+	if err := db.First(&row, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if row.PublishedAt == nil || row.Attempts != 2 {
+		t.Errorf("event: published at %v after %d attempts, want published after 2", row.PublishedAt, row.Attempts)
+	}
+}
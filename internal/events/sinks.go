@@ -0,0 +1,119 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sink receives published events. Publish is called for one event at a time,
+// in order for every aggregate. Delivery is at least once: an event is
+// published again if the relay stops before recording that the sink received
+// it, or if Publish failed after the event got through, so sinks must
+// tolerate duplicates, e.g. by ignoring event IDs they have seen. An event is
+// never republished to a sink because another sink failed.
+type Sink interface {
+	// Name identifies the sink in the record of delivered events; it must be
+	// unique among the sinks of a relay and stay the same across restarts
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// RedisStreamSink appends events to a Redis stream
+type RedisStreamSink struct {
+	client *redis.Client
+	stream string
+}
+
+func NewRedisStreamSink(client *redis.Client, stream string) *RedisStreamSink {
+	return &RedisStreamSink{client: client, stream: stream}
+}
+
+func (s *RedisStreamSink) Name() string {
+	return "redis_stream"
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, event Event) error {
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]interface{}{
+			"id":             strconv.FormatUint(uint64(event.ID), 10),
+			"type":           event.Type,
+			"aggregate_type": event.AggregateType,
+			"aggregate_id":   strconv.FormatUint(uint64(event.AggregateID), 10),
+			"occurred_at":    event.OccurredAt.Format(time.RFC3339Nano),
+			"payload":        string(event.Payload),
+		},
+	}).Err()
+}
+
+// WebhookSink posts events as JSON to a URL. Any response other than 2xx fails
+// the delivery, so the event is sent again.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// MemorySink keeps published events in memory, for tests and local development
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Name() string {
+	return "memory"
+}
+
+func (s *MemorySink) Publish(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Events returns the events published so far
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
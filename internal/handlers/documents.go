@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/middleware"
+	"movie-ticket-booking/internal/services"
+	"net/http"
+	"strconv"
+)
+
+// BookingTicketsHandler serves the tickets of the authenticated user's booking
+// as a PDF, one page per seat
+func BookingTicketsHandler(documentService *services.DocumentService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, bookingID, ok := bookingRequest(w, r)
+		if !ok {
+			return
+		}
+		data, err := documentService.TicketsPDF(r.Context(), bookingID, userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writePDF(w, fmt.Sprintf("tickets-%d.pdf", bookingID), data)
+	})
+}
+
+// BookingInvoiceHandler serves the invoice of the authenticated user's booking as a PDF
+func BookingInvoiceHandler(documentService *services.DocumentService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, bookingID, ok := bookingRequest(w, r)
+		if !ok {
+			return
+		}
+		invoice, data, err := documentService.BookingInvoicePDF(r.Context(), bookingID, userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writePDF(w, invoice.Number+".pdf", data)
+	})
+}
+
+// InvoiceDownloadHandler serves an invoice or credit note of the authenticated
+// user by number as a PDF
+func InvoiceDownloadHandler(documentService *services.DocumentService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, ok := middleware.GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		number := r.URL.Query().Get("number")
+		if number == "" {
+			http.Error(w, "Invoice number is required", http.StatusBadRequest)
+			return
+		}
+
+		invoice, data, err := documentService.InvoicePDF(r.Context(), number, userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writePDF(w, invoice.Number+".pdf", data)
+	})
+}
+
+// bookingRequest checks a GET request for a document of a booking and returns
+// the authenticated user and the booking ID
+func bookingRequest(w http.ResponseWriter, r *http.Request) (uint, uint, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return 0, 0, false
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	bookingID, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || bookingID == 0 {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return userID, uint(bookingID), true
+}
+
+func writePDF(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// writeError responds with the HTTP status matching a domain error. Details
+// of internal errors are not shown to clients.
+func writeError(w http.ResponseWriter, err error) {
+	appErr, ok := apperrors.As(err)
+	if !ok {
+		slog.Error("failed to serve document", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch appErr.Code {
+	case apperrors.CodeNotFound:
+		status = http.StatusNotFound
+	case apperrors.CodeForbidden:
+		status = http.StatusForbidden
+	case apperrors.CodeValidation:
+		status = http.StatusBadRequest
+	case apperrors.CodeUnauthenticated:
+		status = http.StatusUnauthorized
+	case apperrors.CodeConflict:
+		status = http.StatusConflict
+	}
+	http.Error(w, appErr.Message, status)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/services"
+	"net/http"
+	"strings"
+)
+
+// EmailPreviewHandler renders email templates with sample data so designers
+// can check them in a browser. Without a template parameter it lists the
+// templates; locale picks the translation and format=text shows the plain
+// text version.
+func EmailPreviewHandler(receiptService *services.ReceiptService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		name := query.Get("template")
+		if name == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintf(w, "Email templates:\n\n%s\n", strings.Join(receiptService.Templates(), "\n"))
+			return
+		}
+
+		format := query.Get("format")
+		body, err := receiptService.Preview(name, query.Get("locale"), format)
+		if err != nil {
+			if apperrors.CodeOf(err) == apperrors.CodeNotFound {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to render template: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		fmt.Fprint(w, body)
+	})
+}
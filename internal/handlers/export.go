@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+	"movie-ticket-booking/internal/services"
+	"net/http"
+	"path/filepath"
+)
+
+// ExportDownloadHandler serves personal data export archives by download token
+func ExportDownloadHandler(exportService *services.ExportService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "Download token is required", http.StatusBadRequest)
+			return
+		}
+
+		path, err := exportService.OpenExport(token)
+		if err != nil {
+			http.Error(w, "Export not found or expired", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+		http.ServeFile(w, r, path)
+	})
+}
@@ -0,0 +1,204 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const checkTimeout = 2 * time.Second
+
+// Check reports the state of a dependency. The returned detail, such as a
+// version, is included in the report.
+type Check func(ctx context.Context) (detail string, err error)
+
+// Checker runs dependency checks and tracks background worker heartbeats
+type Checker struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	workers  map[string]*Heartbeat
+	draining atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Heartbeat is signalled periodically by a background worker to show it is alive
+type Heartbeat struct {
+	maxSilence time.Duration
+	lastBeat   atomic.Int64
+}
+
+// Beat records that the worker is alive
+func (h *Heartbeat) Beat() {
+	h.lastBeat.Store(time.Now().UnixNano())
+}
+
+func (h *Heartbeat) alive() (time.Time, bool) {
+	last := time.Unix(0, h.lastBeat.Load())
+	return last, time.Since(last) <= h.maxSilence
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the JSON document served by the health endpoints
+type Report struct {
+	Status  string            `json:"status"`
+	Checks  map[string]Result `json:"checks,omitempty"`
+	Workers map[string]Result `json:"workers,omitempty"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+func NewChecker() *Checker {
+	return &Checker{
+		workers: make(map[string]*Heartbeat),
+	}
+}
+
+// AddCheck registers a dependency check used for readiness
+func (c *Checker) AddCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// RegisterWorker registers a background worker that is considered dead when
+// it has not beaten for longer than maxSilence
+func (c *Checker) RegisterWorker(name string, maxSilence time.Duration) *Heartbeat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	heartbeat := &Heartbeat{maxSilence: maxSilence}
+	heartbeat.Beat()
+	c.workers[name] = heartbeat
+	return heartbeat
+}
+
+// SetDraining makes readiness fail so no new traffic is routed to the server
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Liveness reports whether the process is healthy, i.e. its workers are alive
+func (c *Checker) Liveness() Report {
+	report := Report{Status: StatusOK}
+	report.Workers = c.workerResults(&report)
+	return report
+}
+
+// Readiness reports whether the server can take traffic: dependencies must be
+// reachable, workers alive and the server not draining
+func (c *Checker) Readiness(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	for _, nc := range checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			result := runCheck(ctx, nc.check)
+
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(nc)
+	}
+	wg.Wait()
+
+	report.Workers = c.workerResults(&report)
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+func (c *Checker) workerResults(report *Report) map[string]Result {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.workers) == 0 {
+		return nil
+	}
+
+	results := make(map[string]Result, len(c.workers))
+	for name, heartbeat := range c.workers {
+		last, alive := heartbeat.alive()
+		result := Result{Status: StatusOK, Detail: "last heartbeat " + last.Format(time.RFC3339)}
+		if !alive {
+			result.Status = StatusFail
+			result.Error = "no heartbeat for " + time.Since(last).Round(time.Second).String()
+			report.Status = StatusFail
+		}
+		results[name] = result
+	}
+	return results
+}
+
+func runCheck(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check(ctx)
+	result := Result{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler serves the liveness report, answering 503 when unhealthy
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Liveness())
+	})
+}
+
+// ReadinessHandler serves the readiness report, answering 503 when not ready
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Readiness(r.Context()))
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
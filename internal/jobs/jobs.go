@@ -0,0 +1,159 @@
+// Package jobs runs background work stored in Postgres. Jobs survive restarts
+// and are claimed with SKIP LOCKED, so any number of replicas can run the
+// scheduler; a job is retried with backoff until its handler succeeds.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Handler runs a job with its JSON payload. It runs within the transaction
+// that completes the job, so database changes made through tx take effect
+// exactly once; other side effects may be repeated. Returning an error
+// rolls back tx and retries the job later.
+type Handler func(ctx context.Context, tx *gorm.DB, payload []byte) error
+
+// Scheduler stores jobs and runs them with the handler registered for their kind
+type Scheduler struct {
+	db          *gorm.DB
+	handlers    map[string]Handler
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// NewScheduler creates a scheduler that gives up on a job after maxAttempts.
+// Retries wait retryDelay, doubling after every failed attempt.
+func NewScheduler(db *gorm.DB, maxAttempts int, retryDelay time.Duration) *Scheduler {
+	return &Scheduler{
+		db:          db,
+		handlers:    make(map[string]Handler),
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+	}
+}
+
+// Register sets the handler of a kind of job; call it before Run
+func (s *Scheduler) Register(kind string, handler Handler) {
+	s.handlers[kind] = handler
+}
+
+// Schedule stores a job to run at runAt within tx; it only runs if tx commits.
+// Scheduling a job with the key of an existing one does nothing.
+func (s *Scheduler) Schedule(tx *gorm.DB, kind, key string, payload interface{}, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload of %s job: %w", kind, err)
+	}
+	return tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "dedupe_key"}}, DoNothing: true}).
+		Create(&models.Job{
+			Kind:      kind,
+			DedupeKey: key,
+			Payload:   string(data),
+			Status:    models.JobStatusPending,
+			RunAt:     runAt,
+		}).Error
+}
+
+// Run runs due jobs every interval until ctx is cancelled. beat is called
+// after every round to signal the worker is alive.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.runDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to run jobs", "error", err)
+		}
+		beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue runs due jobs one at a time until there are none left
+func (s *Scheduler) runDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		ran, err := s.runNext(ctx)
+		if err != nil || !ran {
+			return err
+		}
+	}
+	return nil
+}
+
+// runNext claims the next due job and runs it in the same transaction. The
+// row lock keeps other replicas from running the job, and is released if this
+// one crashes, so the job is picked up again.
+func (s *Scheduler) runNext(ctx context.Context) (bool, error) {
+	var ran bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job models.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", models.JobStatusPending, time.Now()).
+			Order("run_at, id").First(&job).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ran = true
+
+		handler, ok := s.handlers[job.Kind]
+		if !ok {
+			return s.fail(tx, &job, fmt.Errorf("no handler for job kind %s", job.Kind))
+		}
+
+		if err := tx.SavePoint("job").Error; err != nil {
+			return err
+		}
+		if err := handler(ctx, tx, []byte(job.Payload)); err != nil {
+			if err := tx.RollbackTo("job").Error; err != nil {
+				return err
+			}
+			return s.fail(tx, &job, err)
+		}
+
+		metrics.Jobs.WithLabelValues(job.Kind, "done").Inc()
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":      models.JobStatusDone,
+			"attempts":    job.Attempts + 1,
+			"last_error":  "",
+			"finished_at": time.Now(),
+		}).Error
+	})
+	return ran, err
+}
+
+// fail records a failed attempt, scheduling a retry unless the job has run out of attempts
+func (s *Scheduler) fail(tx *gorm.DB, job *models.Job, jobErr error) error {
+	attempts := job.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": jobErr.Error(),
+	}
+	if attempts >= s.maxAttempts {
+		slog.Error("giving up on job", "job_id", job.ID, "kind", job.Kind, "attempts", attempts, "error", jobErr)
+		updates["status"] = models.JobStatusFailed
+		updates["finished_at"] = time.Now()
+		metrics.Jobs.WithLabelValues(job.Kind, "failed").Inc()
+	} else {
+		delay := s.retryDelay << (attempts - 1)
+		slog.Warn("job failed, will retry", "job_id", job.ID, "kind", job.Kind, "attempt", attempts, "retry_in", delay, "error", jobErr)
+		updates["run_at"] = time.Now().Add(delay)
+		metrics.Jobs.WithLabelValues(job.Kind, "retry").Inc()
+	}
+	return tx.Model(job).Updates(updates).Error
+}
@@ -0,0 +1,198 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type testPayload struct {
+	N int `json:"n"`
+}
+
+func schedule(t *testing.T, db *gorm.DB, s *Scheduler, kind string, n int) {
+	t.Helper()
+	if err := s.Schedule(db, kind, fmt.Sprintf("%s:%d", kind, n), testPayload{N: n}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func onlyJob(t *testing.T, db *gorm.DB) models.Job {
+	t.Helper()
+	var jobs []models.Job
+	if err := db.Order("id").Find(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+	return jobs[0]
+}
+
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.Model(&models.Job{}).Where("status = ?", models.JobStatusPending).
+		Update("run_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailingJobIsRetriedWithBackoffAndGivenUp(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	const maxAttempts, retryDelay = 3, time.Hour
+	s := NewScheduler(db, maxAttempts, retryDelay)
+	calls := 0
+	s.Register("flaky", func(ctx context.Context, tx *gorm.DB, payload []byte) error {
+		calls++
+		return errors.New("mail server down")
+	})
+	schedule(t, db, s, "flaky", 1)
+
+	// The wait before a retry doubles after every attempt
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		before := time.Now()
+		if ran, err := s.runNext(ctx); err != nil || !ran {
+			t.Fatalf("attempt %d: ran %v, error %v", attempt, ran, err)
+		}
+		job := onlyJob(t, db)
+		if job.Status != models.JobStatusPending || job.Attempts != attempt || job.LastError != "mail server down" {
+			t.Fatalf("after attempt %d: status %s, attempts %d, last error %q", attempt, job.Status, job.Attempts, job.LastError)
+		}
+		wait := retryDelay << (attempt - 1)
+		if job.RunAt.Before(before.Add(wait)) || job.RunAt.After(time.Now().Add(wait)) {
+			t.Errorf("after attempt %d: runs at %v, want %v from now", attempt, job.RunAt, wait)
+		}
+
+		// Not retried before it is due
+		if ran, err := s.runNext(ctx); err != nil || ran {
+			t.Fatalf("after attempt %d: ran %v before the retry was due, error %v", attempt, ran, err)
+		}
+		makeDue(t, db)
+	}
+
+	if _, err := s.runNext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	job := onlyJob(t, db)
+	if job.Status != models.JobStatusFailed || job.Attempts != maxAttempts || job.FinishedAt == nil {
+		t.Errorf("after the last attempt: status %s, attempts %d, finished at %v", job.Status, job.Attempts, job.FinishedAt)
+	}
+
+	makeDue(t, db)
+	if ran, err := s.runNext(ctx); err != nil || ran {
+		t.Errorf("ran a job that was given up: ran %v, error %v", ran, err)
+	}
+	if calls != maxAttempts {
+		t.Errorf("handler called %d times, want %d", calls, maxAttempts)
+	}
+}
+
+func TestFailingJobRollsBackItsChanges(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	s := NewScheduler(db, 3, time.Hour)
+	s.Register("create_movie", func(ctx context.Context, tx *gorm.DB, payload []byte) error {
+		var p testPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		movie := &models.Movie{Title: fmt.Sprintf("Movie %d", p.N), Duration: 90, Genre: "Drama", ReleaseDate: time.Now()}
+		if err := tx.Create(movie).Error; err != nil {
+			return err
+		}
+		if p.N == 1 {
+			return errors.New("failed after writing")
+		}
+		return nil
+	})
+	schedule(t, db, s, "create_movie", 1)
+	schedule(t, db, s, "create_movie", 2)
+
+	if err := s.runDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	if err := db.Model(&models.Movie{}).Order("id").Pluck("title", &titles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 1 || titles[0] != "Movie 2" {
+		t.Errorf("movies %v, want only the one of the job that succeeded", titles)
+	}
+
+	var jobs []models.Job
+	if err := db.Order("id").Find(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if failed := jobs[0]; failed.Status != models.JobStatusPending || failed.Attempts != 1 || failed.LastError == "" {
+		t.Errorf("failed job: status %s, attempts %d, last error %q", failed.Status, failed.Attempts, failed.LastError)
+	}
+	if done := jobs[1]; done.Status != models.JobStatusDone || done.Attempts != 1 || done.FinishedAt == nil {
+		t.Errorf("succeeded job: status %s, attempts %d, finished at %v", done.Status, done.Attempts, done.FinishedAt)
+	}
+}
+
+func TestConcurrentRunnersNeverClaimTheSameJob(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	s := NewScheduler(db, 3, time.Hour)
+
+	var mu sync.Mutex
+	runs := make(map[int]int)
+	s.Register("count", func(ctx context.Context, tx *gorm.DB, payload []byte) error {
+		var p testPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		// Hold the claim for a moment so that the runners overlap
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		runs[p.N]++
+		mu.Unlock()
+		return nil
+	})
+	const jobs = 40
+	for n := 0; n < jobs; n++ {
+		schedule(t, db, s, "count", n)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.runDue(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n := 0; n < jobs; n++ {
+		if runs[n] != 1 {
+			t.Errorf("job %d ran %d times, want once", n, runs[n])
+		}
+	}
+	var pending int64
+	if err := db.Model(&models.Job{}).Where("status <> ?", models.JobStatusDone).Count(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("%d jobs not done", pending)
+	}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// occupancyWindow limits the occupancy gauges to showtimes starting soon
+const occupancyWindow = 7 * 24 * time.Hour
+
+// RegisterDBStats exposes the connection pool statistics of the database
+func RegisterDBStats(db *sql.DB, dbName string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// RegisterShowtimeOccupancy exposes seats sold and seats total for every
+// upcoming showtime, computed when the metrics are scraped
+func RegisterShowtimeOccupancy(db *gorm.DB) {
+	prometheus.MustRegister(&occupancyCollector{db: db})
+}
+
+var (
+	seatsSoldDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "showtime", "seats_sold"),
+		"Seats booked for an upcoming showtime.",
+		[]string{"showtime_id", "movie", "hall", "start_time"}, nil,
+	)
+	seatsTotalDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "showtime", "seats_total"),
+		"Seats available for sale in an upcoming showtime.",
+		[]string{"showtime_id", "movie", "hall", "start_time"}, nil,
+	)
+)
+
+type occupancyCollector struct {
+	db *gorm.DB
+}
+
+func (c *occupancyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- seatsSoldDesc
+	ch <- seatsTotalDesc
+}
+
+func (c *occupancyCollector) Collect(ch chan<- prometheus.Metric) {
+	var rows []struct {
+		ShowtimeID uint
+		Movie      string
+		Hall       string
+		StartTime  time.Time
+		SeatsSold  int64
+		SeatsTotal int64
+	}
+
+	now := time.Now()
+	err := c.db.Table("show_times").
+		Select(`show_times.id AS showtime_id, movies.title AS movie, halls.name AS hall, show_times.start_time,
+			COUNT(seats.id) FILTER (WHERE seats.status = 'BOOKED') AS seats_sold,
+			COUNT(seats.id) AS seats_total`).
+		Joins("JOIN movies ON movies.id = show_times.movie_id").
+		Joins("JOIN halls ON halls.id = show_times.hall_id").
+		Joins("LEFT JOIN seats ON seats.show_time_id = show_times.id AND seats.deleted_at IS NULL").
+		Where("show_times.deleted_at IS NULL AND show_times.start_time BETWEEN ? AND ?", now, now.Add(occupancyWindow)).
+		Group("show_times.id, movies.title, halls.name, show_times.start_time").
+		Scan(&rows).Error
+	if err != nil {
+		slog.Error("failed to collect showtime occupancy", "error", err)
+		return
+	}
+
+	for _, row := range rows {
+		labels := []string{
+			strconv.FormatUint(uint64(row.ShowtimeID), 10),
+			row.Movie,
+			row.Hall,
+			row.StartTime.Format(time.RFC3339),
+		}
+		ch <- prometheus.MustNewConstMetric(seatsSoldDesc, prometheus.GaugeValue, float64(row.SeatsSold), labels...)
+		ch <- prometheus.MustNewConstMetric(seatsTotalDesc, prometheus.GaugeValue, float64(row.SeatsTotal), labels...)
+	}
+}
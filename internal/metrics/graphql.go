@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// GraphQLExtension records operation counts, latency and errors by top-level field
+type GraphQLExtension struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = GraphQLExtension{}
+
+func (GraphQLExtension) ExtensionName() string {
+	return "Metrics"
+}
+
+func (GraphQLExtension) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (GraphQLExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	resp := next(ctx)
+
+	opCtx := graphql.GetOperationContext(ctx)
+	name := operationLabel(opCtx)
+	opType := "unknown"
+	if opCtx.Operation != nil {
+		opType = string(opCtx.Operation.Operation)
+	}
+
+	status := "success"
+	if resp != nil && len(resp.Errors) > 0 {
+		status = "error"
+		GraphQLErrors.WithLabelValues(name).Add(float64(len(resp.Errors)))
+	}
+
+	GraphQLOperations.WithLabelValues(name, opType, status).Inc()
+	GraphQLDuration.WithLabelValues(name, opType).Observe(time.Since(start).Seconds())
+
+	return resp
+}
+
+// operationLabel names an operation after the schema field it selects. Client
+// operation names are not used since clients choose them freely, which would
+// create a time series per name. Operations selecting several fields, or that
+// failed validation, are counted as "other".
+func operationLabel(opCtx *graphql.OperationContext) string {
+	if opCtx.Operation == nil || len(opCtx.Operation.SelectionSet) != 1 {
+		return "other"
+	}
+	field, ok := opCtx.Operation.SelectionSet[0].(*ast.Field)
+	if !ok || field.Definition == nil {
+		return "other"
+	}
+	return field.Name
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestOperationLabel(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+type Query {
+  movies: [String!]!
+  me: String
+}`})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"query AnyNameAClientPicks { movies }", "movies"},
+		{"{ aliased: me }", "me"},
+		{"query Both { movies me }", "other"},
+		{"query Spread { ...F } fragment F on Query { me }", "other"},
+	}
+	for _, tt := range tests {
+		doc, errs := gqlparser.LoadQuery(schema, tt.query)
+		if errs != nil {
+			t.Fatalf("%s: %v", tt.query, errs)
+		}
+		opCtx := &graphql.OperationContext{Operation: doc.Operations[0]}
+		if got := operationLabel(opCtx); got != tt.want {
+			t.Errorf("%s: label %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	if got := operationLabel(&graphql.OperationContext{}); got != "other" {
+		t.Errorf("operation that failed validation: label %q, want other", got)
+	}
+}
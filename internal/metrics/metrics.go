@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "movie_ticket_booking"
+
+var (
+	GraphQLOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_operations_total",
+		Help:      "GraphQL operations handled, by top-level field, type and status.",
+	}, []string{"operation", "type", "status"})
+
+	GraphQLDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_operation_duration_seconds",
+		Help:      "Latency of GraphQL operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+
+	GraphQLErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_errors_total",
+		Help:      "Errors returned in GraphQL responses, by top-level field.",
+	}, []string{"operation"})
+
+	BookingsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_created_total",
+		Help:      "Bookings created.",
+	})
+
+	BookingsCancelled = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_cancelled_total",
+		Help:      "Bookings cancelled.",
+	})
+
+	BookingSeatsCancelled = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "booking_seats_cancelled_total",
+		Help:      "Seats cancelled from bookings that stay confirmed.",
+	})
+
+	BookingsExchanged = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_exchanged_total",
+		Help:      "Bookings exchanged for another showtime or other seats.",
+	})
+
+	Payments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_total",
+		Help:      "Payment gateway calls, by kind (charge or refund) and outcome.",
+	}, []string{"kind", "outcome"})
+
+	NotificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Queued notification delivery attempts, by outcome (sent, retry or failed).",
+	}, []string{"outcome"})
+
+	EventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Domain event publish attempts, by type and outcome (published, retry or dead).",
+	}, []string{"type", "outcome"})
+
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by event type and outcome (succeeded, retry or failed).",
+	}, []string{"type", "outcome"})
+
+	Jobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_total",
+		Help:      "Background job runs, by kind and outcome (done, retry or failed).",
+	}, []string{"kind", "outcome"})
+
+	WaitlistEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "waitlist_entries_total",
+		Help:      "Waitlist entries by event (joined, offered, booked, expired or left).",
+	}, []string{"event"})
+
+	ShowtimesCancelled = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "showtimes_cancelled_total",
+		Help:      "Showtimes cancelled by staff.",
+	})
+
+	SeatLockAcquisitions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seat_lock_acquisitions_total",
+		Help:      "Seat locks acquired in Redis while booking.",
+	})
+
+	SeatLockConflicts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seat_lock_conflicts_total",
+		Help:      "Booking attempts rejected because a seat was locked by another booking.",
+	})
+
+	RedisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Latency of Redis commands.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command", "status"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
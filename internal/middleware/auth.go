@@ -8,14 +8,36 @@ import (
 	"movie-ticket-booking/internal/services"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type contextKey string
 
 const (
-	UserIDKey contextKey = "user_id"
+	UserIDKey   contextKey = "user_id"
+	UserRoleKey contextKey = "user_role"
+	AuthTimeKey contextKey = "auth_time"
 )
 
+// publicMutations can be called without an Authorization header
+var publicMutations = []string{
+	"login",
+	"register",
+	"beginoidclogin",
+	"completeoidclogin",
+}
+
+// isPublicMutation reports whether a normalized query calls one of the public mutations
+func isPublicMutation(query string) bool {
+	for _, mutation := range publicMutations {
+		if strings.Contains(query, "mutation{"+mutation+"(") ||
+			strings.Contains(query, "mutation"+mutation+"(") {
+			return true
+		}
+	}
+	return false
+}
+
 func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +48,7 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 				if err != nil {
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusBadRequest)
-					w.Write([]byte(`{"errors":[{"message":"Error reading request body"}]}`))                
+					w.Write([]byte(`{"errors":[{"message":"Error reading request body","extensions":{"code":"VALIDATION"}}]}`))                
 					return
 				}
 				
@@ -38,7 +60,7 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 				if err := json.Unmarshal(body, &reqBody); err != nil {
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusBadRequest)
-					w.Write([]byte(`{"errors":[{"message":"Invalid JSON request body"}]}`))                
+					w.Write([]byte(`{"errors":[{"message":"Invalid JSON request body","extensions":{"code":"VALIDATION"}}]}`))                
 					return
 				}
 
@@ -48,13 +70,17 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 				query = strings.ReplaceAll(query, "\n", "")
 				query = strings.ReplaceAll(query, "\t", "")
 
-				// Check if it's a login or register mutation
-				if strings.Contains(query, "mutation{login(") || 
-				   strings.Contains(query, "mutation{register(") || 
-				   strings.Contains(query, "mutationlogin(") || 
-				   strings.Contains(query, "mutationregister(") {
+				// Check if it's a public mutation such as login or register
+				if isPublicMutation(query) {
 					// Create a new reader with the same body content
 					r.Body = io.NopCloser(bytes.NewBuffer(body))
+					// Signed-in callers, such as users linking a social account, keep their identity
+					parts := strings.Split(r.Header.Get("Authorization"), " ")
+					if len(parts) == 2 && parts[0] == "Bearer" {
+						if claims, err := authService.ValidateToken(parts[1]); err == nil {
+							r = r.WithContext(withClaims(r.Context(), claims))
+						}
+					}
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -67,7 +93,7 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 			if authHeader == "" {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte(`{"errors":[{"message":"Authorization header is required"}]}`))                
+				w.Write([]byte(`{"errors":[{"message":"Authorization header is required","extensions":{"code":"UNAUTHENTICATED"}}]}`))                
 				return
 			}
 
@@ -76,7 +102,7 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 			if len(parts) != 2 || parts[0] != "Bearer" {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte(`{"errors":[{"message":"Invalid authorization header format. Use 'Bearer <token>'"}]}`))                
+				w.Write([]byte(`{"errors":[{"message":"Invalid authorization header format. Use 'Bearer <token>'","extensions":{"code":"UNAUTHENTICATED"}}]}`))                
 				return
 			}
 
@@ -85,19 +111,43 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 			// Validate the token
 			claims, err := authService.ValidateToken(tokenString)
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"errors":[{"message":"Invalid token","extensions":{"code":"UNAUTHENTICATED"}}]}`))
 				return
 			}
 
 			// Add user ID to context
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
 		})
 	}
 }
 
+// withClaims adds the authenticated user to the context
+func withClaims(ctx context.Context, claims *services.Claims) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+	if claims.IssuedAt != nil {
+		ctx = context.WithValue(ctx, AuthTimeKey, claims.IssuedAt.Time)
+	}
+	return ctx
+}
+
 // GetUserID retrieves the user ID from the context
 func GetUserID(ctx context.Context) (uint, bool) {
 	userID, ok := ctx.Value(UserIDKey).(uint)
 	return userID, ok
-}
\ No newline at end of file
+}
+
+// GetUserRole retrieves the role of the authenticated user from the context
+func GetUserRole(ctx context.Context) string {
+	role, _ := ctx.Value(UserRoleKey).(string)
+	return role
+}
+
+// GetAuthTime retrieves when the authenticated user signed in, the zero time
+// if unknown. Tokens are only issued on sign-in.
+func GetAuthTime(ctx context.Context) time.Time {
+	authTime, _ := ctx.Value(AuthTimeKey).(time.Time)
+	return authTime
+}
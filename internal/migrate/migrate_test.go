@@ -0,0 +1,59 @@
+package migrate_test
+
+import (
+	"context"
+	"testing"
+
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/migrate"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/migrations"
+)
+
+func TestModelsMatchMigratedSchema(t *testing.T) {
+	db := dbtest.New(t)
+
+	problems, err := migrate.Verify(context.Background(), db, models.All()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrate.New(sqlDB, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != migrator.Latest() {
+		t.Errorf("version %d after migrating, want %d", version, migrator.Latest())
+	}
+
+	// Without the table the version is 0 and the table is not created
+	if err := db.Exec("DROP TABLE schema_migrations").Error; err != nil {
+		t.Fatal(err)
+	}
+	if version, err := migrator.Version(ctx); err != nil || version != 0 {
+		t.Fatalf("Version without schema_migrations = %d, %v; want 0", version, err)
+	}
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists).Error; err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("Version created the schema_migrations table")
+	}
+}
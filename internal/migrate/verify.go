@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type column struct {
+	dataType string
+	nullable bool
+}
+
+// Verify compares GORM models with the migrated schema and returns every
+// mismatch found in table names, columns, types, nullability and unique indexes
+func Verify(ctx context.Context, db *gorm.DB, models ...interface{}) ([]string, error) {
+	var problems []string
+	cache := &sync.Map{}
+
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+
+		columns, err := tableColumns(ctx, db, s.Table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: table is missing", s.Table))
+			continue
+		}
+
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			col, ok := columns[field.DBName]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: column is missing", s.Table, field.DBName))
+				continue
+			}
+			if want, got := modelTypeFamily(field), columnTypeFamily(col.dataType); want != got {
+				problems = append(problems, fmt.Sprintf("%s.%s: model expects %s but column is %s", s.Table, field.DBName, want, col.dataType))
+			}
+			if !field.PrimaryKey && field.NotNull == col.nullable {
+				problems = append(problems, fmt.Sprintf("%s.%s: model not null is %t but column nullable is %t", s.Table, field.DBName, field.NotNull, col.nullable))
+			}
+		}
+
+		dbUnique, err := uniqueIndexes(ctx, db, s.Table)
+		if err != nil {
+			return nil, err
+		}
+		modelUnique := make(map[string]bool)
+		for _, index := range s.ParseIndexes() {
+			// Partial indexes only constrain some rows and are not compared
+			if index.Class != "UNIQUE" || index.Where != "" {
+				continue
+			}
+			names := make([]string, len(index.Fields))
+			for i, option := range index.Fields {
+				names[i] = option.DBName
+			}
+			modelUnique[columnSet(names)] = true
+		}
+		for _, field := range s.Fields {
+			if field.Unique {
+				modelUnique[columnSet([]string{field.DBName})] = true
+			}
+		}
+		for set := range modelUnique {
+			if !dbUnique[set] {
+				problems = append(problems, fmt.Sprintf("%s: unique index on (%s) is missing", s.Table, set))
+			}
+		}
+		for set := range dbUnique {
+			if !modelUnique[set] {
+				problems = append(problems, fmt.Sprintf("%s: unique index on (%s) is not declared on the model", s.Table, set))
+			}
+		}
+	}
+
+	sort.Strings(problems)
+	return problems, nil
+}
+
+func tableColumns(ctx context.Context, db *gorm.DB, table string) (map[string]column, error) {
+	var rows []struct {
+		ColumnName string
+		DataType   string
+		IsNullable string
+	}
+	err := db.WithContext(ctx).Raw(`SELECT column_name, data_type, is_nullable
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = ?`, table).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+
+	columns := make(map[string]column, len(rows))
+	for _, row := range rows {
+		columns[row.ColumnName] = column{dataType: row.DataType, nullable: row.IsNullable == "YES"}
+	}
+	return columns, nil
+}
+
+// uniqueIndexes returns the column sets of the non-primary unique indexes of a table
+func uniqueIndexes(ctx context.Context, db *gorm.DB, table string) (map[string]bool, error) {
+	var rows []struct {
+		Columns string
+	}
+	err := db.WithContext(ctx).Raw(`SELECT string_agg(a.attname, ',') AS columns
+FROM pg_index i
+JOIN pg_class t ON t.oid = i.indrelid
+JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(i.indkey)
+WHERE t.relname = ? AND t.relnamespace = current_schema()::regnamespace
+  AND i.indisunique AND NOT i.indisprimary AND i.indpred IS NULL
+GROUP BY i.indexrelid`, table).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", table, err)
+	}
+
+	sets := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		sets[columnSet(strings.Split(row.Columns, ","))] = true
+	}
+	return sets, nil
+}
+
+func columnSet(names []string) string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
+
+// modelTypeFamily maps a model field to a coarse type family, using the
+// explicit type tag when there is one
+func modelTypeFamily(field *schema.Field) string {
+	if tag := strings.ToLower(string(field.DataType)); tag != "" {
+		switch {
+		case strings.HasPrefix(tag, "varchar"), strings.HasPrefix(tag, "char"), tag == "text":
+			return "string"
+		case strings.HasPrefix(tag, "decimal"), strings.HasPrefix(tag, "numeric"):
+			return "number"
+		case tag == "date":
+			return "date"
+		case strings.HasPrefix(tag, "timestamp"):
+			return "timestamp"
+		}
+	}
+
+	switch field.GORMDataType {
+	case schema.Bool:
+		return "boolean"
+	case schema.Int, schema.Uint:
+		return "integer"
+	case schema.Float:
+		return "number"
+	case schema.String:
+		return "string"
+	case schema.Time:
+		return "timestamp"
+	}
+	return string(field.GORMDataType)
+}
+
+func columnTypeFamily(dataType string) string {
+	switch {
+	case dataType == "integer", dataType == "bigint", dataType == "smallint":
+		return "integer"
+	case dataType == "numeric", dataType == "double precision", dataType == "real":
+		return "number"
+	case dataType == "character varying", dataType == "character", dataType == "text":
+		return "string"
+	case strings.HasPrefix(dataType, "timestamp"):
+		return "timestamp"
+	}
+	return dataType
+}
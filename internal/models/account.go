@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginEvent records a successful login of a user
+type LoginEvent struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index"`
+	Method string `gorm:"not null;type:varchar(50)"` // password, oidc:<provider>
+}
+
+// DataExport is a personal data export requested by a user
+type DataExport struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	Status    string    `gorm:"not null;type:varchar(20)"` // PENDING, READY, FAILED
+	Token     string    `gorm:"type:varchar(64);index"`    // SHA-256 of the download token
+	FilePath  string    `gorm:"type:varchar(255)"`
+	Error     string    `gorm:"type:text"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+const (
+	DataExportStatusPending = "PENDING"
+	DataExportStatusReady   = "READY"
+	DataExportStatusFailed  = "FAILED"
+)
@@ -8,14 +8,18 @@ import (
 
 type Booking struct {
 	gorm.Model
-	UserID      uint      `gorm:"not null"`
-	User        User      `gorm:"foreignKey:UserID"`
-	ShowTimeID  uint      `gorm:"not null"`
-	Showtime    ShowTime  `gorm:"foreignKey:ShowTimeID"` // Fixed field name to match GraphQL schema
-	TotalAmount float64   `gorm:"not null"`
-	Status      string    `gorm:"not null"` // CONFIRMED, CANCELLED
-	BookedAt    time.Time `gorm:"not null"`
-	Seats       []BookingSeat
+	UserID       uint      `gorm:"not null"`
+	User         User      `gorm:"foreignKey:UserID"`
+	ShowTimeID   uint      `gorm:"not null"`
+	Showtime     ShowTime  `gorm:"foreignKey:ShowTimeID"` // Fixed field name to match GraphQL schema
+	TotalAmount  float64   `gorm:"not null"`
+	Status       string    `gorm:"not null"` // CONFIRMED, CANCELLED, EXCHANGED
+	BookedAt     time.Time `gorm:"not null"`
+	CancelReason string    `gorm:"type:varchar(50)"` // CUSTOMER, SHOWTIME_CANCELLED
+	CancelledAt  *time.Time
+	// ExchangedFromID is the booking this one replaced through an exchange
+	ExchangedFromID *uint `gorm:"uniqueIndex"`
+	Seats           []BookingSeat
 }
 
 type BookingSeat struct {
@@ -29,7 +33,11 @@ type BookingSeat struct {
 const (
 	BookingStatusConfirmed = "CONFIRMED"
 	BookingStatusCancelled = "CANCELLED"
+	BookingStatusExchanged = "EXCHANGED"
 	SeatStatusAvailable    = "AVAILABLE"
 	SeatStatusReserved     = "RESERVED"
 	SeatStatusBooked       = "BOOKED"
-)
\ No newline at end of file
+
+	CancelReasonCustomer          = "CUSTOMER"
+	CancelReasonShowtimeCancelled = "SHOWTIME_CANCELLED"
+)
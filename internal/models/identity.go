@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity links an external OpenID Connect account to a local user
+type UserIdentity struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index"`
+	User     User   `gorm:"foreignKey:UserID"`
+	Provider string `gorm:"not null;type:varchar(50);uniqueIndex:idx_identity_provider_subject"`
+	Subject  string `gorm:"not null;type:varchar(255);uniqueIndex:idx_identity_provider_subject"`
+	Email    string `gorm:"type:varchar(255)"`
+}
+
+// OIDCLoginState keeps the PKCE verifier and nonce of a pending authorization-code login
+type OIDCLoginState struct {
+	gorm.Model
+	State        string    `gorm:"not null;type:varchar(100);uniqueIndex"`
+	Provider     string    `gorm:"not null;type:varchar(50)"`
+	CodeVerifier string    `gorm:"not null;type:varchar(100)"`
+	Nonce        string    `gorm:"not null;type:varchar(100)"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	UserID       *uint     // logged-in user the provider account is linked to
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invoice is a VAT invoice for a charge or a credit note for a refund. Issued
+// invoices are never changed; refunds are documented by credit notes.
+type Invoice struct {
+	gorm.Model
+	Number     string `gorm:"not null;type:varchar(40);uniqueIndex:idx_invoices_number"`
+	Kind       string `gorm:"not null;type:varchar(20)"` // INVOICE, CREDIT_NOTE
+	BookingID  uint   `gorm:"not null;index"`
+	PaymentID  uint   `gorm:"not null;uniqueIndex:idx_invoices_payment_id"`
+	CorrectsID *uint  // invoice a credit note corrects
+	// Customer details at the time the invoice was issued
+	BuyerName  string `gorm:"not null;type:varchar(255)"`
+	BuyerEmail string `gorm:"not null;type:varchar(255)"`
+	// Lines holds the JSON items of the invoice, priced including tax
+	Lines      string    `gorm:"not null;type:text"`
+	Net        float64   `gorm:"not null;type:decimal(10,2)"`
+	Tax        float64   `gorm:"not null;type:decimal(10,2)"`
+	Gross      float64   `gorm:"not null;type:decimal(10,2)"`
+	TaxPercent float64   `gorm:"not null;type:decimal(5,2)"`
+	Currency   string    `gorm:"not null;type:varchar(3)"`
+	IssuedAt   time.Time `gorm:"not null"`
+}
+
+const (
+	InvoiceKindInvoice    = "INVOICE"
+	InvoiceKindCreditNote = "CREDIT_NOTE"
+)
+
+// InvoiceSequence holds the last number issued in an invoice series
+type InvoiceSequence struct {
+	Series     string `gorm:"primaryKey;type:varchar(30)"`
+	LastNumber int    `gorm:"not null;default:0"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job is a unit of background work run by the job scheduler at or after RunAt
+type Job struct {
+	gorm.Model
+	Kind string `gorm:"not null;type:varchar(50)"`
+	// DedupeKey makes scheduling the same work twice a no-op
+	DedupeKey string `gorm:"not null;type:varchar(255);uniqueIndex"`
+	// Payload holds the JSON arguments of the job
+	Payload    string    `gorm:"not null;type:text"`
+	Status     string    `gorm:"not null;type:varchar(20);default:'PENDING';index:idx_jobs_status_run_at"` // PENDING, DONE, FAILED
+	RunAt      time.Time `gorm:"not null;index:idx_jobs_status_run_at"`
+	Attempts   int       `gorm:"not null;default:0"`
+	LastError  string    `gorm:"type:text"`
+	FinishedAt *time.Time
+}
+
+const (
+	JobStatusPending = "PENDING"
+	JobStatusDone    = "DONE"
+	JobStatusFailed  = "FAILED"
+)
@@ -9,10 +9,20 @@ import (
 type User struct {
 	gorm.Model
 	Email     string    `gorm:"uniqueIndex;not null"`
-	Password  string    `gorm:"not null"`
+	Password  string    `gorm:"not null" json:"-"`
 	Name      string    `gorm:"not null"`
 	Phone     string    `gorm:"not null"`
+	Role      string    `gorm:"not null;type:varchar(20);default:'CUSTOMER'"` // CUSTOMER, ADMIN
+	Locale    string    `gorm:"not null;type:varchar(10);default:'en'"`      // language of emails, such as en or de
 	Tickets   []Ticket  `gorm:"foreignKey:UserID"`
+
+	// Set once the user proved to own the email address
+	EmailVerifiedAt *time.Time
+
+	// Pending email change awaiting verification of the new address
+	PendingEmail         string     `gorm:"type:varchar(255)"`
+	EmailChangeToken     string     `gorm:"type:varchar(64)" json:"-"` // SHA-256 of the token sent to the new address
+	EmailChangeExpiresAt *time.Time
 }
 
 type Movie struct {
@@ -21,7 +31,7 @@ type Movie struct {
 	Description string      `gorm:"type:text"`
 	Duration    int         `gorm:"not null"` // in minutes
 	Genre       string      `gorm:"type:varchar(100);not null"`
-	ReleaseDate time.Time   `gorm:"not null"`
+	ReleaseDate time.Time   `gorm:"type:date;not null"`
 	PosterURL   string      `gorm:"type:varchar(255)"`
 	ShowTimes   []ShowTime  `gorm:"foreignKey:MovieID"`
 }
@@ -30,27 +40,58 @@ type Movie struct {
 
 type Hall struct {
 	gorm.Model
-	Name      string     `gorm:"not null"`
-	Capacity  int        `gorm:"not null"`
-	ShowTimes []ShowTime `gorm:"foreignKey:HallID"`
+	Name     string `gorm:"not null"`
+	Capacity int    `gorm:"not null"`
+	// AllowOrphanSeats disables the rule against leaving single empty seats
+	AllowOrphanSeats bool       `gorm:"not null;default:false"`
+	Layout           []HallSeat `gorm:"foreignKey:HallID"`
+	ShowTimes        []ShowTime `gorm:"foreignKey:HallID"`
+}
+
+// HallSeat is a seat in the layout of a hall, copied into every showtime
+type HallSeat struct {
+	gorm.Model
+	HallID     uint   `gorm:"not null;uniqueIndex:idx_hall_seat"`
+	RowNumber  string `gorm:"not null;type:varchar(2);uniqueIndex:idx_hall_seat"`
+	SeatNumber int    `gorm:"not null;uniqueIndex:idx_hall_seat"`
+	Kind       string `gorm:"not null;type:varchar(20);default:'STANDARD'"` // STANDARD, WHEELCHAIR, COMPANION
 }
 
 type Seat struct {
 	gorm.Model
-	HallID     uint    `gorm:"not null;uniqueIndex:idx_hall_seat"`
-	ShowTimeID uint    `gorm:"not null"`
-	RowNumber  string  `gorm:"not null;type:varchar(2);uniqueIndex:idx_hall_seat"` // e.g., "A", "B"
-	SeatNumber int     `gorm:"not null;uniqueIndex:idx_hall_seat"`
+	HallID     uint    `gorm:"not null"`
+	ShowTimeID uint    `gorm:"not null;uniqueIndex:idx_showtime_seat_position"`
+	RowNumber  string  `gorm:"not null;type:varchar(2);uniqueIndex:idx_showtime_seat_position"` // e.g., "A", "B"
+	SeatNumber int     `gorm:"not null;uniqueIndex:idx_showtime_seat_position"`
 	Status     string  `gorm:"not null;type:varchar(20);default:'AVAILABLE'"` // AVAILABLE, RESERVED, BOOKED
+	Kind       string  `gorm:"not null;type:varchar(20);default:'STANDARD'"`  // STANDARD, WHEELCHAIR, COMPANION
 	Tickets    []Ticket `gorm:"foreignKey:SeatID"`
+	HeldForID  *uint   `gorm:"index"` // waitlist entry the seat is RESERVED for
 }
 
 type Ticket struct {
 	gorm.Model
 	UserID      uint      `gorm:"not null"`
-	ShowTimeID  uint      `gorm:"not null"`
-	SeatID      uint      `gorm:"not null;uniqueIndex:idx_showtime_seat"`
+	BookingID   *uint     `gorm:"index"`
+	ShowTimeID  uint      `gorm:"not null;uniqueIndex:idx_showtime_seat,where:status <> 'cancelled' AND deleted_at IS NULL"`
+	SeatID      uint      `gorm:"not null;uniqueIndex:idx_showtime_seat,where:status <> 'cancelled' AND deleted_at IS NULL"`
 	Status      string    `gorm:"not null;type:varchar(20);default:'reserved'"` // reserved, paid, cancelled
 	BookingCode string    `gorm:"not null;type:varchar(50);uniqueIndex"`
 	Price       float64   `gorm:"not null;type:decimal(10,2)"`
-}
\ No newline at end of file
+}
+
+const (
+	SeatKindStandard   = "STANDARD"
+	SeatKindWheelchair = "WHEELCHAIR"
+	SeatKindCompanion  = "COMPANION"
+)
+
+const (
+	TicketStatusPaid      = "paid"
+	TicketStatusCancelled = "cancelled"
+)
+
+const (
+	RoleCustomer = "CUSTOMER"
+	RoleAdmin    = "ADMIN"
+)
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification is an email queued for delivery to a customer
+type Notification struct {
+	gorm.Model
+	UserID    *uint
+	Recipient string `gorm:"not null;type:varchar(255)"`
+	Subject   string `gorm:"not null;type:varchar(255)"`
+	Body      string `gorm:"not null;type:text"`
+	HTMLBody  string `gorm:"not null;type:text;default:''"`
+	Status    string `gorm:"not null;type:varchar(20);default:'PENDING';index"` // PENDING, SENT, FAILED
+	Attempts  int    `gorm:"not null;default:0"`
+	LastError string `gorm:"type:text"`
+	SentAt    *time.Time
+
+	Attachments []NotificationAttachment `gorm:"foreignKey:NotificationID"`
+}
+
+// NotificationAttachment is a file sent with a notification, such as a QR
+// code image or a calendar invite
+type NotificationAttachment struct {
+	gorm.Model
+	NotificationID uint   `gorm:"not null;index"`
+	Filename       string `gorm:"not null;type:varchar(255)"`
+	ContentType    string `gorm:"not null;type:varchar(100)"`
+	ContentID      string `gorm:"type:varchar(100)"` // set for images shown inline in the HTML body
+	Content        []byte `gorm:"not null;type:bytea"`
+}
+
+const (
+	NotificationStatusPending = "PENDING"
+	NotificationStatusSent    = "SENT"
+	NotificationStatusFailed  = "FAILED"
+)
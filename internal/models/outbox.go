@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxEvent is a domain event waiting to be published, written in the same
+// transaction as the change it describes
+type OutboxEvent struct {
+	gorm.Model
+	AggregateType string `gorm:"not null;type:varchar(50)"` // booking, showtime
+	AggregateID   uint   `gorm:"not null"`
+	Type          string `gorm:"not null;type:varchar(50)"`
+	Payload       string `gorm:"not null;type:text"` // JSON
+	PublishedAt   *time.Time
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"type:text"`
+	NextAttemptAt *time.Time // when a failed event is retried
+	DeadAt        *time.Time // set aside after too many failed attempts
+}
+
+// OutboxDelivery records that an event was published to a sink, so that it is
+// not published there again when another sink fails
+type OutboxDelivery struct {
+	ID        uint   `gorm:"primarykey"`
+	EventID   uint   `gorm:"not null;uniqueIndex:idx_outbox_deliveries_event_sink"`
+	Sink      string `gorm:"not null;type:varchar(50);uniqueIndex:idx_outbox_deliveries_event_sink"`
+	CreatedAt time.Time
+}
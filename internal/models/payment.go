@@ -0,0 +1,30 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Payment is a charge or refund of a booking through the payment gateway
+type Payment struct {
+	gorm.Model
+	BookingID      uint    `gorm:"not null;index"`
+	Kind           string  `gorm:"not null;type:varchar(20)"` // CHARGE, REFUND, TRANSFER_IN, TRANSFER_OUT
+	Amount         float64 `gorm:"not null;type:decimal(10,2)"`
+	Status         string  `gorm:"not null;type:varchar(20);index"` // PENDING, SUCCEEDED, FAILED
+	IdempotencyKey string  `gorm:"not null;type:varchar(100);uniqueIndex"`
+	Reference      string  `gorm:"type:varchar(255)"` // gateway transaction ID
+	Error          string  `gorm:"type:text"`
+	Attempts       int     `gorm:"not null;default:0"`
+}
+
+const (
+	PaymentKindCharge = "CHARGE"
+	PaymentKindRefund = "REFUND"
+	// Credit moved between bookings when one is exchanged for another
+	PaymentKindTransferIn  = "TRANSFER_IN"
+	PaymentKindTransferOut = "TRANSFER_OUT"
+
+	PaymentStatusPending   = "PENDING"
+	PaymentStatusSucceeded = "SUCCEEDED"
+	PaymentStatusFailed    = "FAILED"
+)
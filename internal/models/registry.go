@@ -0,0 +1,33 @@
+package models
+
+// All returns every model persisted by the application, used to verify that
+// the models match the migrated database schema
+func All() []interface{} {
+	return []interface{}{
+		&User{},
+		&Movie{},
+		&Hall{},
+		&HallSeat{},
+		&ShowTime{},
+		&ShowtimeSchedule{},
+		&Seat{},
+		&Ticket{},
+		&Booking{},
+		&BookingSeat{},
+		&UserIdentity{},
+		&OIDCLoginState{},
+		&LoginEvent{},
+		&DataExport{},
+		&Payment{},
+		&Notification{},
+		&NotificationAttachment{},
+		&WaitlistEntry{},
+		&Job{},
+		&OutboxEvent{},
+		&OutboxDelivery{},
+		&WebhookSubscription{},
+		&WebhookDelivery{},
+		&Invoice{},
+		&InvoiceSequence{},
+	}
+}
@@ -8,13 +8,44 @@ import (
 
 type ShowTime struct {
 	gorm.Model
-	MovieID   uint      `gorm:"not null"`
-	Movie     Movie     `gorm:"foreignKey:MovieID"`
-	HallID    uint      `gorm:"not null"`
-	Hall      Hall      `gorm:"foreignKey:HallID"`
-	StartTime time.Time `gorm:"not null"`
-	EndTime   time.Time `gorm:"not null"`
-	Price     float64   `gorm:"not null"`
-	Seats     []Seat    `gorm:"foreignKey:ShowTimeID"`
-	Bookings  []Booking `gorm:"foreignKey:ShowTimeID"`
-}
\ No newline at end of file
+	MovieID            uint      `gorm:"not null"`
+	Movie              Movie     `gorm:"foreignKey:MovieID"`
+	HallID             uint      `gorm:"not null"`
+	Hall               Hall      `gorm:"foreignKey:HallID"`
+	StartTime          time.Time `gorm:"not null"`
+	EndTime            time.Time `gorm:"not null"`
+	Price              float64   `gorm:"not null"`
+	Status             string    `gorm:"not null;type:varchar(20);default:'SCHEDULED'"` // SCHEDULED, CANCELLED
+	ScheduleID         *uint     `gorm:"index"`                                         // set when generated from a recurring schedule
+	CancellationReason string    `gorm:"type:text"`
+	CancelledAt        *time.Time
+	Seats              []Seat    `gorm:"foreignKey:ShowTimeID"`
+	Bookings           []Booking `gorm:"foreignKey:ShowTimeID"`
+}
+
+const (
+	ShowTimeStatusScheduled = "SCHEDULED"
+	ShowTimeStatusCancelled = "CANCELLED"
+)
+
+// ShowtimeSchedule is a recurring programme rule, e.g. daily at 14:00 and
+// 20:00, that generates showtimes for a date range
+type ShowtimeSchedule struct {
+	gorm.Model
+	MovieID   uint       `gorm:"not null"`
+	Movie     Movie      `gorm:"foreignKey:MovieID"`
+	HallID    uint       `gorm:"not null"`
+	Hall      Hall       `gorm:"foreignKey:HallID"`
+	StartDate time.Time  `gorm:"type:date;not null"`
+	EndDate   time.Time  `gorm:"type:date;not null"`
+	Weekdays  string     `gorm:"not null;type:varchar(27)"`  // e.g., "MON,WED,FRI"
+	Times     string     `gorm:"not null;type:varchar(255)"` // local start times, e.g., "14:00,20:00"
+	Price     float64    `gorm:"not null;type:decimal(10,2)"`
+	Status    string     `gorm:"not null;type:varchar(20);default:'ACTIVE'"` // ACTIVE, REMOVED
+	ShowTimes []ShowTime `gorm:"foreignKey:ScheduleID"`
+}
+
+const (
+	ScheduleStatusActive  = "ACTIVE"
+	ScheduleStatusRemoved = "REMOVED"
+)
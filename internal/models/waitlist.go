@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WaitlistEntry is a customer waiting for seats of a sold-out showtime. When
+// seats are released the first waiting customer is offered a hold on them.
+type WaitlistEntry struct {
+	gorm.Model
+	ShowTimeID     uint     `gorm:"not null;uniqueIndex:idx_waitlist_active,where:status = 'WAITING' OR status = 'OFFERED'"`
+	Showtime       ShowTime `gorm:"foreignKey:ShowTimeID"`
+	UserID         uint     `gorm:"not null;uniqueIndex:idx_waitlist_active,where:status = 'WAITING' OR status = 'OFFERED'"`
+	User           User     `gorm:"foreignKey:UserID"`
+	SeatCount      int      `gorm:"not null"`
+	Status         string   `gorm:"not null;type:varchar(20);default:'WAITING'"` // WAITING, OFFERED, BOOKED, EXPIRED, LEFT
+	OfferedAt      *time.Time
+	OfferExpiresAt *time.Time `gorm:"index"`
+	BookingID      *uint
+	// HeldSeats are the seats reserved for the customer while the offer lasts
+	HeldSeats []Seat `gorm:"foreignKey:HeldForID"`
+}
+
+const (
+	WaitlistStatusWaiting = "WAITING"
+	WaitlistStatusOffered = "OFFERED"
+	WaitlistStatusBooked  = "BOOKED"
+	WaitlistStatusExpired = "EXPIRED"
+	WaitlistStatusLeft    = "LEFT"
+)
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription is a partner endpoint notified of domain events
+type WebhookSubscription struct {
+	gorm.Model
+	URL        string `gorm:"not null;type:varchar(2048)"`
+	EventTypes string `gorm:"not null;type:varchar(255)"` // comma-separated event types
+	Secret     string `gorm:"not null;type:varchar(255)"` // key the payloads are signed with
+	Active     bool   `gorm:"not null;default:true"`
+}
+
+// WebhookDelivery is an event sent, or to be sent, to a subscription
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint                `gorm:"not null;index"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID"`
+	EventID        uint                `gorm:"not null"`
+	EventType      string              `gorm:"not null;type:varchar(50)"`
+	// Payload is the JSON body posted to the subscription
+	Payload        string    `gorm:"not null;type:text"`
+	Status         string    `gorm:"not null;type:varchar(20);default:'PENDING';index:idx_webhook_deliveries_status_next_attempt_at"` // PENDING, SUCCEEDED, FAILED
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"not null;index:idx_webhook_deliveries_status_next_attempt_at"`
+	ResponseStatus *int      // HTTP status of the last attempt
+	LastError      string    `gorm:"type:text"`
+	DeliveredAt    *time.Time
+	ReplayOfID     *uint // delivery this one replays
+}
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED"
+)
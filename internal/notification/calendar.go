@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"strings"
+	"time"
+)
+
+// CalendarEvent is an event sent as an iCalendar (.ics) attachment
+type CalendarEvent struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Location    string
+	Description string
+	// Cancelled marks the event cancelled in calendars it was added to. The
+	// update is published rather than sent as a METHOD:CANCEL request, which
+	// would need an organizer and attendees the cinema doesn't have.
+	Cancelled bool
+}
+
+// ICS encodes the event as an iCalendar file (RFC 5545)
+func (e CalendarEvent) ICS() []byte {
+	// Calendars apply the update with the higher sequence number
+	status, sequence := "CONFIRMED", "0"
+	if e.Cancelled {
+		status, sequence = "CANCELLED", "1"
+	}
+
+	var b strings.Builder
+	line := func(name, value string) {
+		writeICSLine(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//movie-ticket-booking//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("BEGIN", "VEVENT")
+	line("UID", e.UID)
+	line("DTSTAMP", icsTime(time.Now()))
+	line("DTSTART", icsTime(e.Start))
+	line("DTEND", icsTime(e.End))
+	line("SUMMARY", icsEscape(e.Summary))
+	line("LOCATION", icsEscape(e.Location))
+	line("DESCRIPTION", icsEscape(e.Description))
+	line("STATUS", status)
+	line("SEQUENCE", sequence)
+	line("END", "VEVENT")
+	line("END", "VCALENDAR")
+	return []byte(b.String())
+}
+
+// Attachment returns the event as a message attachment
+func (e CalendarEvent) Attachment(filename string) Attachment {
+	return Attachment{
+		Filename:    filename,
+		ContentType: "text/calendar; charset=utf-8; method=PUBLISH",
+		Content:     e.ICS(),
+	}
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line, folded at 75 octets as RFC 5545 requires
+func writeICSLine(b *strings.Builder, line string) {
+	// Continuation lines start with a space, which counts towards the limit
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// Don't split multi-byte characters
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,101 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteICSLineFoldsLongLines(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Dune"},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"long", "DESCRIPTION:" + strings.Repeat("Seats A1\\, A2\\, A3 ", 20)},
+		{"multi-byte characters", "LOCATION:" + strings.Repeat("Kinosaal für Größen ü ", 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeICSLine(&b, tt.line)
+			out := b.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("%q does not end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, line := range lines {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d octets long", i, len(line))
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d %q does not start with a space", i, line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d %q splits a character", i, line)
+				}
+			}
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != tt.line {
+				t.Errorf("unfolded to %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Dune", "Dune"},
+		{"Hall 1, Cinema Street 5", `Hall 1\, Cinema Street 5`},
+		{"Seats: A1; A2", `Seats: A1\; A2`},
+		{`C:\films`, `C:\\films`},
+		{"Line one\nLine two\r\nLine three", `Line one\nLine two\nLine three`},
+	}
+	for _, tt := range tests {
+		if got := icsEscape(tt.in); got != tt.want {
+			t.Errorf("icsEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCancelledCalendarEventIsPublished(t *testing.T) {
+	start := time.Date(2026, 10, 20, 19, 30, 0, 0, time.UTC)
+	event := CalendarEvent{
+		UID:      "showtime-7@movie-ticket-booking",
+		Start:    start,
+		End:      start.Add(2 * time.Hour),
+		Summary:  "Dune, Part Two",
+		Location: "Hall 1",
+	}
+
+	tests := []struct {
+		cancelled bool
+		status    string
+		sequence  string
+	}{
+		{false, "CONFIRMED", "0"},
+		{true, "CANCELLED", "1"},
+	}
+	for _, tt := range tests {
+		event.Cancelled = tt.cancelled
+		ics := string(event.ICS())
+		for _, want := range []string{
+			"METHOD:PUBLISH\r\n",
+			"STATUS:" + tt.status + "\r\n",
+			"SEQUENCE:" + tt.sequence + "\r\n",
+			"UID:showtime-7@movie-ticket-booking\r\n",
+			"DTSTART:20261020T193000Z\r\n",
+			"SUMMARY:Dune\\, Part Two\r\n",
+		} {
+			if !strings.Contains(ics, want) {
+				t.Errorf("cancelled %v: %q missing from\n%s", tt.cancelled, want, ics)
+			}
+		}
+		if attachment := event.Attachment("showtime.ics"); attachment.ContentType != "text/calendar; charset=utf-8; method=PUBLISH" {
+			t.Errorf("cancelled %v: content type %q", tt.cancelled, attachment.ContentType)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Message is an email sent to a customer
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// HTMLBody is an alternative HTML version of Body, if any
+	HTMLBody    string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message. Attachments with a ContentID are
+// shown inline, referenced from the HTML body as cid:<ContentID>.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Content     []byte
+}
+
+// Sender delivers messages to customers
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender writes messages to the application log instead of delivering them,
+// which is useful in development
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	attachments := make([]string, len(msg.Attachments))
+	for i, attachment := range msg.Attachments {
+		attachments[i] = attachment.Filename
+	}
+	slog.InfoContext(ctx, "sending email", "to", msg.To, "subject", msg.Subject, "body", msg.Body, "attachments", attachments)
+	return nil
+}
@@ -0,0 +1,133 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Email templates live in templates/<locale>/<name>.txt and <name>.html. The
+// text template defines a "subject" block and renders the plain text body;
+// the HTML template renders the HTML body and is optional.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// Renderer renders localized email templates
+type Renderer struct {
+	defaultLocale string
+	text          map[string]*texttemplate.Template
+	html          map[string]*htmltemplate.Template
+	names         map[string]bool
+}
+
+// NewRenderer parses the embedded templates. Messages in a locale without a
+// translation of the template are rendered in defaultLocale.
+func NewRenderer(defaultLocale string) (*Renderer, error) {
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		text:          make(map[string]*texttemplate.Template),
+		html:          make(map[string]*htmltemplate.Template),
+		names:         make(map[string]bool),
+	}
+	err := fs.WalkDir(templateFiles, "templates", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		locale := path.Base(path.Dir(file))
+		name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		key := locale + "/" + name
+		switch path.Ext(file) {
+		case ".txt":
+			t, err := texttemplate.New(path.Base(file)).Funcs(templateFuncs).ParseFS(templateFiles, file)
+			if err != nil {
+				return err
+			}
+			if t.Lookup("subject") == nil {
+				return fmt.Errorf("template %s has no subject", file)
+			}
+			r.text[key] = t
+			r.names[name] = true
+		case ".html":
+			t, err := htmltemplate.New(path.Base(file)).Funcs(htmltemplate.FuncMap(templateFuncs)).ParseFS(templateFiles, file)
+			if err != nil {
+				return err
+			}
+			r.html[key] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email templates: %w", err)
+	}
+	for name := range r.names {
+		if r.text[defaultLocale+"/"+name] == nil {
+			return nil, fmt.Errorf("email template %s has no %s version", name, defaultLocale)
+		}
+	}
+	return r, nil
+}
+
+// Templates returns the names of the email templates
+func (r *Renderer) Templates() []string {
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render renders a template into a message with subject, text and HTML body.
+// Locales such as de-AT fall back to de and then to the default locale.
+func (r *Renderer) Render(name, locale string, data interface{}) (Message, error) {
+	locale = r.resolve(name, locale)
+	text := r.text[locale+"/"+name]
+	if text == nil {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var msg Message
+	var buf bytes.Buffer
+	if err := text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return Message{}, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err := text.Execute(&buf, data); err != nil {
+		return Message{}, err
+	}
+	msg.Body = strings.TrimSpace(buf.String()) + "\n"
+
+	if html := r.html[locale+"/"+name]; html != nil {
+		buf.Reset()
+		if err := html.Execute(&buf, data); err != nil {
+			return Message{}, err
+		}
+		msg.HTMLBody = buf.String()
+	}
+	return msg, nil
+}
+
+// resolve returns the locale a template is rendered in
+func (r *Renderer) resolve(name, locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if r.text[locale+"/"+name] != nil {
+		return locale
+	}
+	if language, _, ok := strings.Cut(locale, "-"); ok && r.text[language+"/"+name] != nil {
+		return language
+	}
+	return r.defaultLocale
+}
+
+var templateFuncs = texttemplate.FuncMap{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+	"join":  strings.Join,
+}
@@ -0,0 +1,97 @@
+package notification
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRendererResolvesLocales(t *testing.T) {
+	r, err := NewRenderer("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		locale, want string
+	}{
+		{"de", "de"},
+		{"de-AT", "de"},
+		{"de_AT", "de"},
+		{"DE", "de"},
+		{"en-GB", "en"},
+		{"fr", "en"},
+		{"fr-CA", "en"},
+		{"", "en"},
+	}
+	for _, tt := range tests {
+		if got := r.resolve("booking_confirmed", tt.locale); got != tt.want {
+			t.Errorf("resolve(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+
+	if _, err := r.Render("no_such_template", "en", nil); err == nil {
+		t.Error("rendered an unknown template")
+	}
+}
+
+func TestRendererRendersEveryTemplate(t *testing.T) {
+	r, err := NewRenderer("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type ticket struct {
+		Seat   string
+		Code   string
+		QRCode htmltemplate.URL
+	}
+	data := struct {
+		Name      string
+		BookingID uint
+		Movie     string
+		Hall      string
+		StartTime time.Time
+		Seats     []string
+		Tickets   []ticket
+		Total     float64
+		Refund    float64
+	}{
+		Name:      "Ada <Lovelace>",
+		BookingID: 42,
+		Movie:     "Dune",
+		Hall:      "Hall 1",
+		StartTime: time.Date(2026, 10, 20, 19, 30, 0, 0, time.UTC),
+		Seats:     []string{"A1", "A2"},
+		Tickets: []ticket{
+			{Seat: "A1", Code: "CODE1", QRCode: "cid:ticket-CODE1"},
+			{Seat: "A2", Code: "CODE2", QRCode: "cid:ticket-CODE2"},
+		},
+		Total:  25,
+		Refund: 12.5,
+	}
+
+	if len(r.Templates()) == 0 {
+		t.Fatal("no templates")
+	}
+	for _, name := range r.Templates() {
+		for _, locale := range []string{"en", "de"} {
+			msg, err := r.Render(name, locale, data)
+			if err != nil {
+				t.Errorf("%s/%s: %v", locale, name, err)
+				continue
+			}
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("%s/%s: subject %q", locale, name, msg.Subject)
+			}
+			if !strings.Contains(msg.Body, "Dune") || !strings.Contains(msg.Body, "42") {
+				t.Errorf("%s/%s: body is missing the booking\n%s", locale, name, msg.Body)
+			}
+			if msg.HTMLBody == "" {
+				t.Errorf("%s/%s: no HTML body", locale, name)
+			}
+			if strings.Contains(msg.HTMLBody, "<Lovelace>") {
+				t.Errorf("%s/%s: HTML body does not escape the customer name", locale, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Gateway charges and refunds customers. Calls with the same idempotency key
+// must have the same effect as a single call, so they can be retried safely.
+type Gateway interface {
+	Charge(ctx context.Context, idempotencyKey string, amount float64) (reference string, err error)
+	Refund(ctx context.Context, idempotencyKey, chargeReference string, amount float64) (reference string, err error)
+}
+
+// LogGateway accepts every payment and only logs it, which is useful in development
+type LogGateway struct{}
+
+func NewLogGateway() *LogGateway {
+	return &LogGateway{}
+}
+
+func (g *LogGateway) Charge(ctx context.Context, idempotencyKey string, amount float64) (string, error) {
+	slog.InfoContext(ctx, "charging payment", "key", idempotencyKey, "amount", amount)
+	return fmt.Sprintf("log-%s", idempotencyKey), nil
+}
+
+func (g *LogGateway) Refund(ctx context.Context, idempotencyKey, chargeReference string, amount float64) (string, error) {
+	slog.InfoContext(ctx, "refunding payment", "key", idempotencyKey, "charge", chargeReference, "amount", amount)
+	return fmt.Sprintf("log-%s", idempotencyKey), nil
+}
@@ -0,0 +1,300 @@
+// Package seating picks the best available seats of a hall. It works on the
+// hall layout and current seat statuses only, so it is independent of storage.
+package seating
+
+import (
+	"math"
+	"sort"
+)
+
+// Seat is a seat of the hall layout
+type Seat struct {
+	ID     uint
+	Row    int // 0 is the row closest to the screen
+	Number int // numbers are consecutive within a block; a gap is an aisle
+	Free   bool
+}
+
+// RowPreference is where in the hall the customer prefers to sit
+type RowPreference string
+
+const (
+	RowFront  RowPreference = "FRONT"
+	RowMiddle RowPreference = "MIDDLE"
+	RowBack   RowPreference = "BACK"
+)
+
+// Preferences tune the seat selection
+type Preferences struct {
+	Row RowPreference
+	// AllowSplit allows suggesting seats that are not next to each other
+	// when no block of adjacent seats is free
+	AllowSplit bool
+}
+
+// Suggestion is a set of seats with its score; higher scores are better
+type Suggestion struct {
+	Seats    []Seat
+	Score    float64
+	Together bool // all seats are adjacent in one row
+}
+
+// Weights of the scoring criteria
+const (
+	centreWeight = 0.4
+	rowWeight    = 0.4
+	orphanWeight = 0.2
+	splitPenalty = 0.5
+)
+
+// idealRow is the preferred distance from the screen as a share of the hall depth
+var idealRow = map[RowPreference]float64{
+	RowFront:  0.2,
+	RowMiddle: 0.6,
+	RowBack:   0.9,
+}
+
+// Suggest returns up to limit suggestions of count seats, best first
+func Suggest(seats []Seat, count, limit int, prefs Preferences) []Suggestion {
+	if count <= 0 || limit <= 0 {
+		return nil
+	}
+	h := newHall(seats)
+
+	var suggestions []Suggestion
+	for _, block := range h.blocks {
+		for start := 0; start+count <= len(block); start++ {
+			window := block[start : start+count]
+			if !allFree(window) {
+				continue
+			}
+			suggestions = append(suggestions, Suggestion{
+				Seats:    copySeats(window),
+				Score:    h.score(window, prefs) - orphanWeight*float64(orphansAround(block, start, start+count)),
+				Together: true,
+			})
+		}
+	}
+	sortSuggestions(suggestions)
+
+	if len(suggestions) == 0 && prefs.AllowSplit {
+		if split := h.split(count, prefs); split != nil {
+			suggestions = append(suggestions, *split)
+		}
+	}
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// LeavesOrphan reports whether taking the seats with the given IDs leaves a
+// single free seat isolated between taken seats, an aisle or the end of a row
+func LeavesOrphan(seats []Seat, taken []uint) bool {
+	return len(Orphans(seats, taken)) > 0
+}
+
+// Orphans returns the free seats that would be isolated by taking the seats
+// with the given IDs. Seats that were isolated before are not reported.
+func Orphans(seats []Seat, taken []uint) []Seat {
+	take := make(map[uint]bool, len(taken))
+	for _, id := range taken {
+		take[id] = true
+	}
+
+	var orphans []Seat
+	for _, block := range newHall(seats).blocks {
+		for i, seat := range block {
+			if !seat.Free || take[seat.ID] {
+				continue
+			}
+			leftTaken := i == 0 || !block[i-1].Free || take[block[i-1].ID]
+			rightTaken := i == len(block)-1 || !block[i+1].Free || take[block[i+1].ID]
+			if !leftTaken || !rightTaken {
+				continue
+			}
+			// Only count seats isolated by this selection
+			wasLeft := i == 0 || !block[i-1].Free
+			wasRight := i == len(block)-1 || !block[i+1].Free
+			if !wasLeft || !wasRight {
+				orphans = append(orphans, seat)
+			}
+		}
+	}
+	return orphans
+}
+
+// Alternative returns the selection of adjacent seats closest to the seats
+// with the given IDs that is free and leaves no orphans, or nil. Only
+// selections within a single block of seats are moved.
+func Alternative(seats []Seat, taken []uint) []Seat {
+	take := make(map[uint]bool, len(taken))
+	for _, id := range taken {
+		take[id] = true
+	}
+
+	for _, block := range newHall(seats).blocks {
+		first, count := -1, 0
+		for i, seat := range block {
+			if take[seat.ID] {
+				if first < 0 {
+					first = i
+				}
+				count++
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		if count != len(take) {
+			return nil
+		}
+
+		n := len(take)
+		for distance := 1; distance < len(block); distance++ {
+			for _, start := range []int{first - distance, first + distance} {
+				if start < 0 || start+n > len(block) {
+					continue
+				}
+				window := block[start : start+n]
+				if !allFree(window) {
+					continue
+				}
+				ids := make([]uint, n)
+				for i, seat := range window {
+					ids[i] = seat.ID
+				}
+				if !LeavesOrphan(seats, ids) {
+					return copySeats(window)
+				}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// hall is the layout grouped into blocks of adjacent seats
+type hall struct {
+	blocks  [][]Seat
+	rows    int
+	centres map[int]float64 // centre seat number of every row
+	widths  map[int]float64 // half the width of every row
+}
+
+func newHall(seats []Seat) *hall {
+	sorted := copySeats(seats)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Row != sorted[j].Row {
+			return sorted[i].Row < sorted[j].Row
+		}
+		return sorted[i].Number < sorted[j].Number
+	})
+
+	h := &hall{centres: make(map[int]float64), widths: make(map[int]float64)}
+	first := make(map[int]int)
+	for i, seat := range sorted {
+		newRow := i == 0 || sorted[i-1].Row != seat.Row
+		if newRow || sorted[i-1].Number+1 != seat.Number {
+			h.blocks = append(h.blocks, nil)
+		}
+		h.blocks[len(h.blocks)-1] = append(h.blocks[len(h.blocks)-1], seat)
+
+		if newRow {
+			first[seat.Row] = seat.Number
+		}
+		h.centres[seat.Row] = float64(first[seat.Row]+seat.Number) / 2
+		h.widths[seat.Row] = math.Max(float64(seat.Number-first[seat.Row])/2, 1)
+		if seat.Row+1 > h.rows {
+			h.rows = seat.Row + 1
+		}
+	}
+	return h
+}
+
+// score rates seats between 0 and 1 by how close they are to the centre of
+// their rows and to the preferred row
+func (h *hall) score(seats []Seat, prefs Preferences) float64 {
+	ideal, ok := idealRow[prefs.Row]
+	if !ok {
+		ideal = idealRow[RowMiddle]
+	}
+	depth := math.Max(float64(h.rows-1), 1)
+
+	var centre, row float64
+	for _, seat := range seats {
+		centre += math.Abs(float64(seat.Number)-h.centres[seat.Row]) / h.widths[seat.Row]
+		row += math.Abs(float64(seat.Row)/depth - ideal)
+	}
+	n := float64(len(seats))
+	return 1 - centreWeight*centre/n - rowWeight*row/n
+}
+
+// split picks the best free seats one by one when no block is free
+func (h *hall) split(count int, prefs Preferences) *Suggestion {
+	var free []Seat
+	for _, block := range h.blocks {
+		for _, seat := range block {
+			if seat.Free {
+				free = append(free, seat)
+			}
+		}
+	}
+	if len(free) < count {
+		return nil
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		return h.score(free[i:i+1], prefs) > h.score(free[j:j+1], prefs)
+	})
+	chosen := free[:count]
+	sort.Slice(chosen, func(i, j int) bool {
+		if chosen[i].Row != chosen[j].Row {
+			return chosen[i].Row < chosen[j].Row
+		}
+		return chosen[i].Number < chosen[j].Number
+	})
+	return &Suggestion{
+		Seats: copySeats(chosen),
+		Score: h.score(chosen, prefs) - splitPenalty,
+	}
+}
+
+// orphansAround counts the single free seats left next to block[start:end]
+func orphansAround(block []Seat, start, end int) int {
+	orphans := 0
+	if start == 1 && block[0].Free || start >= 2 && block[start-1].Free && !block[start-2].Free {
+		orphans++
+	}
+	if end == len(block)-1 && block[end].Free || end <= len(block)-2 && block[end].Free && !block[end+1].Free {
+		orphans++
+	}
+	return orphans
+}
+
+func allFree(seats []Seat) bool {
+	for _, seat := range seats {
+		if !seat.Free {
+			return false
+		}
+	}
+	return true
+}
+
+func copySeats(seats []Seat) []Seat {
+	return append([]Seat(nil), seats...)
+}
+
+// sortSuggestions orders suggestions by score, then from the front row and left
+// seat so that the order is deterministic
+func sortSuggestions(suggestions []Suggestion) {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Seats[0].Row != b.Seats[0].Row {
+			return a.Seats[0].Row < b.Seats[0].Row
+		}
+		return a.Seats[0].Number < b.Seats[0].Number
+	})
+}
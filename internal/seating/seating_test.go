@@ -0,0 +1,181 @@
+package seating
+
+import (
+	"reflect"
+	"testing"
+)
+
+// layout builds a hall from one string per row, front row first: '.' is a
+// free seat, 'X' a taken one and ' ' an aisle. Seat IDs are row*100+number.
+func layout(rows ...string) []Seat {
+	var seats []Seat
+	for row, line := range rows {
+		for i, c := range line {
+			if c == ' ' {
+				continue
+			}
+			number := i + 1
+			seats = append(seats, Seat{
+				ID:     uint(row*100 + number),
+				Row:    row,
+				Number: number,
+				Free:   c == '.',
+			})
+		}
+	}
+	return seats
+}
+
+func ids(seats []Seat) []uint {
+	if seats == nil {
+		return nil
+	}
+	result := make([]uint, len(seats))
+	for i, seat := range seats {
+		result[i] = seat.ID
+	}
+	return result
+}
+
+func TestSuggest(t *testing.T) {
+	hall := layout(".......", ".......", ".......", ".......", ".......")
+
+	tests := []struct {
+		name  string
+		seats []Seat
+		count int
+		prefs Preferences
+		want  [][]uint // IDs of the expected suggestions, best first
+	}{
+		{"centre of the middle row", hall, 1, Preferences{Row: RowMiddle}, [][]uint{{204}}},
+		{"front preference", hall, 1, Preferences{Row: RowFront}, [][]uint{{104}}},
+		{"back preference", hall, 1, Preferences{Row: RowBack}, [][]uint{{404}}},
+		{"unknown preference is middle", hall, 1, Preferences{}, [][]uint{{204}}},
+		{"pair at the centre", layout("......"), 2, Preferences{}, [][]uint{{3, 4}}},
+		{"taken seats are skipped", layout("..XX.."), 2, Preferences{}, [][]uint{{1, 2}, {5, 6}}},
+		{"blocks split by aisles", layout("... ..."), 3, Preferences{}, [][]uint{{1, 2, 3}, {5, 6, 7}}},
+		{"no block spans an aisle", layout("... ..."), 4, Preferences{}, nil},
+		{"split needs permission", layout(".X.X."), 2, Preferences{}, nil},
+		{"count larger than any block", layout(".. ..", ".. .."), 3, Preferences{}, nil},
+		{"count larger than the free seats", layout(".X.X."), 4, Preferences{AllowSplit: true}, nil},
+		{"no seats", nil, 1, Preferences{}, nil},
+		{"zero count", hall, 0, Preferences{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]uint
+			for _, suggestion := range Suggest(tt.seats, tt.count, 2, tt.prefs) {
+				if !suggestion.Together {
+					t.Errorf("suggestion %v is not together", ids(suggestion.Seats))
+				}
+				got = append(got, ids(suggestion.Seats))
+			}
+			if len(tt.want) > 0 && len(got) > len(tt.want) {
+				got = got[:len(tt.want)]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestOrdersByScore(t *testing.T) {
+	suggestions := Suggest(layout(".........", ".........", "........."), 2, 100, Preferences{Row: RowMiddle})
+	if len(suggestions) == 0 {
+		t.Fatal("no suggestions")
+	}
+	for i := 1; i < len(suggestions); i++ {
+		if suggestions[i].Score > suggestions[i-1].Score {
+			t.Errorf("suggestion %d scores %v, more than %v before it", i, suggestions[i].Score, suggestions[i-1].Score)
+		}
+	}
+}
+
+func TestSuggestAvoidsOrphans(t *testing.T) {
+	// Seats 3 and 4 are more central but would strand seat 2 next to the taken seat 1
+	got := ids(Suggest(layout("X.....X"), 2, 1, Preferences{})[0].Seats)
+	if want := []uint{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest = %v, want %v", got, want)
+	}
+}
+
+func TestSuggestSplitFallback(t *testing.T) {
+	suggestions := Suggest(layout(".X.X.", "X.X.X"), 3, 5, Preferences{AllowSplit: true})
+	if len(suggestions) != 1 {
+		t.Fatalf("got %d suggestions, want 1", len(suggestions))
+	}
+	split := suggestions[0]
+	if split.Together {
+		t.Error("split suggestion is marked together")
+	}
+	if len(split.Seats) != 3 || !allFree(split.Seats) {
+		t.Errorf("split suggestion %v is not 3 free seats", ids(split.Seats))
+	}
+	if split.Score >= 1-splitPenalty {
+		t.Errorf("split suggestion score %v is not penalised", split.Score)
+	}
+
+	// Seats together are preferred even when splitting is allowed
+	if suggestions := Suggest(layout(".X..."), 2, 5, Preferences{AllowSplit: true}); len(suggestions) == 0 || !suggestions[0].Together {
+		t.Error("split suggested although adjacent seats are free")
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	tests := []struct {
+		name  string
+		seats []Seat
+		taken []uint
+		want  []uint
+	}{
+		{"row start is an edge", layout("....."), []uint{2}, []uint{1}},
+		{"row end is an edge", layout("....."), []uint{3, 4}, []uint{5}},
+		{"between taken seats", layout("..X.."), []uint{2, 5}, []uint{1, 4}},
+		{"aisle is an edge", layout(".. ..."), []uint{5}, []uint{4, 6}},
+		{"other block is not affected", layout(".. ..."), []uint{1, 2}, nil},
+		{"no orphan", layout("......"), []uint{1, 2}, nil},
+		{"existing orphans are not reported", layout(".X...."), []uint{5, 6}, nil},
+		{"existing and new orphans", layout(".X...."), []uint{4}, []uint{3}},
+		{"rows are separate", layout("...", "..."), []uint{2}, []uint{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(Orphans(tt.seats, tt.taken))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Orphans = %v, want %v", got, tt.want)
+			}
+			if leaves := LeavesOrphan(tt.seats, tt.taken); leaves != (len(tt.want) > 0) {
+				t.Errorf("LeavesOrphan = %v, want %v", leaves, len(tt.want) > 0)
+			}
+		})
+	}
+}
+
+func TestAlternative(t *testing.T) {
+	tests := []struct {
+		name  string
+		seats []Seat
+		taken []uint
+		want  []uint
+	}{
+		{"moves towards the row start", layout("......"), []uint{2, 3}, []uint{1, 2}},
+		{"moves towards the row end", layout("..X...."), []uint{6}, []uint{7}},
+		{"fills the seat next to a taken one", layout("X.....X"), []uint{3}, []uint{2}},
+		{"stays within the block", layout(".. ..."), []uint{5}, []uint{4}},
+		{"no selection avoids an orphan", layout("X...X"), []uint{2, 3}, nil},
+		{"selection across blocks", layout(".. .."), []uint{2, 4}, nil},
+		{"unknown seats", layout("..."), []uint{42}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(Alternative(tt.seats, tt.taken))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Alternative = %v, want %v", got, tt.want)
+			}
+			if got != nil && LeavesOrphan(tt.seats, got) {
+				t.Errorf("alternative %v leaves an orphan", got)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
-	"errors"
+	"log/slog"
+	"movie-ticket-booking/internal/apperrors"
 	"movie-ticket-booking/internal/models"
+	"net/http"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,21 +13,28 @@ import (
 )
 
 type AuthService struct {
-	db          *gorm.DB
-	jwtSecret   []byte
-	tokenExpiry time.Duration
+	db            *gorm.DB
+	jwtSecret     []byte
+	tokenExpiry   time.Duration
+	oidcStateTTL  time.Duration
+	httpClient    *http.Client
+	oidcProviders map[string]*oidcProvider
 }
 
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID uint `json:"user_id"`
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role,omitempty"`
 }
 
-func NewAuthService(db *gorm.DB, jwtSecret string) *AuthService {
+func NewAuthService(db *gorm.DB, jwtSecret string, tokenExpiry, oidcStateTTL time.Duration) *AuthService {
 	return &AuthService{
-		db:          db,
-		jwtSecret:   []byte(jwtSecret),
-		tokenExpiry: 24 * time.Hour, // Token expires in 24 hours
+		db:            db,
+		jwtSecret:     []byte(jwtSecret),
+		tokenExpiry:   tokenExpiry,
+		oidcStateTTL:  oidcStateTTL,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		oidcProviders: make(map[string]*oidcProvider),
 	}
 }
 
@@ -33,7 +42,7 @@ func (s *AuthService) Register(email, password, name, phone string) (*models.Use
 	// Check if user already exists
 	var existingUser models.User
 	if err := s.db.Where("email = ?", email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("user already exists")
+		return nil, apperrors.Conflict("user already exists")
 	} else if err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -64,19 +73,34 @@ func (s *AuthService) Login(email, password string) (string, error) {
 	var user models.User
 	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", errors.New("invalid credentials")
+			return "", apperrors.Unauthenticated("invalid credentials")
 		}
 		return "", err
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+		return "", apperrors.Unauthenticated("invalid credentials")
 	}
 
+	s.recordLogin(user.ID, "password")
+
 	// Generate JWT token
+	return s.generateToken(&user)
+}
+
+// recordLogin stores a login event for the user's login history
+func (s *AuthService) recordLogin(userID uint, method string) {
+	if err := s.db.Create(&models.LoginEvent{UserID: userID, Method: method}).Error; err != nil {
+		slog.Error("failed to record login", "user_id", userID, "error", err)
+	}
+}
+
+// generateToken issues a signed JWT for the given user
+func (s *AuthService) generateToken(user *models.User) (string, error) {
 	claims := Claims{
 		UserID: user.ID,
+		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -101,9 +125,19 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, apperrors.Unauthenticated("invalid token")
+	}
+
+	// Tokens outlive deleted accounts
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("id = ?", claims.UserID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, apperrors.Unauthenticated("account no longer exists")
 	}
 
-	return nil, errors.New("invalid token")
+	return claims, nil
 }
\ No newline at end of file
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancellationPolicy decides how much customers get back when they cancel
+// seats themselves. Cancellations by the cinema are always refunded in full.
+type CancellationPolicy struct {
+	FullRefundBefore     time.Duration // full refund when cancelling at least this long before the show
+	PartialRefundBefore  time.Duration // partial refund when cancelling at least this long before the show
+	PartialRefundPercent float64       // share of the amount refunded in the partial refund window
+}
+
+// Refund returns the part of amount refunded when cancelling at now for a show starting at start
+func (p CancellationPolicy) Refund(amount float64, start, now time.Time) float64 {
+	notice := start.Sub(now)
+	switch {
+	case notice >= p.FullRefundBefore:
+		return amount
+	case notice >= p.PartialRefundBefore:
+		return math.Round(amount*p.PartialRefundPercent) / 100
+	default:
+		return 0
+	}
+}
+
+// charge takes payment for a booking within tx. The customer is charged before
+// tx commits so that no booking is ever confirmed without payment.
+func (s *BookingService) charge(ctx context.Context, tx *gorm.DB, bookingID uint, amount float64, key string) (*models.Payment, error) {
+	reference, err := s.payments.Charge(ctx, key, amount)
+	if err != nil {
+		metrics.Payments.WithLabelValues("charge", "failed").Inc()
+		return nil, apperrors.Wrap(apperrors.CodePaymentFailed, err, "payment was declined")
+	}
+	metrics.Payments.WithLabelValues("charge", "succeeded").Inc()
+
+	payment := &models.Payment{
+		BookingID:      bookingID,
+		Kind:           models.PaymentKindCharge,
+		Amount:         amount,
+		Status:         models.PaymentStatusSucceeded,
+		IdempotencyKey: key,
+		Reference:      reference,
+		Attempts:       1,
+	}
+	// The payment is returned even if recording it fails so that it can be voided
+	if err := tx.Create(payment).Error; err != nil {
+		return payment, err
+	}
+	return payment, recordPayment(tx, payment)
+}
+
+// voidCharge refunds a charge whose transaction failed to commit
+func (s *BookingService) voidCharge(ctx context.Context, payment *models.Payment) {
+	if payment == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if _, err := s.payments.Refund(ctx, payment.IdempotencyKey+"-void", payment.Reference, payment.Amount); err != nil {
+		metrics.Payments.WithLabelValues("refund", "failed").Inc()
+		slog.ErrorContext(ctx, "failed to void charge of rolled back booking", "key", payment.IdempotencyKey, "amount", payment.Amount, "error", err)
+		return
+	}
+	metrics.Payments.WithLabelValues("refund", "succeeded").Inc()
+}
+
+// netPaid returns what the customer has paid for a booking, including credit
+// transferred from exchanged bookings, and not yet been refunded
+func netPaid(tx *gorm.DB, bookingID uint) (float64, error) {
+	var paid float64
+	err := tx.Model(&models.Payment{}).
+		Select("COALESCE(SUM(CASE WHEN kind IN ? THEN amount ELSE -amount END), 0)",
+			[]string{models.PaymentKindCharge, models.PaymentKindTransferIn}).
+		Where("booking_id = ? AND (status = ? OR kind = ?)",
+			bookingID, models.PaymentStatusSucceeded, models.PaymentKindRefund).
+		Scan(&paid).Error
+	return math.Round(paid*100) / 100, err
+}
+
+// transferCredit moves credit paid for one booking to the booking replacing it
+func transferCredit(tx *gorm.DB, fromID, toID uint, amount float64) error {
+	return tx.Create([]*models.Payment{
+		{
+			BookingID:      fromID,
+			Kind:           models.PaymentKindTransferOut,
+			Amount:         amount,
+			Status:         models.PaymentStatusSucceeded,
+			IdempotencyKey: fmt.Sprintf("booking-%d-transfer-out", fromID),
+			Reference:      fmt.Sprintf("booking-%d", toID),
+		},
+		{
+			BookingID:      toID,
+			Kind:           models.PaymentKindTransferIn,
+			Amount:         amount,
+			Status:         models.PaymentStatusSucceeded,
+			IdempotencyKey: fmt.Sprintf("booking-%d-transfer-in", toID),
+			Reference:      fmt.Sprintf("booking-%d", fromID),
+		},
+	}).Error
+}
+
+// queueRefund records a refund within tx. It is paid out by settleRefund after
+// tx commits, or by the recovery worker if that fails.
+func queueRefund(tx *gorm.DB, bookingID uint, amount float64) (*models.Payment, error) {
+	if amount <= 0 {
+		return nil, nil
+	}
+	key, err := nextRefundKey(tx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	refund := &models.Payment{
+		BookingID:      bookingID,
+		Kind:           models.PaymentKindRefund,
+		Amount:         amount,
+		Status:         models.PaymentStatusPending,
+		IdempotencyKey: key,
+	}
+	if err := tx.Create(refund).Error; err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// settleRefund pays out a pending refund. Failures are recorded and retried later.
+func (s *BookingService) settleRefund(ctx context.Context, refund *models.Payment) {
+	if refund == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked models.Payment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.PaymentStatusPending).
+			First(&locked, refund.ID).Error
+		if err == gorm.ErrRecordNotFound {
+			// Already settled or being settled elsewhere
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.payRefund(ctx, tx, &locked)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to settle refund", "payment_id", refund.ID, "error", err)
+	}
+}
+
+// retryPendingRefunds pays out refunds that failed or were interrupted
+func (s *BookingService) retryPendingRefunds(ctx context.Context) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pending []models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("kind = ? AND status = ?", models.PaymentKindRefund, models.PaymentStatusPending).
+			Order("id").Limit(50).Find(&pending).Error; err != nil {
+			return err
+		}
+		for i := range pending {
+			if err := s.payRefund(ctx, tx, &pending[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BookingService) payRefund(ctx context.Context, tx *gorm.DB, refund *models.Payment) error {
+	chargeReference, err := chargeReference(tx, refund.BookingID)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"attempts": refund.Attempts + 1}
+	reference, err := s.payments.Refund(ctx, refund.IdempotencyKey, chargeReference, refund.Amount)
+	if err != nil {
+		// Refunds are owed to the customer, so they stay pending until they succeed
+		metrics.Payments.WithLabelValues("refund", "failed").Inc()
+		slog.WarnContext(ctx, "refund failed, will retry", "payment_id", refund.ID, "attempt", refund.Attempts+1, "error", err)
+		updates["error"] = err.Error()
+	} else {
+		metrics.Payments.WithLabelValues("refund", "succeeded").Inc()
+		updates["status"] = models.PaymentStatusSucceeded
+		updates["reference"] = reference
+		updates["error"] = ""
+	}
+	if err := tx.Model(refund).Updates(updates).Error; err != nil {
+		return err
+	}
+	if updates["status"] != models.PaymentStatusSucceeded {
+		return nil
+	}
+	refund.Reference = reference
+	return recordPayment(tx, refund)
+}
+
+// recordPayment records the event of a payment that went through
+func recordPayment(tx *gorm.DB, payment *models.Payment) error {
+	return events.Record(tx, events.PaymentSucceeded{
+		PaymentID: payment.ID,
+		BookingID: payment.BookingID,
+		Kind:      payment.Kind,
+		Amount:    payment.Amount,
+		Reference: payment.Reference,
+	})
+}
+
+// chargeReference returns the gateway reference of the latest charge of a
+// booking, following exchanges back to the bookings it replaced
+func chargeReference(tx *gorm.DB, bookingID uint) (string, error) {
+	for {
+		var charge models.Payment
+		err := tx.Where("booking_id = ? AND kind = ? AND status = ?", bookingID, models.PaymentKindCharge, models.PaymentStatusSucceeded).
+			Order("id DESC").First(&charge).Error
+		if err == nil {
+			return charge.Reference, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return "", err
+		}
+
+		var booking models.Booking
+		if err := tx.Select("exchanged_from_id").First(&booking, bookingID).Error; err != nil {
+			return "", err
+		}
+		if booking.ExchangedFromID == nil {
+			return "", nil
+		}
+		bookingID = *booking.ExchangedFromID
+	}
+}
+
+func chargeKey(bookingID uint) string {
+	return fmt.Sprintf("booking-%d-charge", bookingID)
+}
+
+// nextRefundKey returns a unique idempotency key for another refund of a booking.
+// The booking must be locked so that concurrent refunds don't get the same key.
+func nextRefundKey(tx *gorm.DB, bookingID uint) (string, error) {
+	var count int64
+	if err := tx.Model(&models.Payment{}).
+		Where("booking_id = ? AND kind = ?", bookingID, models.PaymentKindRefund).
+		Count(&count).Error; err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("booking-%d-refund-%d", bookingID, count+1), nil
+}
@@ -2,31 +2,123 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/metrics"
 	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"movie-ticket-booking/internal/payment"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingService struct {
-	db          *gorm.DB
-	redisClient *redis.Client
+	db            *gorm.DB
+	redisClient   *redis.Client
+	seatLockTTL   time.Duration
+	payments      payment.Gateway
+	notifications *NotificationService
+	reminders     *ReminderService
+	receipts      *ReceiptService
+	policy        CancellationPolicy
+	exchangeURL   string
+	// Reject selections leaving single empty seats in halls that don't allow them
+	preventOrphanSeats bool
+	// Unsold wheelchair and companion seats go on general sale this long before the show
+	accessibleReleaseBefore time.Duration
+	// How long seats offered to a waitlisted customer are held for them
+	waitlistHoldTTL time.Duration
+
+	// In-flight booking operations, drained on shutdown
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+	abortCtx context.Context
+	abort    context.CancelFunc
 }
 
-func NewBookingService(db *gorm.DB, redisClient *redis.Client) *BookingService {
+func NewBookingService(db *gorm.DB, redisClient *redis.Client, seatLockTTL time.Duration, payments payment.Gateway, notifications *NotificationService, reminders *ReminderService, receipts *ReceiptService, policy CancellationPolicy, exchangeURL string, preventOrphanSeats bool, accessibleReleaseBefore, waitlistHoldTTL time.Duration) *BookingService {
+	abortCtx, abort := context.WithCancel(context.Background())
 	return &BookingService{
-		db:          db,
-		redisClient: redisClient,
+		db:                      db,
+		redisClient:             redisClient,
+		seatLockTTL:             seatLockTTL,
+		payments:                payments,
+		notifications:           notifications,
+		reminders:               reminders,
+		receipts:                receipts,
+		policy:                  policy,
+		exchangeURL:             exchangeURL,
+		preventOrphanSeats:      preventOrphanSeats,
+		abortCtx:                abortCtx,
+		abort:                   abort,
+		accessibleReleaseBefore: accessibleReleaseBefore,
+		waitlistHoldTTL:         waitlistHoldTTL,
 	}
 }
 
-// CreateBooking creates a new booking with seat locking
-func (s *BookingService) CreateBooking(ctx context.Context, userID uint, showtimeID uint, seatIDs []uint) (*models.Booking, error) {
+// Drain stops accepting new booking operations and waits for in-flight ones.
+// Operations still running when ctx expires are aborted and rolled back.
+func (s *BookingService) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.abort()
+		<-done
+		return fmt.Errorf("aborted in-flight bookings: %w", ctx.Err())
+	}
+}
+
+// track registers an in-flight booking operation. The returned context is
+// cancelled when the operation has to be aborted during shutdown.
+func (s *BookingService) track(ctx context.Context) (context.Context, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.draining {
+		return nil, nil, apperrors.New(apperrors.CodeUnavailable, "service is shutting down, please retry")
+	}
+	s.inflight.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.abortCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		s.inflight.Done()
+	}, nil
+}
+
+// CreateBooking creates a new booking with seat locking. Wheelchair spaces and
+// companion seats can only be booked by customers declaring accessibility needs.
+func (s *BookingService) CreateBooking(ctx context.Context, userID uint, showtimeID uint, seatIDs []uint, accessible bool) (*models.Booking, error) {
+	ctx, done, err := s.track(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	// Start a database transaction
-	tx := s.db.Begin()
+	tx := s.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -36,92 +128,174 @@ func (s *BookingService) CreateBooking(ctx context.Context, userID uint, showtim
 		}
 	}()
 
-	// Get showtime details
-	var showtime models.ShowTime
-	if err := tx.First(&showtime, showtimeID).Error; err != nil {
+	showtime, seats, err := s.reserveSeats(ctx, tx, userID, showtimeID, seatIDs, accessible)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Calculate total amount
+	totalAmount := float64(len(seatIDs)) * showtime.Price
+
+	// Create booking
+	booking := &models.Booking{
+		UserID:      userID,
+		ShowTimeID:  showtimeID,
+		TotalAmount: totalAmount,
+		Status:      models.BookingStatusConfirmed,
+		BookedAt:    time.Now(),
+	}
+	if err := bookSeats(tx, booking, showtime, seats); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+	released, err := claimHeldSeats(tx, booking, seats)
+	if err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.reminders.Schedule(tx, booking, showtime); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.receipts.ScheduleConfirmation(tx, booking); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
 		tx.Rollback()
-		return nil, errors.New("showtime not found")
+		return nil, err
+	}
+
+	// Charge the customer
+	charge, err := s.charge(ctx, tx, booking.ID, totalAmount, chargeKey(booking.ID))
+	if err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		s.voidCharge(ctx, charge)
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		s.voidCharge(ctx, charge)
+		return nil, err
+	}
+
+	// Release locks after successful commit
+	s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+	metrics.BookingsCreated.Inc()
+	if released {
+		s.releaseToWaitlist(ctx, showtimeID)
+	}
+
+	return booking, nil
+}
+
+// reserveSeats checks that a showtime can be booked, locks the seats in Redis
+// and verifies within tx that they belong to the showtime and are available.
+// The locks are already released when an error is returned; otherwise the
+// caller releases them once tx has finished.
+func (s *BookingService) reserveSeats(ctx context.Context, tx *gorm.DB, userID uint, showtimeID uint, seatIDs []uint, accessible bool) (*models.ShowTime, []*models.Seat, error) {
+	// Get showtime details; the share lock keeps it from being cancelled until we commit
+	var showtime models.ShowTime
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&showtime, showtimeID).Error; err != nil {
+		return nil, nil, apperrors.NotFound("showtime not found")
+	}
+
+	if showtime.Status == models.ShowTimeStatusCancelled {
+		return nil, nil, apperrors.Conflict("showtime has been cancelled")
 	}
 
 	// Check if showtime has already started
 	if time.Now().After(showtime.StartTime) {
-		tx.Rollback()
-		return nil, errors.New("cannot book seats for a show that has already started")
+		return nil, nil, apperrors.New(apperrors.CodeShowtimeStarted, "cannot book seats for a show that has already started")
+	}
+
+	// Seats held for the customer by a waitlist offer are theirs to book
+	offer, err := activeOffer(tx, userID, showtimeID)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Verify seats exist and are available
 	var seats []*models.Seat
-	for _, seatID := range seatIDs {
+	for i, seatID := range seatIDs {
 		// Check if seat is already locked
 		lockKey := fmt.Sprintf("seat_lock:%d:%d", showtimeID, seatID)
 		existingLock, err := s.redisClient.Get(ctx, lockKey).Result()
 		if err != nil && err != redis.Nil {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, fmt.Errorf("error checking seat lock: %v", err)
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i])
+			return nil, nil, fmt.Errorf("error checking seat lock: %w", err)
 		}
 		if existingLock != "" {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, fmt.Errorf("seat %d is currently being booked by user %s", seatID, existingLock)
+			metrics.SeatLockConflicts.Inc()
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i])
+			return nil, nil, apperrors.New(apperrors.CodeSeatTaken, "seat %d is currently being booked by another customer", seatID)
 		}
 
 		// Try to lock the seat in Redis
-		locked, err := s.redisClient.SetNX(ctx, lockKey, userID, 5*time.Minute).Result()
+		locked, err := s.redisClient.SetNX(ctx, lockKey, userID, s.seatLockTTL).Result()
+		if err == nil && !locked {
+			metrics.SeatLockConflicts.Inc()
+		}
 		if err != nil || !locked {
-			// Release any locks we've acquired
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, fmt.Errorf("failed to lock seat %d: %v", seatID, err)
+			// Release only the locks we've acquired, the others may belong to other customers
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i])
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to lock seat %d: %w", seatID, err)
+			}
+			return nil, nil, apperrors.New(apperrors.CodeSeatTaken, "seat %d is currently being booked by another customer", seatID)
 		}
+		metrics.SeatLockAcquisitions.Inc()
 
 		var seat models.Seat
 		if err := tx.First(&seat, seatID).Error; err != nil {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, fmt.Errorf("seat %d not found: %v", seatID, err)
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i+1])
+			if err == gorm.ErrRecordNotFound {
+				return nil, nil, apperrors.NotFound("seat %d not found", seatID)
+			}
+			return nil, nil, err
 		}
 
 		// Verify seat belongs to the correct showtime and is available
 		if seat.ShowTimeID != showtimeID {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, fmt.Errorf("seat %d belongs to showtime %d, not %d", seatID, seat.ShowTimeID, showtimeID)
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i+1])
+			return nil, nil, apperrors.Validation("seat %d does not belong to showtime %d", seatID, showtimeID)
 		}
-		if seat.Status != models.SeatStatusAvailable {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, fmt.Errorf("seat %d is not available (current status: %s)", seatID, seat.Status)
+		held := offer != nil && seat.HeldForID != nil && *seat.HeldForID == offer.ID
+		if seat.Status != models.SeatStatusAvailable && !held {
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i+1])
+			return nil, nil, apperrors.New(apperrors.CodeSeatTaken, "seat %d is not available", seatID)
 		}
 
 		seats = append(seats, &seat)
 	}
 
-	// Calculate total amount
-	totalAmount := float64(len(seatIDs)) * showtime.Price
-
-	// Create booking
-	booking := &models.Booking{
-		UserID:      userID,
-		ShowTimeID:  showtimeID,
-		TotalAmount: totalAmount,
-		Status:      models.BookingStatusConfirmed,
-		BookedAt:    time.Now(),
+	if err := s.checkAccessibleSeats(&showtime, seats, accessible); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		return nil, nil, err
+	}
+	if err := s.checkOrphanSeats(ctx, tx, &showtime, seatIDs, accessible); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		return nil, nil, err
 	}
 
+	return &showtime, seats, nil
+}
+
+// bookSeats creates a booking for reserved seats and marks them booked
+func bookSeats(tx *gorm.DB, booking *models.Booking, showtime *models.ShowTime, seats []*models.Seat) error {
 	if err := tx.Create(booking).Error; err != nil {
-		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-		tx.Rollback()
-		return nil, err
+		return err
 	}
 
 	// Update seat status and create booking seats
 	for _, seat := range seats {
 		seat.Status = models.SeatStatusBooked
 		if err := tx.Save(seat).Error; err != nil {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, err
+			return err
 		}
 
 		// Create booking seats relationship
@@ -131,22 +305,26 @@ func (s *BookingService) CreateBooking(ctx context.Context, userID uint, showtim
 			Price:     showtime.Price,
 		}
 		if err := tx.Create(bookingSeat).Error; err != nil {
-			s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-			tx.Rollback()
-			return nil, err
+			return err
 		}
+		booking.Seats = append(booking.Seats, *bookingSeat)
 	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-		return nil, err
+	if err := issueTickets(tx, booking); err != nil {
+		return err
 	}
 
-	// Release locks after successful commit
-	s.releaseSeatLocks(ctx, showtimeID, seatIDs)
-
-	return booking, nil
+	seatIDs := make([]uint, len(seats))
+	for i, seat := range seats {
+		seatIDs[i] = seat.ID
+	}
+	return events.Record(tx, events.BookingCreated{
+		BookingID:       booking.ID,
+		UserID:          booking.UserID,
+		ShowtimeID:      booking.ShowTimeID,
+		SeatIDs:         seatIDs,
+		TotalAmount:     booking.TotalAmount,
+		ExchangedFromID: booking.ExchangedFromID,
+	})
 }
 
 // GetBooking retrieves a booking by ID
@@ -154,7 +332,7 @@ func (s *BookingService) GetBooking(id uint) (*models.Booking, error) {
 	var booking models.Booking
 	if err := s.db.Preload("User").Preload("Showtime").Preload("Seats").First(&booking, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, errors.New("booking not found")
+			return nil, apperrors.NotFound("booking not found")
 		}
 		return nil, err
 	}
@@ -170,10 +348,16 @@ func (s *BookingService) GetUserBookings(userID uint) ([]*models.Booking, error)
 	return bookings, nil
 }
 
-// CancelBooking cancels a booking and releases the seats
+// CancelBooking cancels a booking, releases the seats and refunds the customer
 func (s *BookingService) CancelBooking(ctx context.Context, bookingID uint, userID uint) error {
+	ctx, done, err := s.track(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	// Start transaction
-	tx := s.db.Begin()
+	tx := s.db.WithContext(ctx).Begin()
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -185,45 +369,544 @@ func (s *BookingService) CancelBooking(ctx context.Context, bookingID uint, user
 
 	// Get booking with seats
 	var booking models.Booking
-	if err := tx.Preload("Seats.Seat").First(&booking, bookingID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Showtime").Preload("Seats.Seat").First(&booking, bookingID).Error; err != nil {
 		tx.Rollback()
-		return errors.New("booking not found")
+		return apperrors.NotFound("booking not found")
 	}
 
 	// Verify booking belongs to user
 	if booking.UserID != userID {
 		tx.Rollback()
-		return errors.New("unauthorized to cancel this booking")
+		return apperrors.Forbidden("not allowed to cancel this booking")
 	}
 
 	// Check if booking can be cancelled (e.g., not already cancelled)
 	if booking.Status == models.BookingStatusCancelled {
 		tx.Rollback()
-		return errors.New("booking is already cancelled")
+		return apperrors.Conflict("booking is already cancelled")
+	}
+	if booking.Status == models.BookingStatusExchanged {
+		tx.Rollback()
+		return apperrors.Conflict("booking has been exchanged")
 	}
 
-	// Update booking status
-	booking.Status = models.BookingStatusCancelled
-	if err := tx.Save(&booking).Error; err != nil {
+	refund, err := s.cancel(tx, &booking, models.CancelReasonCustomer, false)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var refunded float64
+	if refund != nil {
+		refunded = refund.Amount
+	}
+	if err := s.receipts.ScheduleCancellation(tx, &booking, refunded); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Release seats
-	for _, bookingSeat := range booking.Seats {
-		bookingSeat.Seat.Status = models.SeatStatusAvailable
-		if err := tx.Save(&bookingSeat.Seat).Error; err != nil {
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	metrics.BookingsCancelled.Inc()
+	s.settleRefund(ctx, refund)
+	s.releaseToWaitlist(ctx, booking.ShowTimeID)
+
+	return nil
+}
+
+// CancelBookingSeats cancels some seats of a booking. The seats are released,
+// the total is recalculated from the remaining seats, the cancelled seats are
+// refunded under the cancellation policy and the remaining tickets are
+// reissued. Cancelling every seat cancels the whole booking.
+func (s *BookingService) CancelBookingSeats(ctx context.Context, bookingID uint, userID uint, seatIDs []uint) (*models.Booking, error) {
+	if len(seatIDs) == 0 {
+		return nil, apperrors.Validation("at least one seat is required")
+	}
+
+	ctx, done, err := s.track(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var booking models.Booking
+	var refund *models.Payment
+	var cancelledAll bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Showtime").Preload("Seats").
+			First(&booking, bookingID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return apperrors.NotFound("booking not found")
+			}
+			return err
+		}
+		if booking.UserID != userID {
+			return apperrors.Forbidden("not allowed to cancel this booking")
+		}
+		if booking.Status != models.BookingStatusConfirmed {
+			return apperrors.Conflict("booking is no longer active")
+		}
+		if !booking.Showtime.StartTime.After(time.Now()) {
+			return apperrors.New(apperrors.CodeShowtimeStarted, "cannot cancel seats for a show that has already started")
+		}
+
+		cancelled, remaining, err := splitSeats(booking.Seats, seatIDs)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			cancelledAll = true
+			if refund, err = s.cancel(tx, &booking, models.CancelReasonCustomer, false); err != nil {
+				return err
+			}
+			var refunded float64
+			if refund != nil {
+				refunded = refund.Amount
+			}
+			return s.receipts.ScheduleCancellation(tx, &booking, refunded)
+		}
+
+		// Release the cancelled seats; their booking rows are kept for the record
+		booking.Seats = cancelled
+		if err := releaseBookingSeats(tx, &booking); err != nil {
+			return err
+		}
+		if err := tx.Delete(&cancelled).Error; err != nil {
+			return err
+		}
+		booking.Seats = remaining
+
+		// Bookings carry no promotions or minimum-seat discounts: every seat
+		// keeps the price it was booked at, so the total is simply the sum of
+		// the remaining seats
+		var total float64
+		for _, bookingSeat := range remaining {
+			total += bookingSeat.Price
+		}
+		total = math.Round(total*100) / 100
+		released := booking.TotalAmount - total
+		booking.TotalAmount = total
+		if err := tx.Model(&booking).Update("total_amount", total).Error; err != nil {
+			return err
+		}
+		if err := reissueTickets(tx, &booking); err != nil {
+			return err
+		}
+
+		// Never refund more than was actually paid for the booking
+		paid, err := netPaid(tx, booking.ID)
+		if err != nil {
+			return err
+		}
+		amount := math.Min(s.policy.Refund(released, booking.Showtime.StartTime, time.Now()), paid)
+		refund, err = queueRefund(tx, booking.ID, amount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cancelledAll {
+		metrics.BookingsCancelled.Inc()
+	} else {
+		metrics.BookingSeatsCancelled.Add(float64(len(seatIDs)))
+	}
+	s.settleRefund(ctx, refund)
+	s.releaseToWaitlist(ctx, booking.ShowTimeID)
+
+	return &booking, nil
+}
+
+// splitSeats separates the booked seats to cancel from the ones to keep
+func splitSeats(seats []models.BookingSeat, cancelIDs []uint) (cancelled, remaining []models.BookingSeat, err error) {
+	cancel := make(map[uint]bool, len(cancelIDs))
+	for _, seatID := range cancelIDs {
+		if cancel[seatID] {
+			return nil, nil, apperrors.Validation("seat %d is listed twice", seatID)
+		}
+		cancel[seatID] = true
+	}
+	for _, bookingSeat := range seats {
+		if cancel[bookingSeat.SeatID] {
+			cancelled = append(cancelled, bookingSeat)
+			delete(cancel, bookingSeat.SeatID)
+		} else {
+			remaining = append(remaining, bookingSeat)
+		}
+	}
+	for seatID := range cancel {
+		return nil, nil, apperrors.Validation("seat %d is not part of this booking", seatID)
+	}
+	return cancelled, remaining, nil
+}
+
+// ExchangeBooking replaces a booking with one for other seats, possibly of
+// another showtime, in a single transaction. What was paid for the old booking
+// is credited to the new one and the customer is charged or refunded the
+// difference. Bookings cancelled because their showtime was cancelled can be
+// exchanged as well; they have been refunded, so the new booking is charged in full.
+func (s *BookingService) ExchangeBooking(ctx context.Context, bookingID uint, userID uint, showtimeID uint, seatIDs []uint) (*models.Booking, error) {
+	ctx, done, err := s.track(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	tx := s.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	old, err := lockExchangeable(tx, bookingID, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Customers who booked accessible seats keep access to them
+	accessible := false
+	for _, bookingSeat := range old.Seats {
+		accessible = accessible || bookingSeat.Seat.Kind != models.SeatKindStandard
+	}
+
+	// Free the old seats first so that they can be picked again
+	var credit float64
+	if old.Status == models.BookingStatusConfirmed {
+		if credit, err = netPaid(tx, old.ID); err != nil {
 			tx.Rollback()
+			return nil, err
+		}
+		if err := releaseBookingSeats(tx, old); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := cancelTickets(tx, old.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Model(old).Update("status", models.BookingStatusExchanged).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	showtime, seats, err := s.reserveSeats(ctx, tx, userID, showtimeID, seatIDs, accessible)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	totalAmount := float64(len(seatIDs)) * showtime.Price
+	booking := &models.Booking{
+		UserID:          userID,
+		ShowTimeID:      showtimeID,
+		TotalAmount:     totalAmount,
+		Status:          models.BookingStatusConfirmed,
+		BookedAt:        time.Now(),
+		ExchangedFromID: &old.ID,
+	}
+	if err := bookSeats(tx, booking, showtime, seats); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := claimHeldSeats(tx, booking, seats); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.reminders.Schedule(tx, booking, showtime); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.receipts.ScheduleConfirmation(tx, booking); err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Settle the price difference
+	var charge, refund *models.Payment
+	if credit > 0 {
+		err = transferCredit(tx, old.ID, booking.ID, credit)
+	}
+	if difference := math.Round((totalAmount-credit)*100) / 100; err == nil && difference > 0 {
+		charge, err = s.charge(ctx, tx, booking.ID, difference, chargeKey(booking.ID))
+	} else if err == nil && difference < 0 {
+		refund, err = queueRefund(tx, booking.ID, -difference)
+	}
+	if err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		tx.Rollback()
+		s.voidCharge(ctx, charge)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+		s.voidCharge(ctx, charge)
+		return nil, err
+	}
+	s.releaseSeatLocks(ctx, showtimeID, seatIDs)
+	metrics.BookingsExchanged.Inc()
+	s.settleRefund(ctx, refund)
+	// The old seats and any held seats that weren't booked are free again
+	s.releaseToWaitlist(ctx, old.ShowTimeID)
+	if showtimeID != old.ShowTimeID {
+		s.releaseToWaitlist(ctx, showtimeID)
+	}
+
+	return booking, nil
+}
+
+// lockExchangeable loads a booking of the user for update and checks that it can be exchanged
+func lockExchangeable(tx *gorm.DB, bookingID, userID uint) (*models.Booking, error) {
+	var booking models.Booking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Showtime").Preload("Seats.Seat").
+		First(&booking, bookingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("booking not found")
+		}
+		return nil, err
+	}
+	if booking.UserID != userID {
+		return nil, apperrors.Forbidden("not allowed to exchange this booking")
+	}
+
+	switch {
+	case booking.Status == models.BookingStatusConfirmed:
+		if !booking.Showtime.StartTime.After(time.Now()) {
+			return nil, apperrors.New(apperrors.CodeShowtimeStarted, "cannot exchange a booking for a show that has already started")
+		}
+	case booking.Status == models.BookingStatusCancelled && booking.CancelReason == models.CancelReasonShowtimeCancelled:
+		var exchanged int64
+		if err := tx.Model(&models.Booking{}).Where("exchanged_from_id = ?", booking.ID).Count(&exchanged).Error; err != nil {
+			return nil, err
+		}
+		if exchanged > 0 {
+			return nil, apperrors.Conflict("booking has already been exchanged")
+		}
+	case booking.Status == models.BookingStatusExchanged:
+		return nil, apperrors.Conflict("booking has already been exchanged")
+	default:
+		return nil, apperrors.Conflict("booking is cancelled and can't be exchanged")
+	}
+	return &booking, nil
+}
+
+// cancel marks a booking loaded with its showtime and seats cancelled, releases
+// the seats and voids the tickets. Everything paid for the booking is refunded
+// if fullRefund is set, otherwise what the cancellation policy allows.
+func (s *BookingService) cancel(tx *gorm.DB, booking *models.Booking, reason string, fullRefund bool) (*models.Payment, error) {
+	now := time.Now()
+	booking.Status = models.BookingStatusCancelled
+	booking.CancelReason = reason
+	booking.CancelledAt = &now
+	if err := tx.Model(booking).Updates(map[string]interface{}{
+		"status":        booking.Status,
+		"cancel_reason": booking.CancelReason,
+		"cancelled_at":  booking.CancelledAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := releaseBookingSeats(tx, booking); err != nil {
+		return nil, err
+	}
+	if err := cancelTickets(tx, booking.ID); err != nil {
+		return nil, err
+	}
+	if err := events.Record(tx, events.BookingCancelled{
+		BookingID:  booking.ID,
+		UserID:     booking.UserID,
+		ShowtimeID: booking.ShowTimeID,
+		Reason:     reason,
+	}); err != nil {
+		return nil, err
+	}
+
+	paid, err := netPaid(tx, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !fullRefund {
+		paid = s.policy.Refund(paid, booking.Showtime.StartTime, now)
+	}
+	return queueRefund(tx, booking.ID, paid)
+}
+
+// releaseBookingSeats makes the seats of a booking loaded with its seats available again
+func releaseBookingSeats(tx *gorm.DB, booking *models.Booking) error {
+	seatIDs := make([]uint, len(booking.Seats))
+	for i, bookingSeat := range booking.Seats {
+		seatIDs[i] = bookingSeat.SeatID
+	}
+	if len(seatIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.Seat{}).Where("id IN ?", seatIDs).Update("status", models.SeatStatusAvailable).Error; err != nil {
+		return err
+	}
+	return events.Record(tx, events.SeatsReleased{ShowtimeID: booking.ShowTimeID, SeatIDs: seatIDs})
+}
+
+// CancelShowtimeBookings cancels and refunds every confirmed booking of a
+// cancelled showtime and offers the customers an exchange. Each booking is
+// handled in its own transaction, so the work can be resumed by running it
+// again after a crash.
+func (s *BookingService) CancelShowtimeBookings(ctx context.Context, showtimeID uint) error {
+	var showtime models.ShowTime
+	if err := s.db.WithContext(ctx).Preload("Movie").Preload("Hall").First(&showtime, showtimeID).Error; err != nil {
+		return err
+	}
+	if showtime.Status != models.ShowTimeStatusCancelled {
+		return fmt.Errorf("showtime %d is not cancelled", showtimeID)
+	}
+
+	var bookingIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Booking{}).
+		Where("show_time_id = ? AND status = ?", showtimeID, models.BookingStatusConfirmed).
+		Order("id").Pluck("id", &bookingIDs).Error; err != nil {
+		return err
+	}
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+
+	alternatives, err := s.alternativeShowtimes(ctx, &showtime)
+	if err != nil {
+		return err
+	}
+	for _, bookingID := range bookingIDs {
+		if err := s.cancelForShowtime(ctx, &showtime, bookingID, alternatives); err != nil {
+			return fmt.Errorf("failed to cancel booking %d: %w", bookingID, err)
+		}
+	}
+	slog.InfoContext(ctx, "cancelled bookings of cancelled showtime", "showtime_id", showtimeID, "bookings", len(bookingIDs))
+	return nil
+}
+
+func (s *BookingService) cancelForShowtime(ctx context.Context, showtime *models.ShowTime, bookingID uint, alternatives []*models.ShowTime) error {
+	ctx, done, err := s.track(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	var refund *models.Payment
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").Preload("Seats").
+			First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		// Cancelled by the customer or an earlier run in the meantime
+		if booking.Status != models.BookingStatusConfirmed {
+			return nil
+		}
+
+		if refund, err = s.cancel(tx, &booking, models.CancelReasonShowtimeCancelled, true); err != nil {
 			return err
 		}
+		var amount float64
+		if refund != nil {
+			amount = refund.Amount
+		}
+		return s.notifications.Enqueue(tx, &booking.UserID, s.showtimeCancelledMessage(showtime, &booking, amount, alternatives))
+	})
+	if err != nil {
+		return err
 	}
+	metrics.BookingsCancelled.Inc()
+	s.settleRefund(ctx, refund)
+	return nil
+}
 
-	// Commit transaction
-	return tx.Commit().Error
+// alternativeShowtimes returns upcoming shows of the same movie with free seats
+func (s *BookingService) alternativeShowtimes(ctx context.Context, showtime *models.ShowTime) ([]*models.ShowTime, error) {
+	var alternatives []*models.ShowTime
+	err := s.db.WithContext(ctx).Preload("Hall").
+		Where("movie_id = ? AND status = ? AND start_time > ? AND id <> ?",
+			showtime.MovieID, models.ShowTimeStatusScheduled, time.Now(), showtime.ID).
+		Where("EXISTS (SELECT 1 FROM seats WHERE seats.show_time_id = show_times.id AND seats.status = ? AND seats.deleted_at IS NULL)",
+			models.SeatStatusAvailable).
+		Order("start_time").Limit(5).Find(&alternatives).Error
+	return alternatives, err
+}
+
+func (s *BookingService) showtimeCancelledMessage(showtime *models.ShowTime, booking *models.Booking, refunded float64, alternatives []*models.ShowTime) notification.Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nWe're sorry, the showing of %s on %s in %s has been cancelled: %s\n\n",
+		booking.User.Name, showtime.Movie.Title, showtime.StartTime.Format("Mon 2 Jan 15:04"), showtime.Hall.Name, showtime.CancellationReason)
+	if refunded > 0 {
+		fmt.Fprintf(&body, "Your booking #%d has been cancelled and %.2f will be refunded to your original payment method.\n", booking.ID, refunded)
+	} else {
+		fmt.Fprintf(&body, "Your booking #%d has been cancelled.\n", booking.ID)
+	}
+	if len(alternatives) > 0 {
+		body.WriteString("\nWould you rather see another showing? Exchange your booking with one click:\n")
+		for _, alternative := range alternatives {
+			fmt.Fprintf(&body, "- %s in %s: %s?booking=%d&showtime=%d\n",
+				alternative.StartTime.Format("Mon 2 Jan 15:04"), alternative.Hall.Name, s.exchangeURL, booking.ID, alternative.ID)
+		}
+	}
+	return notification.Message{
+		To:      booking.User.Email,
+		Subject: fmt.Sprintf("Cancelled: %s on %s", showtime.Movie.Title, showtime.StartTime.Format("2 Jan 15:04")),
+		Body:    body.String(),
+	}
+}
+
+// RunRecovery finishes interrupted work every interval until ctx is
+// cancelled: bookings of cancelled showtimes that weren't cancelled yet,
+// refunds that haven't been paid out and waitlist offers that ran out. beat is
+// called after every round.
+func (s *BookingService) RunRecovery(ctx context.Context, interval time.Duration, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.recover(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("booking recovery failed", "error", err)
+		}
+		beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BookingService) recover(ctx context.Context) error {
+	var showtimeIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Booking{}).
+		Joins("JOIN show_times ON show_times.id = bookings.show_time_id").
+		Where("show_times.status = ? AND bookings.status = ?", models.ShowTimeStatusCancelled, models.BookingStatusConfirmed).
+		Distinct().Pluck("bookings.show_time_id", &showtimeIDs).Error; err != nil {
+		return err
+	}
+	for _, showtimeID := range showtimeIDs {
+		if err := s.CancelShowtimeBookings(ctx, showtimeID); err != nil {
+			return err
+		}
+	}
+	if err := s.retryPendingRefunds(ctx); err != nil {
+		return err
+	}
+	return s.expireWaitlist(ctx)
 }
 
 // Helper function to release seat locks in Redis
 func (s *BookingService) releaseSeatLocks(ctx context.Context, showtimeID uint, seatIDs []uint) {
+	// Locks must be released even when the booking was aborted
+	ctx = context.WithoutCancel(ctx)
 	for _, seatID := range seatIDs {
 		lockKey := fmt.Sprintf("seat_lock:%d:%d", showtimeID, seatID)
 		s.redisClient.Del(ctx, lockKey)
@@ -0,0 +1,511 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/database/redistest"
+	"movie-ticket-booking/internal/jobs"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// testPolicy refunds in full up to two days before the show and half up to two hours before
+var testPolicy = CancellationPolicy{
+	FullRefundBefore:     48 * time.Hour,
+	PartialRefundBefore:  2 * time.Hour,
+	PartialRefundPercent: 50,
+}
+
+// testGateway is a payment gateway recording the charges and refunds that went through
+type testGateway struct {
+	mu      sync.Mutex
+	charges []gatewayCall
+	refunds []gatewayCall
+	// onRefund is called with the number of every refund attempt, counting
+	// from 1; the refund fails if it returns an error
+	onRefund func(attempt int) error
+	attempts int
+}
+
+type gatewayCall struct {
+	key             string
+	chargeReference string
+	amount          float64
+}
+
+func (g *testGateway) Charge(ctx context.Context, idempotencyKey string, amount float64) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.charges = append(g.charges, gatewayCall{key: idempotencyKey, amount: amount})
+	return "charge-" + idempotencyKey, nil
+}
+
+func (g *testGateway) Refund(ctx context.Context, idempotencyKey, chargeReference string, amount float64) (string, error) {
+	g.mu.Lock()
+	g.attempts++
+	attempt, onRefund := g.attempts, g.onRefund
+	g.mu.Unlock()
+	if onRefund != nil {
+		if err := onRefund(attempt); err != nil {
+			return "", err
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refunds = append(g.refunds, gatewayCall{key: idempotencyKey, chargeReference: chargeReference, amount: amount})
+	return "refund-" + idempotencyKey, nil
+}
+
+func (g *testGateway) setOnRefund(onRefund func(attempt int) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onRefund = onRefund
+}
+
+func (g *testGateway) charged() []gatewayCall {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]gatewayCall(nil), g.charges...)
+}
+
+func (g *testGateway) refunded() []gatewayCall {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]gatewayCall(nil), g.refunds...)
+}
+
+var errGatewayUnavailable = errors.New("gateway unavailable")
+
+func newTestBookingService(t *testing.T, db *gorm.DB, redisClient *redis.Client, gateway *testGateway) *BookingService {
+	t.Helper()
+	scheduler := jobs.NewScheduler(db, 3, time.Minute)
+	notifications := NewNotificationService(db, discardSender{})
+	reminders := NewReminderService(scheduler, notifications, 2*time.Hour, time.Hour, "https://example.com/rate")
+	renderer, err := notification.NewRenderer("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts := NewReceiptService(scheduler, notifications, renderer)
+	return NewBookingService(db, redisClient, time.Minute, gateway, notifications, reminders, receipts, testPolicy,
+		"https://example.com/exchange", false, 0, 15*time.Minute)
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, name string) *models.User {
+	t.Helper()
+	user := &models.User{Email: name + "@example.com", Name: name}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createTestMovie(t *testing.T, db *gorm.DB) *models.Movie {
+	t.Helper()
+	movie := &models.Movie{Title: "Metropolis", Duration: 150, Genre: "Science fiction", ReleaseDate: time.Date(1927, 1, 10, 0, 0, 0, 0, time.UTC)}
+	if err := db.Create(movie).Error; err != nil {
+		t.Fatal(err)
+	}
+	return movie
+}
+
+// createTestShowtime schedules a movie in a hall of its own, built from one
+// string per row, front row A first: '.' is a standard seat, 'W' a wheelchair
+// space, 'C' a companion seat and ' ' an aisle
+func createTestShowtime(t *testing.T, db *gorm.DB, movie *models.Movie, start time.Time, price float64, rows ...string) *models.ShowTime {
+	t.Helper()
+	hall := &models.Hall{Name: fmt.Sprintf("Hall %d", time.Now().UnixNano())}
+	for r, line := range rows {
+		for i, c := range line {
+			kind := models.SeatKindStandard
+			switch c {
+			case ' ':
+				continue
+			case 'W':
+				kind = models.SeatKindWheelchair
+			case 'C':
+				kind = models.SeatKindCompanion
+			}
+			hall.Layout = append(hall.Layout, models.HallSeat{RowNumber: string(rune('A' + r)), SeatNumber: i + 1, Kind: kind})
+		}
+	}
+	hall.Capacity = len(hall.Layout)
+	if err := db.Create(hall).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	showtime := &models.ShowTime{
+		MovieID:   movie.ID,
+		HallID:    hall.ID,
+		StartTime: start,
+		EndTime:   start.Add(time.Duration(movie.Duration) * time.Minute),
+		Price:     price,
+		Status:    models.ShowTimeStatusScheduled,
+	}
+	if err := db.Create(showtime).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := createSeats(db, showtime, hall); err != nil {
+		t.Fatal(err)
+	}
+	return showtime
+}
+
+// seatIDs returns the IDs of seats of a showtime given as row and number, e.g. "A3"
+func seatIDs(t *testing.T, db *gorm.DB, showtime *models.ShowTime, positions ...string) []uint {
+	t.Helper()
+	ids := make([]uint, len(positions))
+	for i, position := range positions {
+		number, err := strconv.Atoi(position[1:])
+		if err != nil {
+			t.Fatalf("invalid seat position %q", position)
+		}
+		var seat models.Seat
+		if err := db.Where("show_time_id = ? AND row_number = ? AND seat_number = ?", showtime.ID, position[:1], number).
+			First(&seat).Error; err != nil {
+			t.Fatalf("seat %s: %v", position, err)
+		}
+		ids[i] = seat.ID
+	}
+	return ids
+}
+
+func bookingPayments(t *testing.T, db *gorm.DB, bookingID uint, kind string) []models.Payment {
+	t.Helper()
+	var found []models.Payment
+	if err := db.Where("booking_id = ? AND kind = ?", bookingID, kind).Order("id").Find(&found).Error; err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func reloadBooking(t *testing.T, db *gorm.DB, id uint) *models.Booking {
+	t.Helper()
+	var booking models.Booking
+	if err := db.Preload("Seats").First(&booking, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &booking
+}
+
+func TestExchangeBookingSettlesTheDifference(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      float64 // of the new showtime; the old one costs 10 a seat
+		wantCharge float64
+		wantRefund float64
+	}{
+		{"cheaper showtime", 8, 0, 4},
+		{"same price", 10, 0, 0},
+		{"more expensive showtime", 15, 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := dbtest.New(t)
+			gateway := &testGateway{}
+			bookings := newTestBookingService(t, db, redistest.New(t), gateway)
+
+			movie := createTestMovie(t, db)
+			from := createTestShowtime(t, db, movie, time.Now().Add(72*time.Hour), 10, ".....")
+			to := createTestShowtime(t, db, movie, time.Now().Add(96*time.Hour), tt.price, ".....")
+			user := createTestUser(t, db, "erin")
+
+			old, err := bookings.CreateBooking(ctx, user.ID, from.ID, seatIDs(t, db, from, "A2", "A3"), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			booking, err := bookings.ExchangeBooking(ctx, old.ID, user.ID, to.ID, seatIDs(t, db, to, "A2", "A3"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := reloadBooking(t, db, old.ID).Status; got != models.BookingStatusExchanged {
+				t.Errorf("old booking is %s, want EXCHANGED", got)
+			}
+			var freed int64
+			if err := db.Model(&models.Seat{}).Where("id IN ? AND status = ?", seatIDs(t, db, from, "A2", "A3"), models.SeatStatusAvailable).
+				Count(&freed).Error; err != nil {
+				t.Fatal(err)
+			}
+			if freed != 2 {
+				t.Errorf("%d of the old seats are free again, want 2", freed)
+			}
+
+			// Everything paid for the old booking is credited to the new one
+			out := bookingPayments(t, db, old.ID, models.PaymentKindTransferOut)
+			in := bookingPayments(t, db, booking.ID, models.PaymentKindTransferIn)
+			if len(out) != 1 || out[0].Amount != old.TotalAmount || len(in) != 1 || in[0].Amount != old.TotalAmount {
+				t.Errorf("credit transferred out %+v and in %+v, want %.2f", out, in, old.TotalAmount)
+			}
+
+			charges := bookingPayments(t, db, booking.ID, models.PaymentKindCharge)
+			if tt.wantCharge == 0 && len(charges) != 0 || tt.wantCharge > 0 && (len(charges) != 1 || charges[0].Amount != tt.wantCharge) {
+				t.Errorf("charges %+v, want %.2f", charges, tt.wantCharge)
+			}
+			refunds := bookingPayments(t, db, booking.ID, models.PaymentKindRefund)
+			if tt.wantRefund == 0 && len(refunds) != 0 ||
+				tt.wantRefund > 0 && (len(refunds) != 1 || refunds[0].Amount != tt.wantRefund || refunds[0].Status != models.PaymentStatusSucceeded) {
+				t.Errorf("refunds %+v, want %.2f", refunds, tt.wantRefund)
+			}
+			if tt.wantRefund > 0 {
+				// The difference goes back to the card the old booking was charged to
+				paid := gateway.refunded()
+				if len(paid) != 1 || paid[0].chargeReference != "charge-"+chargeKey(old.ID) {
+					t.Errorf("gateway refunds %+v, want one against the charge of booking %d", paid, old.ID)
+				}
+			}
+
+			paid, err := netPaid(db, booking.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if paid != booking.TotalAmount {
+				t.Errorf("net paid for the new booking %.2f, want its total %.2f", paid, booking.TotalAmount)
+			}
+		})
+	}
+}
+
+func TestExchangeRefundFollowsExchangeChain(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	gateway := &testGateway{}
+	bookings := newTestBookingService(t, db, redistest.New(t), gateway)
+
+	movie := createTestMovie(t, db)
+	first := createTestShowtime(t, db, movie, time.Now().Add(72*time.Hour), 10, "....")
+	second := createTestShowtime(t, db, movie, time.Now().Add(96*time.Hour), 10, "....")
+	third := createTestShowtime(t, db, movie, time.Now().Add(120*time.Hour), 6, "....")
+	user := createTestUser(t, db, "frank")
+
+	original, err := bookings.CreateBooking(ctx, user.ID, first.ID, seatIDs(t, db, first, "A1", "A2"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same price, so the intermediate booking has no charge of its own
+	intermediate, err := bookings.ExchangeBooking(ctx, original.ID, user.ID, second.ID, seatIDs(t, db, second, "A1", "A2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if charges := bookingPayments(t, db, intermediate.ID, models.PaymentKindCharge); len(charges) != 0 {
+		t.Fatalf("intermediate booking charged %+v", charges)
+	}
+	last, err := bookings.ExchangeBooking(ctx, intermediate.ID, user.ID, third.ID, seatIDs(t, db, third, "A1", "A2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "charge-" + chargeKey(original.ID)
+	if got, err := chargeReference(db, last.ID); err != nil || got != want {
+		t.Errorf("charge reference of the last booking %q (%v), want %q", got, err, want)
+	}
+	refunds := gateway.refunded()
+	if len(refunds) != 1 || refunds[0].chargeReference != want || refunds[0].amount != 8 {
+		t.Errorf("gateway refunds %+v, want 8.00 against %q", refunds, want)
+	}
+}
+
+func TestExchangeBookingOfCancelledShowtimeIsChargedInFull(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	gateway := &testGateway{}
+	bookings := newTestBookingService(t, db, redistest.New(t), gateway)
+
+	movie := createTestMovie(t, db)
+	from := createTestShowtime(t, db, movie, time.Now().Add(72*time.Hour), 10, "....")
+	to := createTestShowtime(t, db, movie, time.Now().Add(96*time.Hour), 10, "....")
+	user := createTestUser(t, db, "grace")
+
+	old, err := bookings.CreateBooking(ctx, user.ID, from.ID, seatIDs(t, db, from, "A1"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(from).Update("status", models.ShowTimeStatusCancelled).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := bookings.CancelShowtimeBookings(ctx, from.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	booking, err := bookings.ExchangeBooking(ctx, old.ID, user.ID, to.ID, seatIDs(t, db, to, "A1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old booking was refunded in full, so there is no credit to transfer
+	if got := reloadBooking(t, db, old.ID); got.Status != models.BookingStatusCancelled {
+		t.Errorf("old booking is %s, want it to stay CANCELLED", got.Status)
+	}
+	if out := bookingPayments(t, db, old.ID, models.PaymentKindTransferOut); len(out) != 0 {
+		t.Errorf("credit transferred out of the refunded booking: %+v", out)
+	}
+	if in := bookingPayments(t, db, booking.ID, models.PaymentKindTransferIn); len(in) != 0 {
+		t.Errorf("credit transferred to the new booking: %+v", in)
+	}
+	charges := bookingPayments(t, db, booking.ID, models.PaymentKindCharge)
+	if len(charges) != 1 || charges[0].Amount != booking.TotalAmount {
+		t.Errorf("charges %+v, want the full %.2f", charges, booking.TotalAmount)
+	}
+	if calls := gateway.charged(); len(calls) != 2 || calls[1].key != chargeKey(booking.ID) || calls[1].amount != booking.TotalAmount {
+		t.Errorf("gateway charges %+v, want the full %.2f for booking %d", calls, booking.TotalAmount, booking.ID)
+	}
+
+	// It can only be exchanged once
+	if _, err := bookings.ExchangeBooking(ctx, old.ID, user.ID, to.ID, seatIDs(t, db, to, "A2")); apperrors.CodeOf(err) != apperrors.CodeConflict {
+		t.Errorf("exchanging again: got %v, want a conflict", err)
+	}
+}
+
+func TestCancelBookingSeatsRefundsUnderThePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		startsIn   time.Duration
+		wantRefund float64
+	}{
+		{"full refund window", 72 * time.Hour, 10},
+		{"partial refund window", 24 * time.Hour, 5},
+		{"too late for a refund", time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := dbtest.New(t)
+			bookings := newTestBookingService(t, db, redistest.New(t), &testGateway{})
+
+			showtime := createTestShowtime(t, db, createTestMovie(t, db), time.Now().Add(tt.startsIn), 10, ".....")
+			user := createTestUser(t, db, "heidi")
+			seats := seatIDs(t, db, showtime, "A1", "A2", "A3")
+			booked, err := bookings.CreateBooking(ctx, user.ID, showtime.ID, seats, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			booking, err := bookings.CancelBookingSeats(ctx, booked.ID, user.ID, seats[2:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if booking.Status != models.BookingStatusConfirmed || booking.TotalAmount != 20 {
+				t.Errorf("booking: status %s, total %.2f, want CONFIRMED and 20.00", booking.Status, booking.TotalAmount)
+			}
+			if got := reloadBooking(t, db, booked.ID); got.TotalAmount != 20 || len(got.Seats) != 2 {
+				t.Errorf("stored booking: total %.2f with %d seats, want 20.00 with 2", got.TotalAmount, len(got.Seats))
+			}
+
+			refunds := bookingPayments(t, db, booked.ID, models.PaymentKindRefund)
+			if tt.wantRefund == 0 && len(refunds) != 0 ||
+				tt.wantRefund > 0 && (len(refunds) != 1 || refunds[0].Amount != tt.wantRefund || refunds[0].Status != models.PaymentStatusSucceeded) {
+				t.Errorf("refunds %+v, want %.2f", refunds, tt.wantRefund)
+			}
+
+			var seat models.Seat
+			if err := db.First(&seat, seats[2]).Error; err != nil {
+				t.Fatal(err)
+			}
+			if seat.Status != models.SeatStatusAvailable {
+				t.Errorf("cancelled seat is %s, want AVAILABLE", seat.Status)
+			}
+
+			// Only the remaining seats have valid tickets, and they are new ones
+			var tickets []models.Ticket
+			if err := db.Where("booking_id = ? AND status <> ?", booked.ID, models.TicketStatusCancelled).Order("seat_id").Find(&tickets).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(tickets) != 2 || tickets[0].SeatID != seats[0] || tickets[1].SeatID != seats[1] {
+				t.Fatalf("valid tickets %+v, want seats %v", tickets, seats[:2])
+			}
+			var voided int64
+			if err := db.Model(&models.Ticket{}).Where("booking_id = ? AND status = ?", booked.ID, models.TicketStatusCancelled).
+				Count(&voided).Error; err != nil {
+				t.Fatal(err)
+			}
+			if voided != 3 {
+				t.Errorf("%d tickets voided, want the 3 original ones", voided)
+			}
+		})
+	}
+}
+
+func TestCancelBookingSeatsNeverRefundsMoreThanPaid(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	bookings := newTestBookingService(t, db, redistest.New(t), &testGateway{})
+
+	showtime := createTestShowtime(t, db, createTestMovie(t, db), time.Now().Add(72*time.Hour), 10, "....")
+	user := createTestUser(t, db, "ivan")
+	seats := seatIDs(t, db, showtime, "A1", "A2")
+	booked, err := bookings.CreateBooking(ctx, user.ID, showtime.ID, seats, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Support already gave back 15 of the 20 paid
+	if err := db.Create(&models.Payment{
+		BookingID:      booked.ID,
+		Kind:           models.PaymentKindRefund,
+		Amount:         15,
+		Status:         models.PaymentStatusSucceeded,
+		IdempotencyKey: fmt.Sprintf("booking-%d-goodwill", booked.ID),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bookings.CancelBookingSeats(ctx, booked.ID, user.ID, seats[1:]); err != nil {
+		t.Fatal(err)
+	}
+	refunds := bookingPayments(t, db, booked.ID, models.PaymentKindRefund)
+	if len(refunds) != 2 || refunds[1].Amount != 5 {
+		t.Errorf("refunds %+v, want the seat's 10.00 capped at the 5.00 left", refunds)
+	}
+}
+
+func TestCancelAllBookingSeatsCancelsTheBooking(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	bookings := newTestBookingService(t, db, redistest.New(t), &testGateway{})
+
+	showtime := createTestShowtime(t, db, createTestMovie(t, db), time.Now().Add(72*time.Hour), 10, "....")
+	user := createTestUser(t, db, "judy")
+	seats := seatIDs(t, db, showtime, "A1", "A2")
+	booked, err := bookings.CreateBooking(ctx, user.ID, showtime.ID, seats, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	booking, err := bookings.CancelBookingSeats(ctx, booked.ID, user.ID, seats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.Status != models.BookingStatusCancelled || booking.CancelReason != models.CancelReasonCustomer {
+		t.Errorf("booking: status %s, reason %q, want CANCELLED by the customer", booking.Status, booking.CancelReason)
+	}
+	refunds := bookingPayments(t, db, booked.ID, models.PaymentKindRefund)
+	if len(refunds) != 1 || refunds[0].Amount != 20 {
+		t.Errorf("refunds %+v, want 20.00", refunds)
+	}
+
+	// The customer is emailed about the cancellation and the refund
+	var job models.Job
+	if err := db.Where("kind = ?", JobBookingCancellation).First(&job).Error; err != nil {
+		t.Fatalf("no cancellation email scheduled: %v", err)
+	}
+	var payload cancellationJob
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.BookingID != booked.ID || payload.Refund != 20 {
+		t.Errorf("cancellation email for booking %d with refund %.2f, want %d and 20.00", payload.BookingID, payload.Refund, booked.ID)
+	}
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/documents"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InvoiceSettings are the seller details and numbering of invoices
+type InvoiceSettings struct {
+	Company          documents.Company
+	TaxPercent       float64 // VAT included in ticket prices
+	Currency         string
+	Prefix           string // invoice number prefix, such as INV
+	CreditNotePrefix string // credit note number prefix, such as CN
+}
+
+// DocumentService renders printable tickets and issues VAT invoices for
+// charges and credit notes for refunds. It is a sink of the event relay, so
+// invoices are issued as payments go through; documents that are requested
+// before the relay caught up are issued on the spot.
+type DocumentService struct {
+	db       *gorm.DB
+	settings InvoiceSettings
+}
+
+func NewDocumentService(db *gorm.DB, settings InvoiceSettings) *DocumentService {
+	return &DocumentService{db: db, settings: settings}
+}
+
+// TicketsPDF renders the tickets of a confirmed booking, one page per seat
+func (s *DocumentService) TicketsPDF(ctx context.Context, bookingID, userID uint) ([]byte, error) {
+	var booking models.Booking
+	if err := s.db.WithContext(ctx).Preload("User").Preload("Showtime.Movie").Preload("Showtime.Hall").Preload("Seats.Seat").
+		First(&booking, bookingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("booking not found")
+		}
+		return nil, err
+	}
+	if booking.UserID != userID {
+		return nil, apperrors.Forbidden("booking does not belong to user")
+	}
+	if booking.Status != models.BookingStatusConfirmed {
+		return nil, apperrors.Conflict("only confirmed bookings have valid tickets")
+	}
+
+	var tickets []models.Ticket
+	if err := s.db.WithContext(ctx).Where("booking_id = ? AND status <> ?", booking.ID, models.TicketStatusCancelled).
+		Order("id").Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	seatNames := make(map[uint]string, len(booking.Seats))
+	for _, bookingSeat := range booking.Seats {
+		seatNames[bookingSeat.SeatID] = seatName(&bookingSeat.Seat)
+	}
+
+	pages := make([]documents.Ticket, len(tickets))
+	for i, ticket := range tickets {
+		pages[i] = documents.Ticket{
+			BookingID: booking.ID,
+			Customer:  booking.User.Name,
+			Movie:     booking.Showtime.Movie.Title,
+			Hall:      booking.Showtime.Hall.Name,
+			StartTime: booking.Showtime.StartTime,
+			Seat:      seatNames[ticket.SeatID],
+			Code:      ticket.BookingCode,
+			Price:     ticket.Price,
+			Currency:  s.settings.Currency,
+		}
+	}
+	var buf bytes.Buffer
+	if err := documents.WriteTickets(&buf, pages); err != nil {
+		return nil, fmt.Errorf("failed to render tickets: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GetInvoices returns the invoice and credit notes of a booking, issuing any
+// that are due
+func (s *DocumentService) GetInvoices(ctx context.Context, bookingID, userID uint) ([]*models.Invoice, error) {
+	if err := s.checkOwner(ctx, bookingID, userID); err != nil {
+		return nil, err
+	}
+
+	var payments []models.Payment
+	if err := s.db.WithContext(ctx).
+		Where("booking_id = ? AND status = ? AND kind IN ?", bookingID, models.PaymentStatusSucceeded,
+			[]string{models.PaymentKindCharge, models.PaymentKindRefund}).
+		Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		if _, err := s.issue(ctx, payment.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var invoices []*models.Invoice
+	if err := s.db.WithContext(ctx).Where("booking_id = ?", bookingID).Order("id").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// BookingInvoicePDF renders the invoice of a booking's charge
+func (s *DocumentService) BookingInvoicePDF(ctx context.Context, bookingID, userID uint) (*models.Invoice, []byte, error) {
+	if err := s.checkOwner(ctx, bookingID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	var charge models.Payment
+	err := s.db.WithContext(ctx).
+		Where("booking_id = ? AND kind = ? AND status = ?", bookingID, models.PaymentKindCharge, models.PaymentStatusSucceeded).
+		Order("id DESC").First(&charge).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, apperrors.NotFound("booking has no invoice")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	invoice, err := s.issue(ctx, charge.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.render(ctx, invoice)
+	return invoice, data, err
+}
+
+// InvoicePDF renders an invoice or credit note by number
+func (s *DocumentService) InvoicePDF(ctx context.Context, number string, userID uint) (*models.Invoice, []byte, error) {
+	var invoice models.Invoice
+	if err := s.db.WithContext(ctx).Where("number = ?", number).First(&invoice).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, apperrors.NotFound("invoice not found")
+		}
+		return nil, nil, err
+	}
+	if err := s.checkOwner(ctx, invoice.BookingID, userID); err != nil {
+		return nil, nil, err
+	}
+	data, err := s.render(ctx, &invoice)
+	return &invoice, data, err
+}
+
+// Name identifies the document service among the sinks of the event relay
+func (s *DocumentService) Name() string {
+	return "invoices"
+}
+
+// Publish issues the invoice or credit note of a payment that went through
+func (s *DocumentService) Publish(ctx context.Context, event events.Event) error {
+	if event.Type != events.TypePaymentSucceeded {
+		return nil
+	}
+	var payload events.PaymentSucceeded
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid %s event: %w", event.Type, err)
+	}
+	if payload.Kind != models.PaymentKindCharge && payload.Kind != models.PaymentKindRefund {
+		// Credit moved between exchanged bookings is covered by the original invoice
+		return nil
+	}
+	_, err := s.issue(ctx, payload.PaymentID)
+	return err
+}
+
+// issue issues the invoice of a charge or the credit note of a refund. The
+// document issued before is returned if there is one, so issuing is idempotent.
+func (s *DocumentService) issue(ctx context.Context, paymentID uint) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		invoice, err = s.issueTx(tx, paymentID)
+		return err
+	})
+	return invoice, err
+}
+
+func (s *DocumentService) issueTx(tx *gorm.DB, paymentID uint) (*models.Invoice, error) {
+	if invoice, err := findInvoice(tx, paymentID); invoice != nil || err != nil {
+		return invoice, err
+	}
+
+	var payment models.Payment
+	if err := tx.First(&payment, paymentID).Error; err != nil {
+		return nil, err
+	}
+	if payment.Status != models.PaymentStatusSucceeded {
+		return nil, apperrors.Conflict("payment %d has not gone through", paymentID)
+	}
+	var booking models.Booking
+	if err := tx.Preload("User").Preload("Showtime.Movie").Preload("Seats.Seat").First(&booking, payment.BookingID).Error; err != nil {
+		return nil, err
+	}
+
+	invoice := &models.Invoice{
+		BookingID:  booking.ID,
+		PaymentID:  payment.ID,
+		BuyerName:  booking.User.Name,
+		BuyerEmail: booking.User.Email,
+		TaxPercent: s.settings.TaxPercent,
+		Currency:   s.settings.Currency,
+	}
+	var lines []documents.InvoiceLine
+	prefix := s.settings.Prefix
+	switch payment.Kind {
+	case models.PaymentKindCharge:
+		invoice.Kind = models.InvoiceKindInvoice
+		lines = chargeLines(&booking, payment.Amount)
+	case models.PaymentKindRefund:
+		// Issue the corrected invoice first, before the credit note series is
+		// locked, so that series are always locked in the same order
+		corrected, err := s.correctedInvoice(tx, booking.ID)
+		if err != nil {
+			return nil, err
+		}
+		if corrected != nil {
+			invoice.CorrectsID = &corrected.ID
+		}
+		invoice.Kind = models.InvoiceKindCreditNote
+		prefix = s.settings.CreditNotePrefix
+		lines = []documents.InvoiceLine{{
+			Description: fmt.Sprintf("Refund for booking #%d, %s", booking.ID, booking.Showtime.Movie.Title),
+			Quantity:    1,
+			UnitPrice:   payment.Amount,
+		}}
+	default:
+		return nil, fmt.Errorf("payments of kind %s are not invoiced", payment.Kind)
+	}
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Lines = string(data)
+	invoice.Gross = payment.Amount
+	invoice.Net = math.Round(payment.Amount/(1+s.settings.TaxPercent/100)*100) / 100
+	invoice.Tax = math.Round((invoice.Gross-invoice.Net)*100) / 100
+
+	// Numbers are taken under a lock on the series, so they have no gaps
+	now := time.Now()
+	series := fmt.Sprintf("%s-%d", prefix, now.Year())
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.InvoiceSequence{Series: series}).Error; err != nil {
+		return nil, err
+	}
+	var sequence models.InvoiceSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sequence, "series = ?", series).Error; err != nil {
+		return nil, err
+	}
+	// Another transaction may have issued the document while we waited
+	if existing, err := findInvoice(tx, paymentID); existing != nil || err != nil {
+		return existing, err
+	}
+	sequence.LastNumber++
+	if err := tx.Model(&sequence).Update("last_number", sequence.LastNumber).Error; err != nil {
+		return nil, err
+	}
+
+	invoice.Number = fmt.Sprintf("%s-%06d", series, sequence.LastNumber)
+	invoice.IssuedAt = now
+	if err := tx.Create(invoice).Error; err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// correctedInvoice returns the invoice of the latest charge of a booking,
+// following exchanges back to the bookings it replaced, issuing it if needed
+func (s *DocumentService) correctedInvoice(tx *gorm.DB, bookingID uint) (*models.Invoice, error) {
+	for {
+		var charge models.Payment
+		err := tx.Where("booking_id = ? AND kind = ? AND status = ?", bookingID, models.PaymentKindCharge, models.PaymentStatusSucceeded).
+			Order("id DESC").First(&charge).Error
+		if err == nil {
+			return s.issueTx(tx, charge.ID)
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+
+		var booking models.Booking
+		if err := tx.Select("exchanged_from_id").First(&booking, bookingID).Error; err != nil {
+			return nil, err
+		}
+		if booking.ExchangedFromID == nil {
+			return nil, nil
+		}
+		bookingID = *booking.ExchangedFromID
+	}
+}
+
+// render renders an issued invoice or credit note as a PDF
+func (s *DocumentService) render(ctx context.Context, invoice *models.Invoice) ([]byte, error) {
+	doc := documents.Invoice{
+		Company:    s.settings.Company,
+		CreditNote: invoice.Kind == models.InvoiceKindCreditNote,
+		Number:     invoice.Number,
+		IssuedAt:   invoice.IssuedAt,
+		BookingID:  invoice.BookingID,
+		BuyerName:  invoice.BuyerName,
+		BuyerEmail: invoice.BuyerEmail,
+		Net:        invoice.Net,
+		Tax:        invoice.Tax,
+		Gross:      invoice.Gross,
+		TaxPercent: invoice.TaxPercent,
+		Currency:   invoice.Currency,
+	}
+	if err := json.Unmarshal([]byte(invoice.Lines), &doc.Lines); err != nil {
+		return nil, fmt.Errorf("invalid lines of invoice %s: %w", invoice.Number, err)
+	}
+	if invoice.CorrectsID != nil {
+		var corrected models.Invoice
+		if err := s.db.WithContext(ctx).Select("number").First(&corrected, *invoice.CorrectsID).Error; err != nil {
+			return nil, err
+		}
+		doc.CorrectsNumber = corrected.Number
+	}
+
+	var buf bytes.Buffer
+	if err := documents.WriteInvoice(&buf, doc); err != nil {
+		return nil, fmt.Errorf("failed to render invoice %s: %w", invoice.Number, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *DocumentService) checkOwner(ctx context.Context, bookingID, userID uint) error {
+	var booking models.Booking
+	if err := s.db.WithContext(ctx).Select("id", "user_id").First(&booking, bookingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return apperrors.NotFound("booking not found")
+		}
+		return err
+	}
+	if booking.UserID != userID {
+		return apperrors.Forbidden("booking does not belong to user")
+	}
+	return nil
+}
+
+func findInvoice(tx *gorm.DB, paymentID uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := tx.Where("payment_id = ?", paymentID).First(&invoice).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// chargeLines lists the seats of a booking when they make up the charge.
+// Charges for the price difference of an exchange are a single line.
+func chargeLines(booking *models.Booking, amount float64) []documents.InvoiceLine {
+	title := booking.Showtime.Movie.Title
+	when := booking.Showtime.StartTime.Format("2 Jan 2006 15:04")
+	var total float64
+	lines := make([]documents.InvoiceLine, len(booking.Seats))
+	for i, bookingSeat := range booking.Seats {
+		lines[i] = documents.InvoiceLine{
+			Description: fmt.Sprintf("Ticket %s, %s, seat %s", title, when, seatName(&bookingSeat.Seat)),
+			Quantity:    1,
+			UnitPrice:   bookingSeat.Price,
+		}
+		total += bookingSeat.Price
+	}
+	if booking.ExchangedFromID == nil && math.Abs(total-amount) < 0.005 {
+		return lines
+	}
+
+	description := fmt.Sprintf("Tickets %s, %s", title, when)
+	if booking.ExchangedFromID != nil {
+		description = fmt.Sprintf("Price difference for exchanging booking #%d to %s, %s", *booking.ExchangedFromID, title, when)
+	}
+	return []documents.InvoiceLine{{Description: description, Quantity: 1, UnitPrice: amount}}
+}
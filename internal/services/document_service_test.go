@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/database/redistest"
+	"movie-ticket-booking/internal/models"
+)
+
+var testInvoiceSettings = InvoiceSettings{
+	TaxPercent:       19,
+	Currency:         "EUR",
+	Prefix:           "INV",
+	CreditNotePrefix: "CN",
+}
+
+func TestConcurrentlyIssuedInvoicesAreNumberedWithoutGaps(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	bookings := newTestBookingService(t, db, redistest.New(t), &testGateway{})
+	documents := NewDocumentService(db, testInvoiceSettings)
+
+	const customers = 12
+	showtime := createTestShowtime(t, db, createTestMovie(t, db), time.Now().Add(72*time.Hour), 10, strings.Repeat(".", customers))
+	for i := 0; i < customers; i++ {
+		user := createTestUser(t, db, fmt.Sprintf("customer%d", i+1))
+		booking, err := bookings.CreateBooking(ctx, user.ID, showtime.ID, seatIDs(t, db, showtime, fmt.Sprintf("A%d", i+1)), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			if err := bookings.CancelBooking(ctx, booking.ID, user.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var payments []models.Payment
+	if err := db.Where("kind IN ? AND status = ?", []string{models.PaymentKindCharge, models.PaymentKindRefund}, models.PaymentStatusSucceeded).
+		Order("id").Find(&payments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != customers+customers/2 {
+		t.Fatalf("%d payments, want %d", len(payments), customers+customers/2)
+	}
+
+	// Every document is issued twice at the same time, as the event relay and
+	// a customer downloading it would; credit notes also issue the invoice
+	// they correct
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*len(payments))
+	for _, payment := range payments {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(paymentID uint) {
+				defer wg.Done()
+				_, err := documents.issue(ctx, paymentID)
+				errs <- err
+			}(payment.ID)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var invoices []models.Invoice
+	if err := db.Order("id").Find(&invoices).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(invoices) != len(payments) {
+		t.Fatalf("%d documents issued for %d payments", len(invoices), len(payments))
+	}
+	year := time.Now().Year()
+	numbers := map[string][]string{}
+	for _, invoice := range invoices {
+		numbers[invoice.Kind] = append(numbers[invoice.Kind], invoice.Number)
+	}
+	for kind, prefix := range map[string]string{models.InvoiceKindInvoice: "INV", models.InvoiceKindCreditNote: "CN"} {
+		got := numbers[kind]
+		sort.Strings(got)
+		for i, number := range got {
+			if want := fmt.Sprintf("%s-%d-%06d", prefix, year, i+1); number != want {
+				t.Errorf("%s numbers %v, want %s-%d-000001 onwards without gaps or duplicates", kind, got, prefix, year)
+				break
+			}
+		}
+	}
+	if len(numbers[models.InvoiceKindInvoice]) != customers || len(numbers[models.InvoiceKindCreditNote]) != customers/2 {
+		t.Errorf("%d invoices and %d credit notes, want %d and %d",
+			len(numbers[models.InvoiceKindInvoice]), len(numbers[models.InvoiceKindCreditNote]), customers, customers/2)
+	}
+
+	byBooking := make(map[uint]uint)
+	for _, invoice := range invoices {
+		if invoice.Kind == models.InvoiceKindInvoice {
+			byBooking[invoice.BookingID] = invoice.ID
+		}
+	}
+	for _, invoice := range invoices {
+		if invoice.Kind == models.InvoiceKindCreditNote && (invoice.CorrectsID == nil || *invoice.CorrectsID != byBooking[invoice.BookingID]) {
+			t.Errorf("credit note %s corrects %v, want the invoice of booking %d", invoice.Number, invoice.CorrectsID, invoice.BookingID)
+		}
+	}
+}
+
+func TestCreditNoteCorrectsInvoiceOfExchangedBooking(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	bookings := newTestBookingService(t, db, redistest.New(t), &testGateway{})
+	documents := NewDocumentService(db, testInvoiceSettings)
+
+	movie := createTestMovie(t, db)
+	first := createTestShowtime(t, db, movie, time.Now().Add(72*time.Hour), 10, "....")
+	second := createTestShowtime(t, db, movie, time.Now().Add(96*time.Hour), 10, "....")
+	third := createTestShowtime(t, db, movie, time.Now().Add(120*time.Hour), 6, "....")
+	user := createTestUser(t, db, "heidi")
+
+	original, err := bookings.CreateBooking(ctx, user.ID, first.ID, seatIDs(t, db, first, "A1", "A2"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same price, so the intermediate booking has no charge of its own
+	intermediate, err := bookings.ExchangeBooking(ctx, original.ID, user.ID, second.ID, seatIDs(t, db, second, "A1", "A2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := bookings.ExchangeBooking(ctx, intermediate.ID, user.ID, third.ID, seatIDs(t, db, third, "A1", "A2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	charges := bookingPayments(t, db, original.ID, models.PaymentKindCharge)
+	refunds := bookingPayments(t, db, last.ID, models.PaymentKindRefund)
+	if len(charges) != 1 || len(refunds) != 1 {
+		t.Fatalf("charges %+v of the original booking and refunds %+v of the last, want one each", charges, refunds)
+	}
+
+	// The charge was never invoiced, so issuing the credit note issues its invoice first
+	creditNote, err := documents.issue(ctx, refunds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoice, err := findInvoice(db, charges[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice == nil {
+		t.Fatalf("charge %d of the original booking was not invoiced", charges[0].ID)
+	}
+	if creditNote.Kind != models.InvoiceKindCreditNote || creditNote.BookingID != last.ID || creditNote.Gross != 8 {
+		t.Errorf("credit note: kind %s, booking %d, gross %.2f", creditNote.Kind, creditNote.BookingID, creditNote.Gross)
+	}
+	if creditNote.CorrectsID == nil || *creditNote.CorrectsID != invoice.ID {
+		t.Errorf("credit note corrects %v, want invoice %d of the original booking", creditNote.CorrectsID, invoice.ID)
+	}
+
+	corrected, err := documents.correctedInvoice(db, intermediate.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrected == nil || corrected.ID != invoice.ID {
+		t.Errorf("corrected invoice of the intermediate booking %+v, want invoice %d", corrected, invoice.ID)
+	}
+}
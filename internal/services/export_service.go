@@ -0,0 +1,329 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// staleExportAfter is how long a pending export may go without a sign of
+// life. Builds touch their export more often than that, so exports pending
+// for longer were interrupted, such as by a restart.
+const staleExportAfter = 5 * time.Minute
+
+// ExportRecoveryInterval is how often interrupted exports are looked for
+const ExportRecoveryInterval = time.Minute
+
+// ExportService assembles personal data exports for users
+type ExportService struct {
+	db             *gorm.DB
+	bookingService *BookingService
+	notifier       notification.Sender
+	dir            string
+	ttl            time.Duration
+	downloadURL    string
+}
+
+func NewExportService(db *gorm.DB, bookingService *BookingService, notifier notification.Sender, dir string, ttl time.Duration, downloadURL string) *ExportService {
+	return &ExportService{
+		db:             db,
+		bookingService: bookingService,
+		notifier:       notifier,
+		dir:            dir,
+		ttl:            ttl,
+		downloadURL:    downloadURL,
+	}
+}
+
+// RequestDataExport queues a data export for the user. The archive is built in
+// the background and the user is notified with a download link once it is ready.
+func (s *ExportService) RequestDataExport(ctx context.Context, userID uint) (*models.DataExport, error) {
+	s.purgeExpired()
+
+	// Interrupted exports would otherwise block new requests forever
+	if err := s.db.Model(&models.DataExport{}).
+		Where("user_id = ? AND status = ? AND updated_at < ?", userID, models.DataExportStatusPending, time.Now().Add(-staleExportAfter)).
+		Updates(map[string]interface{}{
+			"status": models.DataExportStatusFailed,
+			"error":  "export was interrupted",
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	var pending int64
+	if err := s.db.Model(&models.DataExport{}).
+		Where("user_id = ? AND status = ?", userID, models.DataExportStatusPending).
+		Count(&pending).Error; err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, apperrors.Conflict("a data export is already in progress")
+	}
+
+	export := &models.DataExport{
+		UserID:    userID,
+		Status:    models.DataExportStatusPending,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.db.Create(export).Error; err != nil {
+		return nil, err
+	}
+
+	go s.build(context.WithoutCancel(ctx), export)
+
+	return export, nil
+}
+
+// OpenExport returns the path of a ready export archive for a download token
+func (s *ExportService) OpenExport(token string) (string, error) {
+	var export models.DataExport
+	if err := s.db.Where("token = ? AND status = ?", hashToken(token), models.DataExportStatusReady).First(&export).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", apperrors.NotFound("export not found")
+		}
+		return "", err
+	}
+	if time.Now().After(export.ExpiresAt) {
+		return "", apperrors.NotFound("export has expired")
+	}
+	return export.FilePath, nil
+}
+
+// RunRecovery resumes interrupted exports every interval until ctx is
+// cancelled. beat is called after every round to signal the worker is alive.
+func (s *ExportService) RunRecovery(ctx context.Context, interval time.Duration, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.resumeInterrupted(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to resume data exports", "error", err)
+		}
+		beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resumeInterrupted rebuilds the exports whose build was interrupted, such as
+// by a restart
+func (s *ExportService) resumeInterrupted(ctx context.Context) error {
+	var interrupted []*models.DataExport
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", models.DataExportStatusPending, time.Now().Add(-staleExportAfter)).
+		Find(&interrupted).Error; err != nil {
+		return err
+	}
+
+	for _, export := range interrupted {
+		// Claim the export so that replicas starting together build it once
+		result := s.db.WithContext(ctx).Model(export).
+			Where("status = ? AND updated_at = ?", models.DataExportStatusPending, export.UpdatedAt).
+			Update("updated_at", time.Now())
+		if result.Error != nil {
+			slog.Error("failed to resume data export", "export_id", export.ID, "error", result.Error)
+			continue
+		}
+		if result.RowsAffected == 1 {
+			slog.Info("resuming interrupted data export", "export_id", export.ID)
+			s.build(ctx, export)
+		}
+	}
+	return nil
+}
+
+func (s *ExportService) build(ctx context.Context, export *models.DataExport) {
+	stop := s.keepAlive(export)
+	user, token, err := s.complete(export)
+	stop()
+	if err != nil {
+		slog.Error("data export failed", "export_id", export.ID, "error", err)
+		if err := s.db.Model(export).Updates(map[string]interface{}{
+			"status": models.DataExportStatusFailed,
+			"error":  err.Error(),
+		}).Error; err != nil {
+			// The export stays pending until it is stale and resumed or failed
+			slog.Error("failed to mark data export as failed", "export_id", export.ID, "error", err)
+		}
+		return
+	}
+
+	err = s.notifier.Send(ctx, notification.Message{
+		To:      user.Email,
+		Subject: "Your personal data export is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nYour data export is ready: %s?token=%s\n\nThe link expires on %s.",
+			user.Name, s.downloadURL, token, export.ExpiresAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		slog.Error("failed to notify user about data export", "user_id", user.ID, "export_id", export.ID, "error", err)
+	}
+}
+
+// keepAlive touches a pending export while it is built so that it doesn't
+// look interrupted. The returned function stops it.
+func (s *ExportService) keepAlive(export *models.DataExport) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(staleExportAfter / 5)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.db.Model(&models.DataExport{}).
+					Where("id = ? AND status = ?", export.ID, models.DataExportStatusPending).
+					Update("updated_at", time.Now()).Error; err != nil {
+					slog.Error("failed to touch data export", "export_id", export.ID, "error", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// complete writes the archive of an export and makes it ready for download
+// with the returned token
+func (s *ExportService) complete(export *models.DataExport) (*models.User, string, error) {
+	user, path, err := s.writeArchive(export)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.db.Model(export).Updates(map[string]interface{}{
+		"status":    models.DataExportStatusReady,
+		"token":     hashToken(token),
+		"file_path": path,
+	}).Error; err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// exportedInvoice shows the lines of an invoice as JSON rather than a string
+type exportedInvoice struct {
+	models.Invoice
+	Lines json.RawMessage
+}
+
+// writeArchive collects the user's data into a ZIP archive of JSON documents
+func (s *ExportService) writeArchive(export *models.DataExport) (*models.User, string, error) {
+	var user models.User
+	if err := s.db.First(&user, export.UserID).Error; err != nil {
+		return nil, "", err
+	}
+
+	// Bookings come from the booking service so the export matches what the user sees
+	bookings, err := s.bookingService.GetUserBookings(user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var tickets []models.Ticket
+	if err := s.db.Where("user_id = ?", user.ID).Find(&tickets).Error; err != nil {
+		return nil, "", err
+	}
+
+	var logins []models.LoginEvent
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&logins).Error; err != nil {
+		return nil, "", err
+	}
+
+	var identities []models.UserIdentity
+	if err := s.db.Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+		return nil, "", err
+	}
+
+	bookingIDs := s.db.Model(&models.Booking{}).Select("id").Where("user_id = ?", user.ID)
+	var payments []models.Payment
+	if err := s.db.Where("booking_id IN (?)", bookingIDs).Order("id").Find(&payments).Error; err != nil {
+		return nil, "", err
+	}
+
+	var invoiceRows []models.Invoice
+	if err := s.db.Where("booking_id IN (?)", bookingIDs).Order("id").Find(&invoiceRows).Error; err != nil {
+		return nil, "", err
+	}
+	invoices := make([]exportedInvoice, len(invoiceRows))
+	for i, invoice := range invoiceRows {
+		invoices[i] = exportedInvoice{Invoice: invoice, Lines: json.RawMessage(invoice.Lines)}
+	}
+
+	documents := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"bookings.json", bookings},
+		{"tickets.json", tickets},
+		{"login_history.json", logins},
+		{"linked_accounts.json", identities},
+		{"payments.json", payments},
+		{"invoices.json", invoices},
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("export-%d-%d.zip", user.ID, export.ID))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for _, doc := range documents {
+		w, err := archive.Create(doc.name)
+		if err != nil {
+			return nil, "", err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(doc.data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &user, path, nil
+}
+
+// purgeExpired removes expired export archives
+func (s *ExportService) purgeExpired() {
+	var expired []models.DataExport
+	if err := s.db.Where("expires_at < ?", time.Now()).Find(&expired).Error; err != nil {
+		slog.Error("failed to look up expired data exports", "error", err)
+		return
+	}
+	for _, export := range expired {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+				slog.Error("failed to remove data export", "export_id", export.ID, "error", err)
+				continue
+			}
+		}
+		s.db.Delete(&export)
+	}
+}
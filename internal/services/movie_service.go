@@ -1,7 +1,7 @@
 package services
 
 import (
-	"errors"
+	"movie-ticket-booking/internal/apperrors"
 	"movie-ticket-booking/internal/models"
 	"gorm.io/gorm"
 )
@@ -39,7 +39,7 @@ func (s *MovieService) GetMovieByID(id uint) (*models.Movie, error) {
 	var movie models.Movie
 	if err := s.db.First(&movie, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, errors.New("movie not found")
+			return nil, apperrors.NotFound("movie not found")
 		}
 		return nil, err
 	}
@@ -55,7 +55,7 @@ func (s *MovieService) CreateMovie(movie *models.Movie) error {
 func (s *MovieService) UpdateMovie(movie *models.Movie) error {
 	if err := s.db.First(&models.Movie{}, movie.ID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return errors.New("movie not found")
+			return apperrors.NotFound("movie not found")
 		}
 		return err
 	}
@@ -69,7 +69,7 @@ func (s *MovieService) DeleteMovie(id uint) error {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("movie not found")
+		return apperrors.NotFound("movie not found")
 	}
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxNotificationAttempts is how often delivery of a notification is tried before giving up
+const maxNotificationAttempts = 5
+
+// NotificationService queues customer emails in the database and delivers them
+// in the background, so that they are sent even if the server crashes after
+// the change they report was committed
+type NotificationService struct {
+	db     *gorm.DB
+	sender notification.Sender
+}
+
+func NewNotificationService(db *gorm.DB, sender notification.Sender) *NotificationService {
+	return &NotificationService{
+		db:     db,
+		sender: sender,
+	}
+}
+
+// Enqueue queues a message within tx; it is only sent if tx commits
+func (s *NotificationService) Enqueue(tx *gorm.DB, userID *uint, msg notification.Message) error {
+	attachments := make([]models.NotificationAttachment, len(msg.Attachments))
+	for i, attachment := range msg.Attachments {
+		attachments[i] = models.NotificationAttachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			ContentID:   attachment.ContentID,
+			Content:     attachment.Content,
+		}
+	}
+	return tx.Create(&models.Notification{
+		UserID:      userID,
+		Recipient:   msg.To,
+		Subject:     msg.Subject,
+		Body:        msg.Body,
+		HTMLBody:    msg.HTMLBody,
+		Status:      models.NotificationStatusPending,
+		Attachments: attachments,
+	}).Error
+}
+
+// Run delivers pending notifications every interval until ctx is cancelled.
+// beat is called after every round to signal the worker is alive.
+func (s *NotificationService) Run(ctx context.Context, interval time.Duration, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.deliverPending(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to deliver notifications", "error", err)
+		}
+		beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverPending sends a batch of pending notifications. Rows are locked with
+// SKIP LOCKED so that several replicas never send the same message twice.
+func (s *NotificationService) deliverPending(ctx context.Context) error {
+	for {
+		var sent int
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var pending []models.Notification
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ?", models.NotificationStatusPending).
+				Order("id").Limit(50).Preload("Attachments").Find(&pending).Error; err != nil {
+				return err
+			}
+			for i := range pending {
+				if err := s.deliver(ctx, tx, &pending[i]); err != nil {
+					return err
+				}
+			}
+			sent = len(pending)
+			return nil
+		})
+		if err != nil || sent == 0 {
+			return err
+		}
+	}
+}
+
+func (s *NotificationService) deliver(ctx context.Context, tx *gorm.DB, n *models.Notification) error {
+	updates := map[string]interface{}{"attempts": n.Attempts + 1}
+	msg := notification.Message{To: n.Recipient, Subject: n.Subject, Body: n.Body, HTMLBody: n.HTMLBody}
+	for _, attachment := range n.Attachments {
+		msg.Attachments = append(msg.Attachments, notification.Attachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			ContentID:   attachment.ContentID,
+			Content:     attachment.Content,
+		})
+	}
+	err := s.sender.Send(ctx, msg)
+	switch {
+	case err == nil:
+		updates["status"] = models.NotificationStatusSent
+		updates["sent_at"] = time.Now()
+		updates["last_error"] = ""
+		metrics.NotificationsSent.WithLabelValues("sent").Inc()
+	case n.Attempts+1 >= maxNotificationAttempts:
+		slog.Error("giving up on notification", "notification_id", n.ID, "error", err)
+		updates["status"] = models.NotificationStatusFailed
+		updates["last_error"] = err.Error()
+		metrics.NotificationsSent.WithLabelValues("failed").Inc()
+	default:
+		slog.Warn("failed to send notification, will retry", "notification_id", n.ID, "attempt", n.Attempts+1, "error", err)
+		updates["last_error"] = err.Error()
+		metrics.NotificationsSent.WithLabelValues("retry").Inc()
+	}
+	return tx.Model(n).Omit(clause.Associations).Updates(updates).Error
+}
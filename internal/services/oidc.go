@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OIDCProvider describes an OpenID Connect identity provider such as Google or Apple
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type oidcProvider struct {
+	OIDCProvider
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcIDTokenClaims struct {
+	jwt.RegisteredClaims
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // Apple sends "true" as a string
+	Name          string      `json:"name"`
+	Nonce         string      `json:"nonce"`
+}
+
+// RegisterOIDCProvider enables social login with the given provider
+func (s *AuthService) RegisterOIDCProvider(p OIDCProvider) {
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"openid", "email", "profile"}
+	}
+	s.oidcProviders[p.Name] = &oidcProvider{OIDCProvider: p}
+}
+
+// BeginOIDCLogin starts an authorization-code login with PKCE and returns the
+// URL the user has to be redirected to together with the generated state.
+// When linkUserID is set the provider account is linked to that user.
+func (s *AuthService) BeginOIDCLogin(ctx context.Context, providerName string, linkUserID *uint) (string, string, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", "", apperrors.Validation("unknown identity provider: %s", providerName)
+	}
+
+	discovery, err := s.oidcDiscover(ctx, provider)
+	if err != nil {
+		return "", "", apperrors.Wrap(apperrors.CodeUnavailable, err, "identity provider is unavailable")
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState := &models.OIDCLoginState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(s.oidcStateTTL),
+		UserID:       linkUserID,
+	}
+	if err := s.db.Create(loginState).Error; err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", provider.ClientID)
+	params.Set("redirect_uri", provider.RedirectURL)
+	params.Set("scope", strings.Join(provider.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), state, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the ID token and
+// returns a JWT for the local user linked to the external identity
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, providerName, code, state string) (string, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", apperrors.Validation("unknown identity provider: %s", providerName)
+	}
+
+	// The state can only be used once, so it is consumed in one statement
+	var loginState models.OIDCLoginState
+	result := s.db.WithContext(ctx).Unscoped().Clauses(clause.Returning{}).
+		Where("state = ? AND provider = ?", state, providerName).Delete(&loginState)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", apperrors.Unauthenticated("invalid login state")
+	}
+	if time.Now().After(loginState.ExpiresAt) {
+		return "", apperrors.Unauthenticated("login state has expired")
+	}
+
+	discovery, err := s.oidcDiscover(ctx, provider)
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.CodeUnavailable, err, "identity provider is unavailable")
+	}
+
+	rawIDToken, err := s.oidcExchangeCode(ctx, provider, discovery, code, loginState.CodeVerifier)
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.CodeUnauthenticated, err, "social login failed")
+	}
+
+	claims, err := s.oidcVerifyIDToken(ctx, provider, discovery, rawIDToken)
+	if err != nil {
+		return "", apperrors.Wrap(apperrors.CodeUnauthenticated, err, "social login failed")
+	}
+	if claims.Nonce != loginState.Nonce {
+		return "", apperrors.Unauthenticated("social login failed")
+	}
+
+	user, err := s.linkOIDCIdentity(providerName, claims, loginState.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	s.recordLogin(user.ID, "oidc:"+providerName)
+
+	return s.generateToken(user)
+}
+
+// linkOIDCIdentity finds the user for an external identity. A new identity is
+// linked to linkUserID when set, otherwise to the account with the same
+// verified email, or to a new user when there is none.
+func (s *AuthService) linkOIDCIdentity(providerName string, claims *oidcIDTokenClaims, linkUserID *uint) (*models.User, error) {
+	var identity models.UserIdentity
+	err := s.db.Preload("User").Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+	if err == nil {
+		if linkUserID != nil && identity.UserID != *linkUserID {
+			return nil, apperrors.Conflict("this %s account is linked to another user", providerName)
+		}
+		return &identity.User, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var user models.User
+	if linkUserID != nil {
+		if err := s.db.First(&user, *linkUserID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, apperrors.Unauthenticated("user not found")
+			}
+			return nil, err
+		}
+		if err := s.db.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	if claims.Email == "" || !isEmailVerified(claims.EmailVerified) {
+		return nil, apperrors.Unauthenticated("identity provider did not return a verified email")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", claims.Email).First(&user).Error
+		if err == nil {
+			// Anyone can register an address they don't own, so only
+			// accounts that verified it are taken over by the provider account
+			if user.EmailVerifiedAt == nil {
+				return apperrors.Conflict("an account with this email already exists; sign in with your password to link your %s account", providerName)
+			}
+		} else {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+
+			name := claims.Name
+			if name == "" {
+				name = claims.Email
+			}
+			verifiedAt := time.Now()
+			// Social accounts have no password until the user sets one
+			user = models.User{
+				Email:           claims.Email,
+				Name:            name,
+				EmailVerifiedAt: &verifiedAt,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *AuthService) oidcDiscover(ctx context.Context, provider *oidcProvider) (*oidcDiscovery, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.discovery != nil {
+		return provider.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	wellKnown := strings.TrimSuffix(provider.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := s.oidcGetJSON(ctx, wellKnown, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+	if discovery.Issuer != provider.Issuer {
+		return nil, fmt.Errorf("identity provider issuer mismatch: %s", discovery.Issuer)
+	}
+
+	provider.discovery = &discovery
+	return provider.discovery, nil
+}
+
+func (s *AuthService) oidcExchangeCode(ctx context.Context, provider *oidcProvider, discovery *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.RedirectURL)
+	form.Set("client_id", provider.ClientID)
+	form.Set("code_verifier", verifier)
+	if provider.ClientSecret != "" {
+		form.Set("client_secret", provider.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to exchange authorization code: status %d", resp.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("token response did not contain an ID token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+func (s *AuthService) oidcVerifyIDToken(ctx context.Context, provider *oidcProvider, discovery *oidcDiscovery, rawIDToken string) (*oidcIDTokenClaims, error) {
+	claims := &oidcIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.oidcSigningKey(ctx, provider, discovery, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+
+	return claims, nil
+}
+
+// oidcSigningKey returns the provider key with the given ID, refreshing the
+// key set once when the key is unknown to pick up rotated keys
+func (s *AuthService) oidcSigningKey(ctx context.Context, provider *oidcProvider, discovery *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if key, ok := provider.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := s.oidcGetJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	provider.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return key, nil
+}
+
+func (s *AuthService) oidcGetJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func isEmailVerified(v interface{}) bool {
+	switch verified := v.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+// randomToken returns a URL-safe random string suitable for state, nonce and PKCE verifiers
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
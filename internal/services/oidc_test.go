@@ -0,0 +1,442 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	testProviderName = "fake"
+	testClientID     = "test-client"
+	testRedirectURL  = "http://localhost:3000/auth/callback/fake"
+	testSigningKeyID = "test-key"
+)
+
+// fakeOIDCProvider is an in-process OpenID Connect provider that issues
+// authorization codes, checks PKCE and signs ID tokens with its own key
+type fakeOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthRequest
+
+	// Claims of the next ID tokens
+	subject       string
+	email         string
+	emailVerified bool
+	nonce         string // overrides the nonce of the authorization request when set
+}
+
+type fakeAuthRequest struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeOIDCProvider{
+		t:             t,
+		key:           key,
+		codes:         make(map[string]fakeAuthRequest),
+		subject:       "subject-1",
+		email:         "alice@example.com",
+		emailVerified: true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(oidcDiscovery{
+		Issuer:                p.server.URL,
+		AuthorizationEndpoint: p.server.URL + "/authorize",
+		TokenEndpoint:         p.server.URL + "/token",
+		JWKSURI:               p.server.URL + "/jwks",
+	})
+}
+
+func (p *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": testSigningKeyID,
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *fakeOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != testClientID ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" ||
+		q.Get("state") == "" || q.Get("nonce") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		p.t.Error(err)
+		return
+	}
+	p.mu.Lock()
+	p.codes[code] = fakeAuthRequest{
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+	}
+	p.mu.Unlock()
+
+	callback, _ := url.Parse(q.Get("redirect_uri"))
+	params := callback.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Codes can only be redeemed once
+	p.mu.Lock()
+	request, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != testClientID ||
+		r.PostForm.Get("redirect_uri") != request.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != request.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := request.nonce
+	if p.nonce != "" {
+		nonce = p.nonce
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            testClientID,
+		"sub":            p.subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          p.email,
+		"email_verified": p.emailVerified,
+		"name":           "Alice",
+		"nonce":          nonce,
+	})
+	token.Header["kid"] = testSigningKeyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		p.t.Error(err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "access_token": "access"})
+}
+
+// authorizeUser follows the authorization URL like a browser whose user
+// consents, and returns the code and state of the callback
+func (p *fakeOIDCProvider) authorizeUser(authorizationURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authorizationURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		p.t.Fatalf("authorization request failed with status %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if got := callback.Scheme + "://" + callback.Host + callback.Path; got != testRedirectURL {
+		p.t.Fatalf("redirected to %s, want %s", got, testRedirectURL)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func newOIDCTestAuthService(t *testing.T) (*AuthService, *fakeOIDCProvider, *gorm.DB) {
+	db := dbtest.New(t)
+	provider := newFakeOIDCProvider(t)
+	auth := NewAuthService(db, "test-secret-that-is-long-enough-for-jwt", time.Hour, 10*time.Minute)
+	auth.RegisterOIDCProvider(OIDCProvider{
+		Name:        testProviderName,
+		Issuer:      provider.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	})
+	return auth, provider, db
+}
+
+// oidcLogin runs a social login through the fake provider
+func oidcLogin(t *testing.T, auth *AuthService, provider *fakeOIDCProvider, linkUserID *uint) (*Claims, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authorizationURL, state, err := auth.BeginOIDCLogin(ctx, testProviderName, linkUserID)
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin: %v", err)
+	}
+	code, callbackState := provider.authorizeUser(authorizationURL)
+	if callbackState != state {
+		t.Fatalf("callback state %q, want %q", callbackState, state)
+	}
+
+	token, err := auth.CompleteOIDCLogin(ctx, testProviderName, code, state)
+	if err != nil {
+		return nil, err
+	}
+	return auth.ValidateToken(token)
+}
+
+func assertCode(t *testing.T, err error, want apperrors.Code) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected a %s error, got none", want)
+	}
+	if got := apperrors.CodeOf(err); got != want {
+		t.Fatalf("error code %s, want %s: %v", got, want, err)
+	}
+}
+
+func countIdentities(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.UserIdentity{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	auth, provider, db := newOIDCTestAuthService(t)
+
+	claims, err := oidcLogin(t, auth, provider, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	var user models.User
+	if err := db.First(&user, claims.UserID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != provider.email || user.EmailVerifiedAt == nil {
+		t.Errorf("user %s verified at %v, want verified %s", user.Email, user.EmailVerifiedAt, provider.email)
+	}
+
+	// Logging in again finds the linked identity
+	again, err := oidcLogin(t, auth, provider, nil)
+	if err != nil {
+		t.Fatalf("second login failed: %v", err)
+	}
+	if again.UserID != claims.UserID {
+		t.Errorf("second login got user %d, want %d", again.UserID, claims.UserID)
+	}
+	if n := countIdentities(t, db); n != 1 {
+		t.Errorf("%d identities, want 1", n)
+	}
+}
+
+func TestOIDCLoginRejectsInvalidCallbacks(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the pending login or the provider before the callback
+		tamper func(t *testing.T, db *gorm.DB, provider *fakeOIDCProvider, state string)
+		// state returns the state sent with the callback
+		state func(state string) string
+	}{
+		{
+			name:  "unknown state",
+			state: func(string) string { return "forged" },
+		},
+		{
+			name: "expired state",
+			tamper: func(t *testing.T, db *gorm.DB, _ *fakeOIDCProvider, state string) {
+				if err := db.Model(&models.OIDCLoginState{}).Where("state = ?", state).
+					Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "wrong PKCE verifier",
+			tamper: func(t *testing.T, db *gorm.DB, _ *fakeOIDCProvider, state string) {
+				if err := db.Model(&models.OIDCLoginState{}).Where("state = ?", state).
+					Update("code_verifier", "not-the-verifier").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "nonce mismatch",
+			tamper: func(_ *testing.T, _ *gorm.DB, provider *fakeOIDCProvider, _ string) {
+				provider.nonce = "replayed-nonce"
+			},
+		},
+		{
+			name: "unverified email",
+			tamper: func(_ *testing.T, _ *gorm.DB, provider *fakeOIDCProvider, _ string) {
+				provider.emailVerified = false
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, provider, db := newOIDCTestAuthService(t)
+			ctx := context.Background()
+
+			authorizationURL, state, err := auth.BeginOIDCLogin(ctx, testProviderName, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, _ := provider.authorizeUser(authorizationURL)
+			if tt.tamper != nil {
+				tt.tamper(t, db, provider, state)
+			}
+			if tt.state != nil {
+				state = tt.state(state)
+			}
+
+			_, err = auth.CompleteOIDCLogin(ctx, testProviderName, code, state)
+			assertCode(t, err, apperrors.CodeUnauthenticated)
+			if n := countIdentities(t, db); n != 0 {
+				t.Errorf("%d identities linked, want 0", n)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginStateIsSingleUse(t *testing.T) {
+	auth, provider, db := newOIDCTestAuthService(t)
+	ctx := context.Background()
+
+	authorizationURL, state, err := auth.BeginOIDCLogin(ctx, testProviderName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := provider.authorizeUser(authorizationURL)
+	if _, err := auth.CompleteOIDCLogin(ctx, testProviderName, code, state); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	_, err = auth.CompleteOIDCLogin(ctx, testProviderName, code, state)
+	assertCode(t, err, apperrors.CodeUnauthenticated)
+
+	var count int64
+	if err := db.Unscoped().Model(&models.OIDCLoginState{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("%d login states left, want 0", count)
+	}
+}
+
+func TestOIDCLoginDoesNotTakeOverUnverifiedAccount(t *testing.T) {
+	auth, provider, db := newOIDCTestAuthService(t)
+
+	// Someone registered the address before its owner signs in with the provider
+	squatter, err := auth.Register(provider.email, "password123", "Mallory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = oidcLogin(t, auth, provider, nil)
+	assertCode(t, err, apperrors.CodeConflict)
+	if n := countIdentities(t, db); n != 0 {
+		t.Errorf("%d identities linked, want 0", n)
+	}
+
+	// Once the address is verified, the provider account is linked to it
+	if err := db.Model(squatter).Update("email_verified_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	claims, err := oidcLogin(t, auth, provider, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if claims.UserID != squatter.ID {
+		t.Errorf("logged in as user %d, want %d", claims.UserID, squatter.ID)
+	}
+}
+
+func TestOIDCLoginLinksSignedInUser(t *testing.T) {
+	auth, provider, db := newOIDCTestAuthService(t)
+
+	user, err := auth.Register("alice.local@example.com", "password123", "Alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := oidcLogin(t, auth, provider, &user.ID)
+	if err != nil {
+		t.Fatalf("linking failed: %v", err)
+	}
+	if claims.UserID != user.ID {
+		t.Fatalf("linked to user %d, want %d", claims.UserID, user.ID)
+	}
+
+	// The provider account now signs in to the local account
+	claims, err = oidcLogin(t, auth, provider, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if claims.UserID != user.ID {
+		t.Errorf("logged in as user %d, want %d", claims.UserID, user.ID)
+	}
+
+	// and cannot be linked to a second account
+	other, err := auth.Register("bob@example.com", "password123", "Bob", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = oidcLogin(t, auth, provider, &other.ID)
+	assertCode(t, err, apperrors.CodeConflict)
+	if n := countIdentities(t, db); n != 1 {
+		t.Errorf("%d identities, want 1", n)
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/jobs"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// Kinds of background jobs run by the receipt service
+const (
+	JobBookingConfirmation = "booking_confirmation"
+	JobBookingCancellation = "booking_cancellation"
+)
+
+// Email templates rendered by the receipt service
+const (
+	TemplateBookingConfirmed = "booking_confirmed"
+	TemplateBookingCancelled = "booking_cancelled"
+)
+
+// qrCodeSize is the width and height of ticket QR code images in pixels
+const qrCodeSize = 320
+
+// cancellationJob is the payload of cancellation email jobs
+type cancellationJob struct {
+	BookingID uint    `json:"booking_id"`
+	Refund    float64 `json:"refund"`
+}
+
+// bookingEmail is the data email templates about a booking are rendered with
+type bookingEmail struct {
+	Name      string
+	BookingID uint
+	Movie     string
+	Hall      string
+	StartTime time.Time
+	Seats     []string
+	Tickets   []emailTicket
+	Total     float64
+	Refund    float64
+}
+
+// emailTicket is a ticket shown in an email with its QR code image
+type emailTicket struct {
+	Seat   string
+	Code   string
+	QRCode htmltemplate.URL
+}
+
+// ReceiptService emails customers a confirmation with their e-tickets and a
+// calendar invite when they book, and a confirmation when they cancel. The
+// emails are rendered in background jobs scheduled with the booking change.
+type ReceiptService struct {
+	scheduler     *jobs.Scheduler
+	notifications *NotificationService
+	renderer      *notification.Renderer
+}
+
+func NewReceiptService(scheduler *jobs.Scheduler, notifications *NotificationService, renderer *notification.Renderer) *ReceiptService {
+	s := &ReceiptService{
+		scheduler:     scheduler,
+		notifications: notifications,
+		renderer:      renderer,
+	}
+	scheduler.Register(JobBookingConfirmation, s.sendConfirmation)
+	scheduler.Register(JobBookingCancellation, s.sendCancellation)
+	return s
+}
+
+// ScheduleConfirmation plans the confirmation email of a new booking within tx
+func (s *ReceiptService) ScheduleConfirmation(tx *gorm.DB, booking *models.Booking) error {
+	key := fmt.Sprintf("%s:%d", JobBookingConfirmation, booking.ID)
+	return s.scheduler.Schedule(tx, JobBookingConfirmation, key, bookingJob{BookingID: booking.ID}, time.Now())
+}
+
+// ScheduleCancellation plans the cancellation email of a booking within tx
+func (s *ReceiptService) ScheduleCancellation(tx *gorm.DB, booking *models.Booking, refund float64) error {
+	key := fmt.Sprintf("%s:%d", JobBookingCancellation, booking.ID)
+	return s.scheduler.Schedule(tx, JobBookingCancellation, key, cancellationJob{BookingID: booking.ID, Refund: refund}, time.Now())
+}
+
+// Preview renders an email template with sample data, for designers. format
+// is html or text.
+func (s *ReceiptService) Preview(name, locale, format string) (string, error) {
+	data := sampleBookingEmail()
+	switch name {
+	case TemplateBookingConfirmed:
+		for i := range data.Tickets {
+			png, err := qrcode.Encode(data.Tickets[i].Code, qrcode.Medium, qrCodeSize)
+			if err != nil {
+				return "", err
+			}
+			data.Tickets[i].QRCode = htmltemplate.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png))
+		}
+	case TemplateBookingCancelled:
+		data.Refund = data.Total
+	default:
+		return "", apperrors.NotFound("email template %q not found", name)
+	}
+
+	msg, err := s.renderer.Render(name, locale, data)
+	if err != nil {
+		return "", err
+	}
+	if format == "text" {
+		return fmt.Sprintf("Subject: %s\n\n%s", msg.Subject, msg.Body), nil
+	}
+	return msg.HTMLBody, nil
+}
+
+// Templates returns the names of the email templates
+func (s *ReceiptService) Templates() []string {
+	return s.renderer.Templates()
+}
+
+func (s *ReceiptService) sendConfirmation(ctx context.Context, tx *gorm.DB, payload []byte) error {
+	booking, err := loadJobBooking(tx, payload)
+	if err != nil || booking == nil {
+		return err
+	}
+	var tickets []models.Ticket
+	if err := tx.Where("booking_id = ? AND status <> ?", booking.ID, models.TicketStatusCancelled).
+		Order("id").Find(&tickets).Error; err != nil {
+		return err
+	}
+
+	data := newBookingEmail(booking)
+	seatNames := make(map[uint]string, len(booking.Seats))
+	for _, bookingSeat := range booking.Seats {
+		seatNames[bookingSeat.SeatID] = seatName(&bookingSeat.Seat)
+	}
+	var attachments []notification.Attachment
+	for _, ticket := range tickets {
+		png, err := qrcode.Encode(ticket.BookingCode, qrcode.Medium, qrCodeSize)
+		if err != nil {
+			return fmt.Errorf("failed to encode QR code: %w", err)
+		}
+		contentID := "ticket-" + ticket.BookingCode
+		data.Tickets = append(data.Tickets, emailTicket{
+			Seat:   seatNames[ticket.SeatID],
+			Code:   ticket.BookingCode,
+			QRCode: htmltemplate.URL("cid:" + contentID),
+		})
+		attachments = append(attachments, notification.Attachment{
+			Filename:    fmt.Sprintf("ticket-%s.png", seatNames[ticket.SeatID]),
+			ContentType: "image/png",
+			ContentID:   contentID,
+			Content:     png,
+		})
+	}
+	attachments = append(attachments, showtimeCalendarEvent(booking, false).Attachment("showtime.ics"))
+
+	msg, err := s.renderer.Render(TemplateBookingConfirmed, booking.User.Locale, data)
+	if err != nil {
+		return err
+	}
+	msg.To = booking.User.Email
+	msg.Attachments = attachments
+	return s.notifications.Enqueue(tx, &booking.UserID, msg)
+}
+
+func (s *ReceiptService) sendCancellation(ctx context.Context, tx *gorm.DB, payload []byte) error {
+	var job cancellationJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("invalid cancellation job payload: %w", err)
+	}
+	var booking models.Booking
+	err := tx.Preload("User").Preload("Showtime.Movie").Preload("Showtime.Hall").Preload("Seats.Seat").
+		First(&booking, job.BookingID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data := newBookingEmail(&booking)
+	data.Refund = job.Refund
+	msg, err := s.renderer.Render(TemplateBookingCancelled, booking.User.Locale, data)
+	if err != nil {
+		return err
+	}
+	msg.To = booking.User.Email
+	msg.Attachments = []notification.Attachment{showtimeCalendarEvent(&booking, true).Attachment("showtime.ics")}
+	return s.notifications.Enqueue(tx, &booking.UserID, msg)
+}
+
+// newBookingEmail fills the template data of a booking loaded with its
+// customer, showtime and seats
+func newBookingEmail(booking *models.Booking) *bookingEmail {
+	showtime := &booking.Showtime
+	seats := make([]string, len(booking.Seats))
+	for i, bookingSeat := range booking.Seats {
+		seats[i] = seatName(&bookingSeat.Seat)
+	}
+	return &bookingEmail{
+		Name:      booking.User.Name,
+		BookingID: booking.ID,
+		Movie:     showtime.Movie.Title,
+		Hall:      showtime.Hall.Name,
+		StartTime: showtime.StartTime,
+		Seats:     seats,
+		Total:     booking.TotalAmount,
+	}
+}
+
+// showtimeCalendarEvent returns the calendar event of a booked show. The UID
+// is stable so that the cancellation removes the event the confirmation added.
+func showtimeCalendarEvent(booking *models.Booking, cancelled bool) notification.CalendarEvent {
+	showtime := &booking.Showtime
+	return notification.CalendarEvent{
+		UID:         fmt.Sprintf("booking-%d@movie-ticket-booking", booking.ID),
+		Start:       showtime.StartTime,
+		End:         showtime.EndTime,
+		Summary:     showtime.Movie.Title,
+		Location:    showtime.Hall.Name,
+		Description: fmt.Sprintf("Booking #%d", booking.ID),
+		Cancelled:   cancelled,
+	}
+}
+
+// sampleBookingEmail returns made-up booking data for template previews
+func sampleBookingEmail() *bookingEmail {
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	return &bookingEmail{
+		Name:      "Alex Doe",
+		BookingID: 1042,
+		Movie:     "The Grand Premiere",
+		Hall:      "Hall 1",
+		StartTime: start,
+		Seats:     []string{"F7", "F8"},
+		Tickets: []emailTicket{
+			{Seat: "F7", Code: "SAMPLE-F7"},
+			{Seat: "F8", Code: "SAMPLE-F8"},
+		},
+		Total: 24.00,
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"movie-ticket-booking/internal/jobs"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of background jobs run by the reminder service
+const (
+	JobBookingReminder = "booking_reminder"
+	JobRatingRequest   = "rating_request"
+)
+
+// bookingJob is the payload of jobs about a booking
+type bookingJob struct {
+	BookingID uint `json:"booking_id"`
+}
+
+// ReminderService reminds customers of their show and asks them to rate the
+// movie afterwards, through jobs scheduled when they book
+type ReminderService struct {
+	scheduler     *jobs.Scheduler
+	notifications *NotificationService
+	remindBefore  time.Duration
+	ratingDelay   time.Duration
+	ratingURL     string
+}
+
+func NewReminderService(scheduler *jobs.Scheduler, notifications *NotificationService, remindBefore, ratingDelay time.Duration, ratingURL string) *ReminderService {
+	s := &ReminderService{
+		scheduler:     scheduler,
+		notifications: notifications,
+		remindBefore:  remindBefore,
+		ratingDelay:   ratingDelay,
+		ratingURL:     ratingURL,
+	}
+	scheduler.Register(JobBookingReminder, s.sendReminder)
+	scheduler.Register(JobRatingRequest, s.sendRatingRequest)
+	return s
+}
+
+// Schedule plans the reminder and rating request of a new booking within tx.
+// Bookings made less than the reminder period before the show get no reminder.
+func (s *ReminderService) Schedule(tx *gorm.DB, booking *models.Booking, showtime *models.ShowTime) error {
+	payload := bookingJob{BookingID: booking.ID}
+	if remindAt := showtime.StartTime.Add(-s.remindBefore); remindAt.After(time.Now()) {
+		key := fmt.Sprintf("%s:%d", JobBookingReminder, booking.ID)
+		if err := s.scheduler.Schedule(tx, JobBookingReminder, key, payload, remindAt); err != nil {
+			return err
+		}
+	}
+	key := fmt.Sprintf("%s:%d", JobRatingRequest, booking.ID)
+	return s.scheduler.Schedule(tx, JobRatingRequest, key, payload, showtime.EndTime.Add(s.ratingDelay))
+}
+
+func (s *ReminderService) sendReminder(ctx context.Context, tx *gorm.DB, payload []byte) error {
+	booking, err := loadJobBooking(tx, payload)
+	if err != nil || booking == nil {
+		return err
+	}
+	showtime := &booking.Showtime
+	seats := make([]string, len(booking.Seats))
+	for i, bookingSeat := range booking.Seats {
+		seats[i] = seatName(&bookingSeat.Seat)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nA reminder that %s starts %s in %s.\n\n",
+		booking.User.Name, showtime.Movie.Title, showtime.StartTime.Format("Mon 2 Jan at 15:04"), showtime.Hall.Name)
+	fmt.Fprintf(&body, "Booking #%d, seats %s.\n\nEnjoy the show!\n", booking.ID, strings.Join(seats, ", "))
+	return s.notifications.Enqueue(tx, &booking.UserID, notification.Message{
+		To:      booking.User.Email,
+		Subject: fmt.Sprintf("Reminder: %s at %s", showtime.Movie.Title, showtime.StartTime.Format("15:04")),
+		Body:    body.String(),
+	})
+}
+
+func (s *ReminderService) sendRatingRequest(ctx context.Context, tx *gorm.DB, payload []byte) error {
+	booking, err := loadJobBooking(tx, payload)
+	if err != nil || booking == nil {
+		return err
+	}
+	movie := &booking.Showtime.Movie
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nThanks for watching %s with us. How did you like it?\n\n", booking.User.Name, movie.Title)
+	fmt.Fprintf(&body, "Rate the movie: %s?movie=%d&booking=%d\n", s.ratingURL, movie.ID, booking.ID)
+	return s.notifications.Enqueue(tx, &booking.UserID, notification.Message{
+		To:      booking.User.Email,
+		Subject: fmt.Sprintf("How was %s?", movie.Title),
+		Body:    body.String(),
+	})
+}
+
+// loadJobBooking loads the booking a job is about with its customer, showtime
+// and seats. It returns nil if the booking is no longer confirmed or its
+// showtime was cancelled, so there's nothing to send.
+func loadJobBooking(tx *gorm.DB, payload []byte) (*models.Booking, error) {
+	var job bookingJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, fmt.Errorf("invalid booking job payload: %w", err)
+	}
+
+	var booking models.Booking
+	err := tx.Preload("User").Preload("Showtime.Movie").Preload("Showtime.Hall").Preload("Seats.Seat").
+		First(&booking, job.BookingID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusConfirmed || booking.Showtime.Status == models.ShowTimeStatusCancelled {
+		return nil, nil
+	}
+	return &booking, nil
+}
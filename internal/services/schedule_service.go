@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxScheduleDays limits how far a single schedule can reach
+const maxScheduleDays = 92
+
+var weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// ScheduleRule describes a recurring programme: the movie plays in the hall
+// at each of Times on the selected weekdays between StartDate and EndDate
+type ScheduleRule struct {
+	MovieID   uint
+	HallID    uint
+	StartDate time.Time // dates are interpreted in the cinema's timezone
+	EndDate   time.Time
+	Weekdays  []time.Weekday
+	Times     []string // "15:04"
+	Price     float64
+}
+
+// ScheduleOccurrence is a showtime a schedule would generate. Conflict
+// explains why it cannot be scheduled, if so.
+type ScheduleOccurrence struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Conflict  string
+}
+
+// ScheduleResult summarises the showtimes touched by a schedule change
+type ScheduleResult struct {
+	Schedule *models.ShowtimeSchedule
+	Created  []*models.ShowTime
+	Kept     []*models.ShowTime // booked shows left untouched
+	Removed  int
+	Skipped  []ScheduleOccurrence
+}
+
+// ScheduleService manages recurring schedules and the showtimes they generate
+type ScheduleService struct {
+	db              *gorm.DB
+	showtimeService *ShowtimeService
+	location        *time.Location // the cinema's timezone
+}
+
+func NewScheduleService(db *gorm.DB, showtimeService *ShowtimeService, location *time.Location) *ScheduleService {
+	return &ScheduleService{
+		db:              db,
+		showtimeService: showtimeService,
+		location:        location,
+	}
+}
+
+// Location returns the timezone schedule dates and times are interpreted in
+func (s *ScheduleService) Location() *time.Location {
+	return s.location
+}
+
+// GetSchedules returns the active schedules
+func (s *ScheduleService) GetSchedules(ctx context.Context) ([]*models.ShowtimeSchedule, error) {
+	var schedules []*models.ShowtimeSchedule
+	err := s.db.WithContext(ctx).Preload("Movie").Preload("Hall").
+		Where("status = ?", models.ScheduleStatusActive).
+		Order("start_date, id").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// PreviewSchedule returns the showtimes a rule would generate, with conflicts
+// against the current programme, without saving anything
+func (s *ScheduleService) PreviewSchedule(ctx context.Context, rule ScheduleRule) ([]ScheduleOccurrence, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	var occurrences []ScheduleOccurrence
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		movie, _, err := s.showtimeService.loadMovieAndHall(tx, rule.MovieID, rule.HallID)
+		if err != nil {
+			return err
+		}
+		occurrences, err = s.plan(tx, rule, movie, time.Time{}, nil)
+		return err
+	})
+	return occurrences, err
+}
+
+// CreateSchedule saves a schedule and generates its showtimes. Conflicting
+// occurrences fail the whole operation unless skipConflicts is set.
+func (s *ScheduleService) CreateSchedule(ctx context.Context, rule ScheduleRule, skipConflicts bool) (*ScheduleResult, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	result := &ScheduleResult{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		schedule := &models.ShowtimeSchedule{Status: models.ScheduleStatusActive}
+		rule.apply(schedule)
+		if err := tx.Create(schedule).Error; err != nil {
+			return err
+		}
+		result.Schedule = schedule
+		return s.generate(tx, schedule, rule, time.Time{}, nil, skipConflicts, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, s.reload(ctx, result)
+}
+
+// UpdateSchedule replaces the rule of a schedule. Future showtimes of the
+// series without bookings are cancelled and regenerated from the new rule;
+// showtimes that already have bookings are left untouched.
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, id uint, rule ScheduleRule, skipConflicts bool) (*ScheduleResult, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	result := &ScheduleResult{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		schedule, err := lockSchedule(tx, id)
+		if err != nil {
+			return err
+		}
+		result.Schedule = schedule
+
+		if err := s.clearSeries(tx, schedule, result); err != nil {
+			return err
+		}
+
+		rule.apply(schedule)
+		if err := tx.Omit(clause.Associations).Save(schedule).Error; err != nil {
+			return err
+		}
+
+		// Past occurrences are history; booked shows at the same time in the
+		// same hall already cover an occurrence
+		covered := make(map[int64]bool)
+		for _, kept := range result.Kept {
+			if kept.HallID == rule.HallID {
+				covered[kept.StartTime.Unix()] = true
+			}
+		}
+		return s.generate(tx, schedule, rule, time.Now(), covered, skipConflicts, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, s.reload(ctx, result)
+}
+
+// RemoveSchedule cancels the future showtimes of a series that have no
+// bookings and deactivates the schedule
+func (s *ScheduleService) RemoveSchedule(ctx context.Context, id uint) (*ScheduleResult, error) {
+	result := &ScheduleResult{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		schedule, err := lockSchedule(tx, id)
+		if err != nil {
+			return err
+		}
+		result.Schedule = schedule
+
+		if err := s.clearSeries(tx, schedule, result); err != nil {
+			return err
+		}
+		schedule.Status = models.ScheduleStatusRemoved
+		return tx.Model(schedule).Update("status", schedule.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, s.reload(ctx, result)
+}
+
+// clearSeries cancels the future showtimes of a schedule without bookings and
+// collects the booked ones in result.Kept
+func (s *ScheduleService) clearSeries(tx *gorm.DB, schedule *models.ShowtimeSchedule, result *ScheduleResult) error {
+	var series []*models.ShowTime
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("schedule_id = ? AND status = ? AND start_time > ?", schedule.ID, models.ShowTimeStatusScheduled, time.Now()).
+		Order("start_time").
+		Find(&series).Error; err != nil {
+		return err
+	}
+
+	for _, showtime := range series {
+		var bookings int64
+		if err := tx.Model(&models.Booking{}).
+			Where("show_time_id = ? AND status = ?", showtime.ID, models.BookingStatusConfirmed).
+			Count(&bookings).Error; err != nil {
+			return err
+		}
+		if bookings > 0 {
+			result.Kept = append(result.Kept, showtime)
+			continue
+		}
+		if err := tx.Model(showtime).Update("status", models.ShowTimeStatusCancelled).Error; err != nil {
+			return err
+		}
+		result.Removed++
+	}
+	return nil
+}
+
+// generate creates the showtimes of a schedule from its rule
+func (s *ScheduleService) generate(tx *gorm.DB, schedule *models.ShowtimeSchedule, rule ScheduleRule, since time.Time, covered map[int64]bool, skipConflicts bool, result *ScheduleResult) error {
+	movie, hall, err := s.showtimeService.loadMovieAndHall(tx, rule.MovieID, rule.HallID)
+	if err != nil {
+		return err
+	}
+	occurrences, err := s.plan(tx, rule, movie, since, covered)
+	if err != nil {
+		return err
+	}
+
+	for _, occurrence := range occurrences {
+		if occurrence.Conflict != "" {
+			result.Skipped = append(result.Skipped, occurrence)
+		}
+	}
+	if len(result.Skipped) > 0 && !skipConflicts {
+		first := result.Skipped[0]
+		return apperrors.Conflict("%d showtimes conflict with the programme, first at %s: %s",
+			len(result.Skipped), first.StartTime.Format(time.RFC3339), first.Conflict)
+	}
+
+	for _, occurrence := range occurrences {
+		if occurrence.Conflict != "" {
+			continue
+		}
+		showtime := &models.ShowTime{
+			MovieID:    movie.ID,
+			HallID:     hall.ID,
+			StartTime:  occurrence.StartTime,
+			EndTime:    occurrence.EndTime,
+			Price:      rule.Price,
+			Status:     models.ShowTimeStatusScheduled,
+			ScheduleID: &schedule.ID,
+		}
+		if err := tx.Create(showtime).Error; err != nil {
+			return translateOverlap(err)
+		}
+		if err := createSeats(tx, showtime, hall); err != nil {
+			return err
+		}
+		result.Created = append(result.Created, showtime)
+	}
+	return nil
+}
+
+// plan expands a rule into occurrences and marks those that are in the past
+// or overlap scheduled shows in the hall or each other. Occurrences starting
+// before since or at a covered time are left out.
+func (s *ScheduleService) plan(tx *gorm.DB, rule ScheduleRule, movie *models.Movie, since time.Time, covered map[int64]bool) ([]ScheduleOccurrence, error) {
+	var occurrences []ScheduleOccurrence
+	for _, start := range rule.starts(s.location) {
+		if start.Before(since) || covered[start.Unix()] {
+			continue
+		}
+		occurrences = append(occurrences, ScheduleOccurrence{
+			StartTime: start,
+			EndTime:   s.showtimeService.EndTime(movie, start),
+		})
+	}
+	if len(occurrences) == 0 {
+		return nil, nil
+	}
+
+	var existing []*models.ShowTime
+	if err := tx.Preload("Movie").
+		Where("hall_id = ? AND status = ?", rule.HallID, models.ShowTimeStatusScheduled).
+		Where("start_time < ? AND end_time > ?", occurrences[len(occurrences)-1].EndTime, occurrences[0].StartTime).
+		Order("start_time").
+		Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range occurrences {
+		occurrence := &occurrences[i]
+		if !occurrence.StartTime.After(now) {
+			occurrence.Conflict = "starts in the past"
+			continue
+		}
+		for _, other := range existing {
+			if other.StartTime.Before(occurrence.EndTime) && other.EndTime.After(occurrence.StartTime) {
+				occurrence.Conflict = fmt.Sprintf("overlaps %s from %s to %s",
+					other.Movie.Title, other.StartTime.Format(time.RFC3339), other.EndTime.Format(time.RFC3339))
+				break
+			}
+		}
+		if occurrence.Conflict != "" {
+			continue
+		}
+		for _, previous := range occurrences[:i] {
+			if previous.Conflict == "" && previous.EndTime.After(occurrence.StartTime) {
+				occurrence.Conflict = fmt.Sprintf("overlaps the show of this schedule at %s", previous.StartTime.Format(time.RFC3339))
+				break
+			}
+		}
+	}
+	return occurrences, nil
+}
+
+// reload fetches the schedule and showtimes of a result with their associations
+func (s *ScheduleService) reload(ctx context.Context, result *ScheduleResult) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Preload("Movie").Preload("Hall").First(result.Schedule, result.Schedule.ID).Error; err != nil {
+		return err
+	}
+	for _, list := range [][]*models.ShowTime{result.Created, result.Kept} {
+		for _, showtime := range list {
+			if err := s.showtimeService.preload(db).First(showtime, showtime.ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func lockSchedule(tx *gorm.DB, id uint) (*models.ShowtimeSchedule, error) {
+	var schedule models.ShowtimeSchedule
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("schedule not found")
+		}
+		return nil, err
+	}
+	if schedule.Status == models.ScheduleStatusRemoved {
+		return nil, apperrors.Conflict("schedule has been removed")
+	}
+	return &schedule, nil
+}
+
+func (r ScheduleRule) validate() error {
+	if r.Price <= 0 {
+		return apperrors.Validation("price must be positive")
+	}
+	if r.EndDate.Before(r.StartDate) {
+		return apperrors.Validation("end date must not be before start date")
+	}
+	if days := int(r.EndDate.Sub(r.StartDate).Hours()/24) + 1; days > maxScheduleDays {
+		return apperrors.Validation("a schedule can span at most %d days", maxScheduleDays)
+	}
+	if len(r.Weekdays) == 0 {
+		return apperrors.Validation("at least one weekday is required")
+	}
+	if len(r.Times) == 0 {
+		return apperrors.Validation("at least one start time is required")
+	}
+	for _, t := range r.Times {
+		if _, err := time.Parse("15:04", t); err != nil {
+			return apperrors.Validation("invalid start time %q, expected HH:MM", t)
+		}
+	}
+	return nil
+}
+
+// starts returns the start times of all occurrences in chronological order,
+// taking the times as wall clock times in loc
+func (r ScheduleRule) starts(loc *time.Location) []time.Time {
+	days := make(map[time.Weekday]bool)
+	for _, day := range r.Weekdays {
+		days[day] = true
+	}
+
+	var starts []time.Time
+	for date := r.StartDate; !date.After(r.EndDate); date = date.AddDate(0, 0, 1) {
+		if !days[date.Weekday()] {
+			continue
+		}
+		for _, t := range r.Times {
+			clock, _ := time.Parse("15:04", t)
+			starts = append(starts, time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, loc))
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+func (r ScheduleRule) apply(schedule *models.ShowtimeSchedule) {
+	names := make([]string, len(r.Weekdays))
+	for i, day := range r.Weekdays {
+		names[i] = weekdayNames[day]
+	}
+
+	schedule.MovieID = r.MovieID
+	schedule.HallID = r.HallID
+	schedule.StartDate = r.StartDate
+	schedule.EndDate = r.EndDate
+	schedule.Weekdays = strings.Join(names, ",")
+	schedule.Times = strings.Join(r.Times, ",")
+	schedule.Price = r.Price
+}
+
+// ScheduleWeekdays returns the weekdays stored on a schedule
+func ScheduleWeekdays(schedule *models.ShowtimeSchedule) []time.Weekday {
+	var days []time.Weekday
+	for _, name := range strings.Split(schedule.Weekdays, ",") {
+		for day, weekdayName := range weekdayNames {
+			if name == weekdayName {
+				days = append(days, time.Weekday(day))
+			}
+		}
+	}
+	return days
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleStartsUseCinemaTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone database unavailable:", err)
+	}
+
+	// The clocks go forward on 2026-03-29, between the two dates
+	rule := ScheduleRule{
+		StartDate: time.Date(2026, 3, 28, 0, 0, 0, 0, loc),
+		EndDate:   time.Date(2026, 3, 29, 0, 0, 0, 0, loc),
+		Weekdays:  []time.Weekday{time.Saturday, time.Sunday},
+		Times:     []string{"20:00"},
+	}
+	want := []string{"2026-03-28T19:00:00Z", "2026-03-29T18:00:00Z"}
+
+	starts := rule.starts(loc)
+	if len(starts) != len(want) {
+		t.Fatalf("got %d starts, want %d", len(starts), len(want))
+	}
+	for i, start := range starts {
+		if got := start.UTC().Format(time.RFC3339); got != want[i] {
+			t.Errorf("start %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
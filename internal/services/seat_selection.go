@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/seating"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// maxSuggestedSeats is the largest group seats are suggested for
+const maxSuggestedSeats = 10
+
+// maxBookBestSeatsAttempts bounds how many suggestions BookBestSeats tries
+// when other customers keep taking the seats it suggests
+const maxBookBestSeatsAttempts = 20
+
+// SeatSuggestion is a set of free seats suggested for a group
+type SeatSuggestion struct {
+	Seats    []*models.Seat
+	Score    float64
+	Together bool
+}
+
+// SuggestSeats returns up to limit suggestions of count free seats of a
+// showtime, best first. Seats locked by customers who are booking right now
+// count as taken.
+func (s *BookingService) SuggestSeats(ctx context.Context, showtimeID uint, count, limit int, prefs seating.Preferences, accessible bool) ([]SeatSuggestion, error) {
+	if count < 1 || count > maxSuggestedSeats {
+		return nil, apperrors.Validation("seat count must be between 1 and %d", maxSuggestedSeats)
+	}
+
+	var showtime models.ShowTime
+	if err := s.db.WithContext(ctx).First(&showtime, showtimeID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("showtime not found")
+		}
+		return nil, err
+	}
+	if showtime.Status == models.ShowTimeStatusCancelled {
+		return nil, apperrors.Conflict("showtime has been cancelled")
+	}
+	if time.Now().After(showtime.StartTime) {
+		return nil, apperrors.New(apperrors.CodeShowtimeStarted, "showtime has already started")
+	}
+
+	layout, seats, err := s.seatLayout(ctx, s.db.WithContext(ctx), &showtime, accessible)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := seating.Suggest(layout, count, limit, prefs)
+	result := make([]SeatSuggestion, len(suggestions))
+	for i, suggestion := range suggestions {
+		result[i] = SeatSuggestion{Score: suggestion.Score, Together: suggestion.Together}
+		for _, seat := range suggestion.Seats {
+			result[i].Seats = append(result[i].Seats, seats[seat.ID])
+		}
+	}
+	return result, nil
+}
+
+// BookBestSeats books the best free seats of a showtime for count people. If
+// the suggested seats are taken while booking, seats are suggested again from
+// the current state of the hall, skipping the ones already tried, until none
+// are left or maxBookBestSeatsAttempts were tried.
+func (s *BookingService) BookBestSeats(ctx context.Context, userID uint, showtimeID uint, count int, prefs seating.Preferences, accessible bool) (*models.Booking, error) {
+	tried := make(map[string]bool)
+	for len(tried) < maxBookBestSeatsAttempts {
+		suggestions, err := s.SuggestSeats(ctx, showtimeID, count, len(tried)+1, prefs, accessible)
+		if err != nil {
+			return nil, err
+		}
+
+		var seatIDs []uint
+		for _, suggestion := range suggestions {
+			ids := make([]uint, len(suggestion.Seats))
+			for i, seat := range suggestion.Seats {
+				ids[i] = seat.ID
+			}
+			if key := fmt.Sprint(ids); !tried[key] {
+				tried[key] = true
+				seatIDs = ids
+				break
+			}
+		}
+		if seatIDs == nil {
+			break
+		}
+
+		booking, err := s.CreateBooking(ctx, userID, showtimeID, seatIDs, accessible)
+		if code := apperrors.CodeOf(err); code == apperrors.CodeSeatTaken || code == apperrors.CodeOrphanSeat {
+			continue
+		}
+		return booking, err
+	}
+	if len(tried) == 0 {
+		return nil, apperrors.New(apperrors.CodeSeatTaken, "there are not enough free seats for %d people", count)
+	}
+	return nil, apperrors.New(apperrors.CodeSeatTaken, "the suggested seats were just taken, please try again")
+}
+
+// checkOrphanSeats rejects a selection of seats that would leave a single empty
+// seat between taken seats, an aisle or the end of a row, suggesting the
+// nearest selection that doesn't. The requested seats must be locked by the caller.
+func (s *BookingService) checkOrphanSeats(ctx context.Context, tx *gorm.DB, showtime *models.ShowTime, seatIDs []uint, accessible bool) error {
+	if !s.preventOrphanSeats {
+		return nil
+	}
+	var hall models.Hall
+	if err := tx.First(&hall, showtime.HallID).Error; err != nil {
+		return err
+	}
+	if hall.AllowOrphanSeats {
+		return nil
+	}
+
+	layout, seats, err := s.seatLayout(ctx, tx, showtime, accessible)
+	if err != nil {
+		return err
+	}
+	// The requested seats are locked by this booking, not by someone else
+	requested := make(map[uint]bool, len(seatIDs))
+	for _, id := range seatIDs {
+		requested[id] = true
+	}
+	for i := range layout {
+		if requested[layout[i].ID] {
+			layout[i].Free = true
+		}
+	}
+
+	orphans := seating.Orphans(layout, seatIDs)
+	if len(orphans) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("seat %s would be left empty on its own", seatName(seats[orphans[0].ID]))
+	if alternative := seating.Alternative(layout, seatIDs); alternative != nil {
+		names := make([]string, len(alternative))
+		for i, seat := range alternative {
+			names[i] = seatName(seats[seat.ID])
+		}
+		message += fmt.Sprintf("; try %s instead", strings.Join(names, ", "))
+	} else {
+		message += "; please choose seats next to it"
+	}
+	return apperrors.New(apperrors.CodeOrphanSeat, "%s", message)
+}
+
+// preventsOrphans reports whether selections leaving orphan seats are refused in a hall
+func (s *BookingService) preventsOrphans(hall *models.Hall) bool {
+	return s.preventOrphanSeats && !hall.AllowOrphanSeats
+}
+
+func seatName(seat *models.Seat) string {
+	return fmt.Sprintf("%s%d", seat.RowNumber, seat.SeatNumber)
+}
+
+// checkAccessibleSeats enforces the rules for wheelchair spaces and companion
+// seats until they are released to general sale: they can only be booked by
+// customers with accessibility needs, and companion seats only together with
+// a wheelchair space
+func (s *BookingService) checkAccessibleSeats(showtime *models.ShowTime, seats []*models.Seat, accessible bool) error {
+	wheelchairs, companions := 0, 0
+	for _, seat := range seats {
+		if !s.restricted(seat, showtime) {
+			continue
+		}
+		if !accessible {
+			return apperrors.Validation("seat %s is reserved for customers with accessibility needs", seatName(seat))
+		}
+		switch seat.Kind {
+		case models.SeatKindWheelchair:
+			wheelchairs++
+		case models.SeatKindCompanion:
+			companions++
+		}
+	}
+	if companions > wheelchairs {
+		return apperrors.Validation("companion seats can only be booked together with a wheelchair space")
+	}
+	return nil
+}
+
+// restricted reports whether a seat is still held back for customers with accessibility needs
+func (s *BookingService) restricted(seat *models.Seat, showtime *models.ShowTime) bool {
+	return seat.Kind != models.SeatKindStandard && time.Now().Before(showtime.StartTime.Add(-s.accessibleReleaseBefore))
+}
+
+// seatLayout loads the seats of a showtime as a seating layout, indexed by ID.
+// Seats that are locked in Redis, and accessible seats unless the customer
+// needs them, are not free.
+func (s *BookingService) seatLayout(ctx context.Context, db *gorm.DB, showtime *models.ShowTime, accessible bool) ([]seating.Seat, map[uint]*models.Seat, error) {
+	var seats []*models.Seat
+	if err := db.Where("show_time_id = ?", showtime.ID).Order("row_number, seat_number").Find(&seats).Error; err != nil {
+		return nil, nil, err
+	}
+
+	locked, err := s.lockedSeats(ctx, showtime.ID, seats)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := rowIndexes(seats)
+	layout := make([]seating.Seat, len(seats))
+	byID := make(map[uint]*models.Seat, len(seats))
+	for i, seat := range seats {
+		layout[i] = seating.Seat{
+			ID:     seat.ID,
+			Row:    rows[seat.RowNumber],
+			Number: seat.SeatNumber,
+			Free:   seat.Status == models.SeatStatusAvailable && !locked[seat.ID] && (accessible || !s.restricted(seat, showtime)),
+		}
+		byID[seat.ID] = seat
+	}
+	return layout, byID, nil
+}
+
+// lockedSeats returns the available seats that are locked in Redis by a booking in progress
+func (s *BookingService) lockedSeats(ctx context.Context, showtimeID uint, seats []*models.Seat) (map[uint]bool, error) {
+	var ids []uint
+	var keys []string
+	for _, seat := range seats {
+		if seat.Status == models.SeatStatusAvailable {
+			ids = append(ids, seat.ID)
+			keys = append(keys, fmt.Sprintf("seat_lock:%d:%d", showtimeID, seat.ID))
+		}
+	}
+	locked := make(map[uint]bool)
+	if len(keys) == 0 {
+		return locked, nil
+	}
+
+	values, err := s.redisClient.MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("error checking seat locks: %w", err)
+	}
+	for i, value := range values {
+		if value != nil {
+			locked[ids[i]] = true
+		}
+	}
+	return locked, nil
+}
+
+// rowIndexes numbers the rows from the screen: A, B, ..., Z, AA, AB, ...
+func rowIndexes(seats []*models.Seat) map[string]int {
+	var names []string
+	seen := make(map[string]bool)
+	for _, seat := range seats {
+		if !seen[seat.RowNumber] {
+			seen[seat.RowNumber] = true
+			names = append(names, seat.RowNumber)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	indexes := make(map[string]int, len(names))
+	for i, name := range names {
+		indexes[name] = i
+	}
+	return indexes
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/database/redistest"
+	"movie-ticket-booking/internal/models"
+)
+
+func TestCheckAccessibleSeats(t *testing.T) {
+	// Accessible seats go on general sale two hours before the show
+	bookings := &BookingService{accessibleReleaseBefore: 2 * time.Hour}
+	later := &models.ShowTime{StartTime: time.Now().Add(24 * time.Hour)}
+	soon := &models.ShowTime{StartTime: time.Now().Add(time.Hour)}
+
+	seat := func(kind string) *models.Seat {
+		return &models.Seat{RowNumber: "A", SeatNumber: 1, Kind: kind}
+	}
+	wheelchair, companion, standard := seat(models.SeatKindWheelchair), seat(models.SeatKindCompanion), seat(models.SeatKindStandard)
+
+	tests := []struct {
+		name       string
+		showtime   *models.ShowTime
+		seats      []*models.Seat
+		accessible bool
+		wantErr    bool
+	}{
+		{"standard seat", later, []*models.Seat{standard}, false, false},
+		{"wheelchair space without accessibility needs", later, []*models.Seat{wheelchair}, false, true},
+		{"companion seat without accessibility needs", later, []*models.Seat{wheelchair, companion}, false, true},
+		{"wheelchair space and companion seat", later, []*models.Seat{wheelchair, companion}, true, false},
+		{"companion seat without a wheelchair space", later, []*models.Seat{companion}, true, true},
+		{"more companion seats than wheelchair spaces", later, []*models.Seat{wheelchair, companion, companion}, true, true},
+		{"wheelchair space after the release", soon, []*models.Seat{wheelchair}, false, false},
+		{"companion seat alone after the release", soon, []*models.Seat{companion}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := bookings.checkAccessibleSeats(tt.showtime, tt.seats, tt.accessible)
+			if tt.wantErr && apperrors.CodeOf(err) != apperrors.CodeValidation {
+				t.Errorf("got %v, want a validation error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("got %v, want the seats accepted", err)
+			}
+		})
+	}
+}
+
+func TestSeatLayoutHoldsBackAccessibleSeats(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	bookings := newTestBookingService(t, db, redistest.New(t), &testGateway{})
+	bookings.accessibleReleaseBefore = 2 * time.Hour
+
+	movie := createTestMovie(t, db)
+	showtime := createTestShowtime(t, db, movie, time.Now().Add(24*time.Hour), 10, "WC..")
+	restricted := seatIDs(t, db, showtime, "A1", "A2")
+
+	free := func(accessible bool) map[uint]bool {
+		t.Helper()
+		layout, _, err := bookings.seatLayout(ctx, db, showtime, accessible)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[uint]bool)
+		for _, seat := range layout {
+			result[seat.ID] = seat.Free
+		}
+		return result
+	}
+
+	others := free(false)
+	for _, id := range restricted {
+		if others[id] {
+			t.Errorf("accessible seat %d is free for customers without accessibility needs", id)
+		}
+	}
+	for _, id := range seatIDs(t, db, showtime, "A3", "A4") {
+		if !others[id] {
+			t.Errorf("standard seat %d is not free", id)
+		}
+	}
+	for _, id := range restricted {
+		if !free(true)[id] {
+			t.Errorf("accessible seat %d is not free for customers who need it", id)
+		}
+	}
+
+	// Once released they are on general sale
+	bookings.accessibleReleaseBefore = 48 * time.Hour
+	for _, id := range restricted {
+		if !free(false)[id] {
+			t.Errorf("released accessible seat %d is not free", id)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// exclusionViolation is the Postgres error code raised by show_times_no_overlap
+const exclusionViolation = "23P01"
+
+// ShowtimeService schedules showtimes and keeps halls free of overlapping shows
+type ShowtimeService struct {
+	db              *gorm.DB
+	bookingService  *BookingService
+	trailerDuration time.Duration
+	cleaningBuffer  time.Duration
+}
+
+func NewShowtimeService(db *gorm.DB, bookingService *BookingService, trailerDuration, cleaningBuffer time.Duration) *ShowtimeService {
+	return &ShowtimeService{
+		db:              db,
+		bookingService:  bookingService,
+		trailerDuration: trailerDuration,
+		cleaningBuffer:  cleaningBuffer,
+	}
+}
+
+// ShowtimeChanges holds the fields of a showtime to update; nil fields are kept
+type ShowtimeChanges struct {
+	MovieID   *uint
+	HallID    *uint
+	StartTime *time.Time
+	Price     *float64
+}
+
+// EndTime returns when the hall is free again after a show of the movie
+// starting at start: trailers, the movie itself and the cleaning buffer
+func (s *ShowtimeService) EndTime(movie *models.Movie, start time.Time) time.Time {
+	return start.Add(s.trailerDuration + time.Duration(movie.Duration)*time.Minute + s.cleaningBuffer)
+}
+
+// GetShowtimes returns all scheduled showtimes ordered by start time
+func (s *ShowtimeService) GetShowtimes(ctx context.Context) ([]*models.ShowTime, error) {
+	return s.find(ctx, s.db.Where("status = ?", models.ShowTimeStatusScheduled))
+}
+
+// GetMovieShowtimes returns the scheduled showtimes of a movie
+func (s *ShowtimeService) GetMovieShowtimes(ctx context.Context, movieID uint) ([]*models.ShowTime, error) {
+	return s.find(ctx, s.db.Where("movie_id = ? AND status = ?", movieID, models.ShowTimeStatusScheduled))
+}
+
+// GetShowtime retrieves a showtime by ID
+func (s *ShowtimeService) GetShowtime(ctx context.Context, id uint) (*models.ShowTime, error) {
+	var showtime models.ShowTime
+	if err := s.preload(s.db.WithContext(ctx)).First(&showtime, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("showtime not found")
+		}
+		return nil, err
+	}
+	return &showtime, nil
+}
+
+// CreateShowtime schedules a movie in a hall and creates its seats from the hall layout
+func (s *ShowtimeService) CreateShowtime(ctx context.Context, movieID, hallID uint, start time.Time, price float64) (*models.ShowTime, error) {
+	if price <= 0 {
+		return nil, apperrors.Validation("price must be positive")
+	}
+	if !start.After(time.Now()) {
+		return nil, apperrors.Validation("start time must be in the future")
+	}
+
+	var showtime *models.ShowTime
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		movie, hall, err := s.loadMovieAndHall(tx, movieID, hallID)
+		if err != nil {
+			return err
+		}
+
+		showtime = &models.ShowTime{
+			MovieID:   movie.ID,
+			HallID:    hall.ID,
+			StartTime: start,
+			EndTime:   s.EndTime(movie, start),
+			Price:     price,
+			Status:    models.ShowTimeStatusScheduled,
+		}
+		if err := checkOverlap(tx, showtime); err != nil {
+			return err
+		}
+		if err := tx.Create(showtime).Error; err != nil {
+			return translateOverlap(err)
+		}
+		return createSeats(tx, showtime, hall)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetShowtime(ctx, showtime.ID)
+}
+
+// UpdateShowtime changes a showtime that hasn't started yet. The movie, hall
+// and start time are fixed once tickets have been sold; the price only
+// applies to new bookings.
+func (s *ShowtimeService) UpdateShowtime(ctx context.Context, id uint, changes ShowtimeChanges) (*models.ShowTime, error) {
+	if changes.Price != nil && *changes.Price <= 0 {
+		return nil, apperrors.Validation("price must be positive")
+	}
+	if changes.StartTime != nil && !changes.StartTime.After(time.Now()) {
+		return nil, apperrors.Validation("start time must be in the future")
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		showtime, err := lockShowtime(tx, id)
+		if err != nil {
+			return err
+		}
+
+		hallChange := changes.HallID != nil && *changes.HallID != showtime.HallID
+		reschedule := hallChange ||
+			(changes.MovieID != nil && *changes.MovieID != showtime.MovieID) ||
+			(changes.StartTime != nil && !changes.StartTime.Equal(showtime.StartTime))
+		if reschedule {
+			if err := requireNoBookings(tx, showtime.ID); err != nil {
+				return err
+			}
+		}
+		// Replacing the seats would delete the booked seats and tickets of
+		// cancelled and expired bookings too
+		if hallChange {
+			if err := requireNoBookingHistory(tx, showtime.ID); err != nil {
+				return err
+			}
+		}
+
+		previousHallID := showtime.HallID
+		if changes.MovieID != nil {
+			showtime.MovieID = *changes.MovieID
+		}
+		if changes.HallID != nil {
+			showtime.HallID = *changes.HallID
+		}
+		if changes.StartTime != nil {
+			showtime.StartTime = *changes.StartTime
+		}
+		if changes.Price != nil {
+			showtime.Price = *changes.Price
+		}
+
+		movie, hall, err := s.loadMovieAndHall(tx, showtime.MovieID, showtime.HallID)
+		if err != nil {
+			return err
+		}
+		showtime.EndTime = s.EndTime(movie, showtime.StartTime)
+
+		if err := checkOverlap(tx, showtime); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(showtime).Error; err != nil {
+			return translateOverlap(err)
+		}
+
+		// Seats follow the layout of the new hall
+		if showtime.HallID != previousHallID {
+			if err := tx.Unscoped().Where("show_time_id = ?", showtime.ID).Delete(&models.Seat{}).Error; err != nil {
+				return err
+			}
+			return createSeats(tx, showtime, hall)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetShowtime(ctx, id)
+}
+
+// CancelShowtime takes a showtime off the programme. Its bookings are
+// cancelled and refunded in the background, and the customers are offered an
+// exchange; the booking recovery worker finishes the job if it is interrupted.
+func (s *ShowtimeService) CancelShowtime(ctx context.Context, id uint, reason string) (*models.ShowTime, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, apperrors.Validation("a cancellation reason is required")
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		showtime, err := lockShowtime(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(showtime).Updates(map[string]interface{}{
+			"status":              models.ShowTimeStatusCancelled,
+			"cancellation_reason": reason,
+			"cancelled_at":        time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return events.Record(tx, events.ShowtimeCancelled{ShowtimeID: showtime.ID, Reason: reason})
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.ShowtimesCancelled.Inc()
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := s.bookingService.CancelShowtimeBookings(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "failed to cancel bookings of cancelled showtime, recovery will retry", "showtime_id", id, "error", err)
+		}
+	}()
+
+	return s.GetShowtime(ctx, id)
+}
+
+// SetAllowOrphanSeats turns the rule against leaving single empty seats off or on for a hall
+func (s *ShowtimeService) SetAllowOrphanSeats(ctx context.Context, hallID uint, allow bool) (*models.Hall, error) {
+	var hall models.Hall
+	if err := s.db.WithContext(ctx).First(&hall, hallID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("hall not found")
+		}
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&hall).Update("allow_orphan_seats", allow).Error; err != nil {
+		return nil, err
+	}
+	hall.AllowOrphanSeats = allow
+	return &hall, nil
+}
+
+// SeatPosition identifies a seat of a hall layout
+type SeatPosition struct {
+	Row    string
+	Number int
+}
+
+// SetHallSeatKind marks seats of a hall as wheelchair spaces, companion seats
+// or standard seats. Showtimes that haven't started yet follow the new layout.
+func (s *ShowtimeService) SetHallSeatKind(ctx context.Context, hallID uint, positions []SeatPosition, kind string) (*models.Hall, error) {
+	switch kind {
+	case models.SeatKindStandard, models.SeatKindWheelchair, models.SeatKindCompanion:
+	default:
+		return nil, apperrors.Validation("invalid seat kind: %s", kind)
+	}
+	if len(positions) == 0 {
+		return nil, apperrors.Validation("at least one seat is required")
+	}
+
+	var hall models.Hall
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&hall, hallID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return apperrors.NotFound("hall not found")
+			}
+			return err
+		}
+
+		for _, position := range positions {
+			result := tx.Model(&models.HallSeat{}).
+				Where("hall_id = ? AND row_number = ? AND seat_number = ?", hallID, position.Row, position.Number).
+				Update("kind", kind)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return apperrors.NotFound("seat %s%d not found in hall", position.Row, position.Number)
+			}
+
+			err := tx.Model(&models.Seat{}).
+				Where("row_number = ? AND seat_number = ?", position.Row, position.Number).
+				Where("show_time_id IN (?)", tx.Model(&models.ShowTime{}).Select("id").
+					Where("hall_id = ? AND status = ? AND start_time > ?", hallID, models.ShowTimeStatusScheduled, time.Now())).
+				Update("kind", kind).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hall, nil
+}
+
+func (s *ShowtimeService) find(ctx context.Context, query *gorm.DB) ([]*models.ShowTime, error) {
+	var showtimes []*models.ShowTime
+	if err := s.preload(query.WithContext(ctx)).Order("start_time").Find(&showtimes).Error; err != nil {
+		return nil, err
+	}
+	return showtimes, nil
+}
+
+func (s *ShowtimeService) preload(db *gorm.DB) *gorm.DB {
+	return db.Preload("Movie").Preload("Hall").Preload("Seats", func(db *gorm.DB) *gorm.DB {
+		return db.Order("row_number, seat_number")
+	})
+}
+
+func (s *ShowtimeService) loadMovieAndHall(tx *gorm.DB, movieID, hallID uint) (*models.Movie, *models.Hall, error) {
+	var movie models.Movie
+	if err := tx.First(&movie, movieID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, apperrors.NotFound("movie not found")
+		}
+		return nil, nil, err
+	}
+
+	// Locking the hall serialises scheduling in it so the overlap check holds
+	var hall models.Hall
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hall, hallID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, apperrors.NotFound("hall not found")
+		}
+		return nil, nil, err
+	}
+	if err := tx.Where("hall_id = ?", hall.ID).Order("row_number, seat_number").Find(&hall.Layout).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(hall.Layout) == 0 {
+		return nil, nil, apperrors.Validation("hall %s has no seat layout", hall.Name)
+	}
+	return &movie, &hall, nil
+}
+
+// lockShowtime loads a showtime for update and checks it can still be changed
+func lockShowtime(tx *gorm.DB, id uint) (*models.ShowTime, error) {
+	var showtime models.ShowTime
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&showtime, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("showtime not found")
+		}
+		return nil, err
+	}
+	if showtime.Status == models.ShowTimeStatusCancelled {
+		return nil, apperrors.Conflict("showtime has been cancelled")
+	}
+	if !showtime.StartTime.After(time.Now()) {
+		return nil, apperrors.New(apperrors.CodeShowtimeStarted, "showtime has already started")
+	}
+	return &showtime, nil
+}
+
+func requireNoBookings(tx *gorm.DB, showtimeID uint) error {
+	var count int64
+	if err := tx.Model(&models.Booking{}).
+		Where("show_time_id = ? AND status = ?", showtimeID, models.BookingStatusConfirmed).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.Conflict("showtime already has %d confirmed bookings", count)
+	}
+	return nil
+}
+
+// requireNoBookingHistory refuses changes that would remove the seats of a
+// showtime while any booking, whatever its status, still refers to them
+func requireNoBookingHistory(tx *gorm.DB, showtimeID uint) error {
+	var count int64
+	if err := tx.Unscoped().Model(&models.Booking{}).
+		Where("show_time_id = ?", showtimeID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.Conflict("showtime has %d bookings; cancel it and schedule a new one in the other hall", count)
+	}
+	return nil
+}
+
+// checkOverlap rejects a showtime that overlaps another scheduled show in the same hall
+func checkOverlap(tx *gorm.DB, showtime *models.ShowTime) error {
+	var conflict models.ShowTime
+	err := tx.Preload("Movie").
+		Where("hall_id = ? AND status = ? AND id <> ?", showtime.HallID, models.ShowTimeStatusScheduled, showtime.ID).
+		Where("start_time < ? AND end_time > ?", showtime.EndTime, showtime.StartTime).
+		First(&conflict).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return apperrors.Conflict("hall is already booked from %s to %s for %s",
+		conflict.StartTime.Format(time.RFC3339), conflict.EndTime.Format(time.RFC3339), conflict.Movie.Title)
+}
+
+// translateOverlap reports violations of the database overlap constraint as a conflict
+func translateOverlap(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == exclusionViolation {
+		return apperrors.Conflict("showtime overlaps another show in the same hall")
+	}
+	return err
+}
+
+// createSeats copies the hall layout into the seats of a showtime
+func createSeats(tx *gorm.DB, showtime *models.ShowTime, hall *models.Hall) error {
+	seats := make([]models.Seat, len(hall.Layout))
+	for i, hallSeat := range hall.Layout {
+		seats[i] = models.Seat{
+			HallID:     hall.ID,
+			ShowTimeID: showtime.ID,
+			RowNumber:  hallSeat.RowNumber,
+			SeatNumber: hallSeat.SeatNumber,
+			Kind:       hallSeat.Kind,
+			Status:     models.SeatStatusAvailable,
+		}
+	}
+	return tx.CreateInBatches(seats, 500).Error
+}
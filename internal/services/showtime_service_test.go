@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/database/redistest"
+	"movie-ticket-booking/internal/models"
+)
+
+func TestInterruptedShowtimeCancellationIsResumed(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	redisClient := redistest.New(t)
+	gateway := &testGateway{}
+	bookings := newTestBookingService(t, db, redisClient, gateway)
+	showtimes := NewShowtimeService(db, bookings, 15*time.Minute, 15*time.Minute)
+
+	movie := createTestMovie(t, db)
+	showtime := createTestShowtime(t, db, movie, time.Now().Add(72*time.Hour), 12.5, "......")
+	const customers = 5
+	booked := make([]*models.Booking, customers)
+	for i := range booked {
+		user := createTestUser(t, db, fmt.Sprintf("customer%d", i+1))
+		booking, err := bookings.CreateBooking(ctx, user.ID, showtime.ID, seatIDs(t, db, showtime, fmt.Sprintf("A%d", i+1)), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		booked[i] = booking
+	}
+
+	// The refund of the second booking fails and the server starts shutting
+	// down while it is handled, leaving the rest of the bookings untouched
+	interrupted := make(chan struct{})
+	gateway.setOnRefund(func(attempt int) error {
+		if attempt != 2 {
+			return nil
+		}
+		bookings.mu.Lock()
+		bookings.draining = true
+		bookings.mu.Unlock()
+		close(interrupted)
+		return errGatewayUnavailable
+	})
+	if _, err := showtimes.CancelShowtime(ctx, showtime.ID, "Projector broken"); err != nil {
+		t.Fatal(err)
+	}
+	<-interrupted
+	if err := bookings.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var cancelled int64
+	if err := db.Model(&models.Booking{}).Where("status = ?", models.BookingStatusCancelled).Count(&cancelled).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cancelled != 2 {
+		t.Fatalf("%d bookings cancelled before the interruption, want 2", cancelled)
+	}
+	if got := len(gateway.refunded()); got != 1 {
+		t.Fatalf("%d refunds paid before the interruption, want 1", got)
+	}
+
+	// After a restart the recovery worker finishes the job; running it again changes nothing
+	gateway.setOnRefund(nil)
+	restarted := newTestBookingService(t, db, redisClient, gateway)
+	for i := 0; i < 2; i++ {
+		if err := restarted.recover(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refundsPaid := make(map[uint]int)
+	for _, refund := range gateway.refunded() {
+		var bookingID uint
+		if _, err := fmt.Sscanf(refund.key, "booking-%d-refund-", &bookingID); err != nil {
+			t.Fatalf("unexpected refund key %q", refund.key)
+		}
+		refundsPaid[bookingID]++
+	}
+	for _, original := range booked {
+		booking := reloadBooking(t, db, original.ID)
+		if booking.Status != models.BookingStatusCancelled || booking.CancelReason != models.CancelReasonShowtimeCancelled {
+			t.Errorf("booking %d: status %s, reason %q", booking.ID, booking.Status, booking.CancelReason)
+		}
+
+		refunds := bookingPayments(t, db, booking.ID, models.PaymentKindRefund)
+		if len(refunds) != 1 || refunds[0].Status != models.PaymentStatusSucceeded || refunds[0].Amount != original.TotalAmount {
+			t.Errorf("booking %d: refunds %+v, want one of %.2f", booking.ID, refunds, original.TotalAmount)
+		}
+		if refundsPaid[booking.ID] != 1 {
+			t.Errorf("booking %d refunded %d times by the gateway, want once", booking.ID, refundsPaid[booking.ID])
+		}
+
+		var notified int64
+		if err := db.Model(&models.Notification{}).Where("user_id = ?", booking.UserID).Count(&notified).Error; err != nil {
+			t.Fatal(err)
+		}
+		if notified != 1 {
+			t.Errorf("customer of booking %d notified %d times, want once", booking.ID, notified)
+		}
+	}
+}
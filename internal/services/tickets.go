@@ -0,0 +1,59 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"movie-ticket-booking/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// issueTickets creates a ticket with a new code for every seat of a booking
+// loaded with its seats
+func issueTickets(tx *gorm.DB, booking *models.Booking) error {
+	if len(booking.Seats) == 0 {
+		return nil
+	}
+	tickets := make([]models.Ticket, len(booking.Seats))
+	for i, bookingSeat := range booking.Seats {
+		code, err := newTicketCode()
+		if err != nil {
+			return err
+		}
+		tickets[i] = models.Ticket{
+			UserID:      booking.UserID,
+			BookingID:   &booking.ID,
+			ShowTimeID:  booking.ShowTimeID,
+			SeatID:      bookingSeat.SeatID,
+			Status:      models.TicketStatusPaid,
+			BookingCode: code,
+			Price:       bookingSeat.Price,
+		}
+	}
+	return tx.Create(&tickets).Error
+}
+
+// cancelTickets voids the valid tickets of a booking
+func cancelTickets(tx *gorm.DB, bookingID uint) error {
+	return tx.Model(&models.Ticket{}).
+		Where("booking_id = ? AND status <> ?", bookingID, models.TicketStatusCancelled).
+		Update("status", models.TicketStatusCancelled).Error
+}
+
+// reissueTickets voids the tickets of a booking and issues new ones for its
+// current seats, so that tickets printed before a change can't be used
+func reissueTickets(tx *gorm.DB, booking *models.Booking) error {
+	if err := cancelTickets(tx, booking.ID); err != nil {
+		return err
+	}
+	return issueTickets(tx, booking)
+}
+
+// newTicketCode returns a random code printed on a ticket and encoded in its QR code
+func newTicketCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// localePattern matches the language tags customers can pick for their emails
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+type UserService struct {
+	db             *gorm.DB
+	notifier       notification.Sender
+	emailChangeTTL time.Duration
+	reauthWindow   time.Duration
+}
+
+func NewUserService(db *gorm.DB, notifier notification.Sender, emailChangeTTL, reauthWindow time.Duration) *UserService {
+	return &UserService{
+		db:             db,
+		notifier:       notifier,
+		emailChangeTTL: emailChangeTTL,
+		reauthWindow:   reauthWindow,
+	}
+}
+
+// GetUser retrieves a user by ID
+func (s *UserService) GetUser(id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateProfile changes the name, phone number and email language of a user
+func (s *UserService) UpdateProfile(userID uint, name, phone string, locale *string) (*models.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.Validation("name is required")
+	}
+	if locale != nil && !localePattern.MatchString(*locale) {
+		return nil, apperrors.Validation("locale must be a language tag such as en or de-AT")
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Name = name
+	user.Phone = strings.TrimSpace(phone)
+	if locale != nil {
+		user.Locale = *locale
+	}
+	if err := s.db.Save(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password of a user after verifying the current
+// one. Social-only accounts set their first password after a recent sign-in.
+func (s *UserService) ChangePassword(userID uint, oldPassword *string, newPassword string, authTime time.Time) error {
+	if len(newPassword) < 8 {
+		return apperrors.Validation("new password must be at least 8 characters")
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reauthenticate(user, oldPassword, authTime); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Model(user).Update("password", string(hashedPassword)).Error
+}
+
+// RequestEmailChange sends a verification token to the new address. The email
+// is only changed once the token is confirmed with ConfirmEmailChange.
+func (s *UserService) RequestEmailChange(ctx context.Context, userID uint, newEmail string, password *string, authTime time.Time) error {
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+	if !strings.Contains(newEmail, "@") {
+		return apperrors.Validation("invalid email address")
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reauthenticate(user, password, authTime); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("email = ?", newEmail).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.Conflict("email is already in use")
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.emailChangeTTL)
+
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"pending_email":           newEmail,
+		"email_change_token":      hashToken(token),
+		"email_change_expires_at": expiresAt,
+	}).Error; err != nil {
+		return err
+	}
+
+	return s.notifier.Send(ctx, notification.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body:    fmt.Sprintf("Hi %s,\n\nUse this code to confirm your new email address: %s\n\nThe code expires on %s.", user.Name, token, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+// ConfirmEmailChange applies a pending email change
+func (s *UserService) ConfirmEmailChange(userID uint, token string) (*models.User, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PendingEmail == "" || user.EmailChangeToken != hashToken(token) {
+		return nil, apperrors.Validation("invalid email confirmation token")
+	}
+	if user.EmailChangeExpiresAt == nil || time.Now().After(*user.EmailChangeExpiresAt) {
+		return nil, apperrors.Validation("email confirmation token has expired")
+	}
+
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"email":                   user.PendingEmail,
+		"email_verified_at":       time.Now(),
+		"pending_email":           "",
+		"email_change_token":      "",
+		"email_change_expires_at": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(userID)
+}
+
+// DeleteAccount anonymises the personal data of a user after confirming it's
+// them. Bookings are kept for accounting and keep pointing at the anonymised
+// user row.
+func (s *UserService) DeleteAccount(userID uint, password *string, authTime time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return apperrors.NotFound("user not found")
+			}
+			return err
+		}
+		if err := s.reauthenticate(&user, password, authTime); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":                   fmt.Sprintf("deleted-user-%d@deleted.invalid", user.ID),
+			"password":                "",
+			"name":                    "Deleted User",
+			"phone":                   "",
+			"pending_email":           "",
+			"email_change_token":      "",
+			"email_change_expires_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Linked social accounts would otherwise allow logging back in
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
+
+// reauthenticate confirms a sensitive change is made by the account owner.
+// Accounts with a password need it; social-only accounts have none and need
+// a sign-in at authTime within the reauthentication window instead.
+func (s *UserService) reauthenticate(user *models.User, password *string, authTime time.Time) error {
+	if !HasPassword(user) {
+		if time.Since(authTime) > s.reauthWindow {
+			return apperrors.Unauthenticated("sign in again to confirm this change")
+		}
+		return nil
+	}
+
+	if password == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(*password)) != nil {
+		return apperrors.Unauthenticated("invalid credentials")
+	}
+	return nil
+}
+
+// HasPassword reports whether a user can sign in with a password. Accounts
+// created by a social login have none until the user sets one.
+func HasPassword(user *models.User) bool {
+	return user.Password != ""
+}
+
+// hashToken returns the hex encoded SHA-256 of a token so that only hashes are stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+)
+
+type discardSender struct{}
+
+func (discardSender) Send(context.Context, notification.Message) error { return nil }
+
+func TestDeleteAccountRequiresReauthentication(t *testing.T) {
+	db := dbtest.New(t)
+	auth := NewAuthService(db, "test-secret-that-is-long-enough-for-jwt", time.Hour, 10*time.Minute)
+	users := NewUserService(db, discardSender{}, time.Hour, 5*time.Minute)
+
+	user, err := auth.Register("carol@example.com", "password123", "Carol", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.Login("carol@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := "wrong-password"
+	for _, password := range []*string{nil, &wrong} {
+		err := users.DeleteAccount(user.ID, password, time.Now())
+		if apperrors.CodeOf(err) != apperrors.CodeUnauthenticated {
+			t.Fatalf("deleting with password %v: got %v, want unauthenticated", password, err)
+		}
+	}
+
+	password := "password123"
+	if err := users.DeleteAccount(user.ID, &password, time.Now()); err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if _, err := auth.ValidateToken(token); apperrors.CodeOf(err) != apperrors.CodeUnauthenticated {
+		t.Errorf("token of deleted user: got %v, want unauthenticated", err)
+	}
+}
+
+func TestSocialOnlyAccountReauthenticatesBySigningIn(t *testing.T) {
+	db := dbtest.New(t)
+	users := NewUserService(db, discardSender{}, time.Hour, 5*time.Minute)
+
+	// Social logins create users without a password
+	user := &models.User{Email: "dave@example.com", Name: "Dave"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stale := time.Now().Add(-time.Hour)
+	err := users.ChangePassword(user.ID, nil, "new-password", stale)
+	if apperrors.CodeOf(err) != apperrors.CodeUnauthenticated {
+		t.Fatalf("setting a password after a stale sign-in: got %v, want unauthenticated", err)
+	}
+	err = users.RequestEmailChange(context.Background(), user.ID, "dave@example.org", nil, stale)
+	if apperrors.CodeOf(err) != apperrors.CodeUnauthenticated {
+		t.Fatalf("changing email after a stale sign-in: got %v, want unauthenticated", err)
+	}
+
+	if err := users.ChangePassword(user.ID, nil, "new-password", time.Now()); err != nil {
+		t.Fatalf("setting the first password: %v", err)
+	}
+	updated, err := users.GetUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasPassword(updated) {
+		t.Fatal("user has no password after setting one")
+	}
+
+	// From now on the password is required
+	if err := users.ChangePassword(user.ID, nil, "another-password", time.Now()); apperrors.CodeOf(err) != apperrors.CodeUnauthenticated {
+		t.Errorf("changing the password without the old one: got %v, want unauthenticated", err)
+	}
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/notification"
+	"movie-ticket-booking/internal/seating"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activeWaitlistStatuses are the statuses of entries still in the queue
+var activeWaitlistStatuses = []string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}
+
+// WaitlistPlace is a customer's entry on the waitlist of a showtime
+type WaitlistPlace struct {
+	Entry *models.WaitlistEntry
+	// Position in the queue counting from 1; 0 unless the customer is waiting
+	Position int
+}
+
+// JoinWaitlist puts a customer on the waitlist of a showtime that doesn't have
+// seatCount free seats. Released seats are offered to waiting customers in the
+// order they joined.
+func (s *BookingService) JoinWaitlist(ctx context.Context, userID uint, showtimeID uint, seatCount int) (*WaitlistPlace, error) {
+	if seatCount < 1 || seatCount > maxSuggestedSeats {
+		return nil, apperrors.Validation("seat count must be between 1 and %d", maxSuggestedSeats)
+	}
+
+	var entry models.WaitlistEntry
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var showtime models.ShowTime
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&showtime, showtimeID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return apperrors.NotFound("showtime not found")
+			}
+			return err
+		}
+		if showtime.Status == models.ShowTimeStatusCancelled {
+			return apperrors.Conflict("showtime has been cancelled")
+		}
+		if time.Now().After(showtime.StartTime) {
+			return apperrors.New(apperrors.CodeShowtimeStarted, "showtime has already started")
+		}
+
+		var active int64
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("show_time_id = ? AND user_id = ? AND status IN ?", showtimeID, userID, activeWaitlistStatuses).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return apperrors.Conflict("already on the waitlist for this showtime")
+		}
+
+		// Nobody needs to wait while there are enough seats and no queue
+		var waiting int64
+		if err := tx.Model(&models.WaitlistEntry{}).
+			Where("show_time_id = ? AND status = ?", showtimeID, models.WaitlistStatusWaiting).
+			Count(&waiting).Error; err != nil {
+			return err
+		}
+		if waiting == 0 {
+			layout, _, err := s.seatLayout(ctx, tx, &showtime, false)
+			if err != nil {
+				return err
+			}
+			free := 0
+			for _, seat := range layout {
+				if seat.Free {
+					free++
+				}
+			}
+			if free >= seatCount {
+				return apperrors.Conflict("%d seats are still available; book them directly", free)
+			}
+		}
+
+		entry = models.WaitlistEntry{
+			ShowTimeID: showtimeID,
+			UserID:     userID,
+			SeatCount:  seatCount,
+			Status:     models.WaitlistStatusWaiting,
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.WaitlistEntries.WithLabelValues("joined").Inc()
+
+	return s.waitlistPlace(ctx, &entry)
+}
+
+// GetWaitlistPlace returns the latest waitlist entry of a customer for a
+// showtime, or nil if they never joined its waitlist
+func (s *BookingService) GetWaitlistPlace(ctx context.Context, userID uint, showtimeID uint) (*WaitlistPlace, error) {
+	var entry models.WaitlistEntry
+	err := s.db.WithContext(ctx).Where("show_time_id = ? AND user_id = ?", showtimeID, userID).
+		Order("id DESC").First(&entry).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.waitlistPlace(ctx, &entry)
+}
+
+// LeaveWaitlist takes a customer off the waitlist of a showtime. Seats held
+// for them are offered to the next customer.
+func (s *BookingService) LeaveWaitlist(ctx context.Context, userID uint, showtimeID uint) error {
+	var held bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entry models.WaitlistEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("show_time_id = ? AND user_id = ? AND status IN ?", showtimeID, userID, activeWaitlistStatuses).
+			First(&entry).Error
+		if err == gorm.ErrRecordNotFound {
+			return apperrors.NotFound("not on the waitlist for this showtime")
+		}
+		if err != nil {
+			return err
+		}
+		held = entry.Status == models.WaitlistStatusOffered
+		return closeWaitlistEntry(tx, &entry, models.WaitlistStatusLeft)
+	})
+	if err != nil {
+		return err
+	}
+	metrics.WaitlistEntries.WithLabelValues("left").Inc()
+
+	if held {
+		s.releaseToWaitlist(ctx, showtimeID)
+	}
+	return nil
+}
+
+// waitlistPlace loads the seats held for an entry and its position in the queue
+func (s *BookingService) waitlistPlace(ctx context.Context, entry *models.WaitlistEntry) (*WaitlistPlace, error) {
+	place := &WaitlistPlace{Entry: entry}
+	if entry.Status == models.WaitlistStatusOffered {
+		if err := s.db.WithContext(ctx).Where("held_for_id = ?", entry.ID).
+			Order("row_number, seat_number").Find(&entry.HeldSeats).Error; err != nil {
+			return nil, err
+		}
+	}
+	if entry.Status != models.WaitlistStatusWaiting {
+		return place, nil
+	}
+
+	var ahead int64
+	if err := s.db.WithContext(ctx).Model(&models.WaitlistEntry{}).
+		Where("show_time_id = ? AND status = ? AND id < ?", entry.ShowTimeID, models.WaitlistStatusWaiting, entry.ID).
+		Count(&ahead).Error; err != nil {
+		return nil, err
+	}
+	place.Position = int(ahead) + 1
+	return place, nil
+}
+
+// activeOffer returns the waitlist entry a customer holds seats of a showtime
+// for, or nil if they don't
+func activeOffer(tx *gorm.DB, userID uint, showtimeID uint) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("show_time_id = ? AND user_id = ? AND status = ? AND offer_expires_at > ?",
+			showtimeID, userID, models.WaitlistStatusOffered, time.Now()).
+		First(&entry).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// claimHeldSeats completes the waitlist offer the booked seats were held for,
+// if any. Held seats the customer didn't book are released; it returns whether
+// there were any.
+func claimHeldSeats(tx *gorm.DB, booking *models.Booking, seats []*models.Seat) (bool, error) {
+	for _, seat := range seats {
+		if seat.HeldForID == nil {
+			continue
+		}
+		entryID := *seat.HeldForID
+		if err := tx.Model(&models.WaitlistEntry{}).Where("id = ?", entryID).Updates(map[string]interface{}{
+			"status":     models.WaitlistStatusBooked,
+			"booking_id": booking.ID,
+		}).Error; err != nil {
+			return false, err
+		}
+		metrics.WaitlistEntries.WithLabelValues("booked").Inc()
+		return releaseHeldSeats(tx, entryID)
+	}
+	return false, nil
+}
+
+// closeWaitlistEntry takes an entry off the waitlist, releasing any seats held for it
+func closeWaitlistEntry(tx *gorm.DB, entry *models.WaitlistEntry, status string) error {
+	entry.Status = status
+	if err := tx.Model(entry).Update("status", status).Error; err != nil {
+		return err
+	}
+	_, err := releaseHeldSeats(tx, entry.ID)
+	return err
+}
+
+// releaseHeldSeats makes the seats still reserved for a waitlist entry
+// available and detaches the booked ones. It returns whether any were released.
+func releaseHeldSeats(tx *gorm.DB, entryID uint) (bool, error) {
+	var released []models.Seat
+	if err := tx.Select("id", "show_time_id").
+		Where("held_for_id = ? AND status = ?", entryID, models.SeatStatusReserved).
+		Find(&released).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.Seat{}).Where("held_for_id = ?", entryID).Update("held_for_id", nil).Error; err != nil {
+		return false, err
+	}
+	if len(released) == 0 {
+		return false, nil
+	}
+
+	seatIDs := make([]uint, len(released))
+	for i, seat := range released {
+		seatIDs[i] = seat.ID
+	}
+	if err := tx.Model(&models.Seat{}).Where("id IN ?", seatIDs).Update("status", models.SeatStatusAvailable).Error; err != nil {
+		return false, err
+	}
+	return true, events.Record(tx, events.SeatsReleased{ShowtimeID: released[0].ShowTimeID, SeatIDs: seatIDs})
+}
+
+// releaseToWaitlist offers released seats of a showtime to its waitlist.
+// Failures are only logged; the booking recovery worker offers the seats later.
+func (s *BookingService) releaseToWaitlist(ctx context.Context, showtimeID uint) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.offerWaitlistSeats(ctx, showtimeID); err != nil {
+		slog.ErrorContext(ctx, "failed to offer released seats to the waitlist", "showtime_id", showtimeID, "error", err)
+	}
+}
+
+// offerWaitlistSeats offers the free seats of a showtime to waiting customers
+// in the order they joined, until the next customer's group doesn't fit; they
+// are not overtaken by customers after them.
+func (s *BookingService) offerWaitlistSeats(ctx context.Context, showtimeID uint) error {
+	for {
+		offered, err := s.offerNext(ctx, showtimeID)
+		if apperrors.CodeOf(err) == apperrors.CodeSeatTaken {
+			// Someone is booking the seats right now; try again next round
+			return nil
+		}
+		if err != nil || !offered {
+			return err
+		}
+	}
+}
+
+// offerNext holds the best free seats for the first waiting customer of a
+// showtime and tells them about it. It returns whether seats were offered.
+func (s *BookingService) offerNext(ctx context.Context, showtimeID uint) (bool, error) {
+	var (
+		offered bool
+		locked  []uint
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var showtime models.ShowTime
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Preload("Movie").Preload("Hall").
+			First(&showtime, showtimeID).Error; err != nil {
+			return err
+		}
+		if showtime.Status == models.ShowTimeStatusCancelled || time.Now().After(showtime.StartTime) {
+			return nil
+		}
+
+		var entry models.WaitlistEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").
+			Where("show_time_id = ? AND status = ?", showtimeID, models.WaitlistStatusWaiting).
+			Order("id").First(&entry).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		layout, seats, err := s.seatLayout(ctx, tx, &showtime, false)
+		if err != nil {
+			return err
+		}
+		hold := s.pickHold(layout, entry.SeatCount, &showtime.Hall)
+		if hold == nil {
+			return nil
+		}
+		held := make([]*models.Seat, len(hold))
+		seatIDs := make([]uint, len(held))
+		for i, seat := range hold {
+			held[i] = seats[seat.ID]
+			seatIDs[i] = seat.ID
+		}
+
+		// Lock the seats like a booking does, so nobody books them meanwhile
+		if err := s.lockSeats(ctx, showtimeID, seatIDs, entry.UserID); err != nil {
+			return err
+		}
+		locked = seatIDs
+
+		result := tx.Model(&models.Seat{}).
+			Where("id IN ? AND status = ?", seatIDs, models.SeatStatusAvailable).
+			Updates(map[string]interface{}{"status": models.SeatStatusReserved, "held_for_id": entry.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(seatIDs)) {
+			return apperrors.New(apperrors.CodeSeatTaken, "seats were taken while offering them")
+		}
+
+		now := time.Now()
+		expires := now.Add(s.waitlistHoldTTL)
+		if err := tx.Model(&entry).Updates(map[string]interface{}{
+			"status":           models.WaitlistStatusOffered,
+			"offered_at":       now,
+			"offer_expires_at": expires,
+		}).Error; err != nil {
+			return err
+		}
+		offered = true
+		return s.notifications.Enqueue(tx, &entry.UserID, waitlistOfferMessage(&showtime, &entry, held, expires))
+	})
+	s.releaseSeatLocks(ctx, showtimeID, locked)
+	if err != nil {
+		return false, err
+	}
+	if offered {
+		metrics.WaitlistEntries.WithLabelValues("offered").Inc()
+	}
+	return offered, nil
+}
+
+// pickHold chooses the best seats to hold for a waitlist offer, or nil. Where
+// orphan seats are prevented, the hold must not leave any, or the customer
+// could not book it.
+func (s *BookingService) pickHold(layout []seating.Seat, count int, hall *models.Hall) []seating.Seat {
+	suggestions := seating.Suggest(layout, count, len(layout), seating.Preferences{Row: seating.RowMiddle, AllowSplit: true})
+	for _, suggestion := range suggestions {
+		ids := make([]uint, len(suggestion.Seats))
+		for i, seat := range suggestion.Seats {
+			ids[i] = seat.ID
+		}
+		if !s.preventsOrphans(hall) || len(seating.Orphans(layout, ids)) == 0 {
+			return suggestion.Seats
+		}
+	}
+	return nil
+}
+
+// lockSeats takes the Redis locks of seats for a customer, all or none
+func (s *BookingService) lockSeats(ctx context.Context, showtimeID uint, seatIDs []uint, userID uint) error {
+	for i, seatID := range seatIDs {
+		lockKey := fmt.Sprintf("seat_lock:%d:%d", showtimeID, seatID)
+		locked, err := s.redisClient.SetNX(ctx, lockKey, userID, s.seatLockTTL).Result()
+		if err != nil || !locked {
+			s.releaseSeatLocks(ctx, showtimeID, seatIDs[:i])
+			if err != nil {
+				return fmt.Errorf("failed to lock seat %d: %w", seatID, err)
+			}
+			metrics.SeatLockConflicts.Inc()
+			return apperrors.New(apperrors.CodeSeatTaken, "seat %d is currently being booked by another customer", seatID)
+		}
+		metrics.SeatLockAcquisitions.Inc()
+	}
+	return nil
+}
+
+// expireWaitlist closes offers that ran out and entries of showtimes that were
+// cancelled or have started, then offers free seats of every showtime with a
+// queue. It is run by the booking recovery worker.
+func (s *BookingService) expireWaitlist(ctx context.Context) error {
+	now := time.Now()
+	var entryIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.WaitlistEntry{}).
+		Joins("JOIN show_times ON show_times.id = waitlist_entries.show_time_id").
+		Where("waitlist_entries.status IN ?", activeWaitlistStatuses).
+		Where("(waitlist_entries.status = ? AND waitlist_entries.offer_expires_at <= ?) OR show_times.status = ? OR show_times.start_time <= ?",
+			models.WaitlistStatusOffered, now, models.ShowTimeStatusCancelled, now).
+		Pluck("waitlist_entries.id", &entryIDs).Error; err != nil {
+		return err
+	}
+	for _, entryID := range entryIDs {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var entry models.WaitlistEntry
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, entryID).Error; err != nil {
+				return err
+			}
+			// Booked or left in the meantime
+			if entry.Status != models.WaitlistStatusWaiting && entry.Status != models.WaitlistStatusOffered {
+				return nil
+			}
+			return closeWaitlistEntry(tx, &entry, models.WaitlistStatusExpired)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to expire waitlist entry %d: %w", entryID, err)
+		}
+		metrics.WaitlistEntries.WithLabelValues("expired").Inc()
+	}
+
+	var showtimeIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.WaitlistEntry{}).
+		Where("status = ?", models.WaitlistStatusWaiting).
+		Distinct().Pluck("show_time_id", &showtimeIDs).Error; err != nil {
+		return err
+	}
+	for _, showtimeID := range showtimeIDs {
+		if err := s.offerWaitlistSeats(ctx, showtimeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitlistOfferMessage(showtime *models.ShowTime, entry *models.WaitlistEntry, seats []*models.Seat, expires time.Time) notification.Message {
+	names := make([]string, len(seats))
+	for i, seat := range seats {
+		names[i] = seatName(seat)
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nGood news: seats have become available for %s on %s in %s.\n\n",
+		entry.User.Name, showtime.Movie.Title, showtime.StartTime.Format("Mon 2 Jan 15:04"), showtime.Hall.Name)
+	fmt.Fprintf(&body, "We're holding seats %s for you until %s. Book them before then, or they go to the next person on the waitlist.\n",
+		strings.Join(names, ", "), expires.Format("15:04"))
+	return notification.Message{
+		To:      entry.User.Email,
+		Subject: fmt.Sprintf("Seats available: %s on %s", showtime.Movie.Title, showtime.StartTime.Format("2 Jan 15:04")),
+		Body:    body.String(),
+	}
+}
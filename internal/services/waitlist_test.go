@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/database/redistest"
+	"movie-ticket-booking/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// waitlistFixture is a sold-out showtime of two seats with two customers
+// waiting for a seat each, first before second
+type waitlistFixture struct {
+	db       *gorm.DB
+	bookings *BookingService
+	showtime *models.ShowTime
+	seats    []uint
+	owner    *models.User
+	booking  *models.Booking
+	first    *models.User
+	second   *models.User
+}
+
+func newWaitlistFixture(t *testing.T) *waitlistFixture {
+	t.Helper()
+	ctx := context.Background()
+	db := dbtest.New(t)
+	f := &waitlistFixture{db: db, bookings: newTestBookingService(t, db, redistest.New(t), &testGateway{})}
+	f.showtime = createTestShowtime(t, db, createTestMovie(t, db), time.Now().Add(72*time.Hour), 10, "..")
+	f.seats = seatIDs(t, db, f.showtime, "A1", "A2")
+
+	f.owner = createTestUser(t, db, "owner")
+	booking, err := f.bookings.CreateBooking(ctx, f.owner.ID, f.showtime.ID, f.seats, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.booking = booking
+
+	f.first, f.second = createTestUser(t, db, "first"), createTestUser(t, db, "second")
+	for _, user := range []*models.User{f.first, f.second} {
+		if _, err := f.bookings.JoinWaitlist(ctx, user.ID, f.showtime.ID, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+// entry returns the latest waitlist entry of a customer
+func (f *waitlistFixture) entry(t *testing.T, user *models.User) *models.WaitlistEntry {
+	t.Helper()
+	var entry models.WaitlistEntry
+	if err := f.db.Where("user_id = ? AND show_time_id = ?", user.ID, f.showtime.ID).Order("id DESC").First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &entry
+}
+
+// heldFor returns the IDs of the seats held for a waitlist entry
+func (f *waitlistFixture) heldFor(t *testing.T, entry *models.WaitlistEntry) []uint {
+	t.Helper()
+	var ids []uint
+	if err := f.db.Model(&models.Seat{}).Where("held_for_id = ? AND status = ?", entry.ID, models.SeatStatusReserved).
+		Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func (f *waitlistFixture) offersSent(t *testing.T, user *models.User) int64 {
+	t.Helper()
+	var count int64
+	if err := f.db.Model(&models.Notification{}).Where("user_id = ? AND subject LIKE ?", user.ID, "Seats available:%").
+		Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// release cancels the first seat of the owner's booking, which offers it to the waitlist
+func (f *waitlistFixture) release(t *testing.T) {
+	t.Helper()
+	if _, err := f.bookings.CancelBookingSeats(context.Background(), f.booking.ID, f.owner.ID, f.seats[:1]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitlistOffersSeatsInJoiningOrder(t *testing.T) {
+	f := newWaitlistFixture(t)
+
+	f.release(t)
+	first, second := f.entry(t, f.first), f.entry(t, f.second)
+	if first.Status != models.WaitlistStatusOffered || first.OfferExpiresAt == nil {
+		t.Fatalf("first customer: status %s, offer expires %v", first.Status, first.OfferExpiresAt)
+	}
+	if held := f.heldFor(t, first); !equalUints(held, f.seats[:1]) {
+		t.Errorf("seats held for the first customer %v, want %v", held, f.seats[:1])
+	}
+	if f.offersSent(t, f.first) != 1 {
+		t.Error("first customer was not told about the offer")
+	}
+	if second.Status != models.WaitlistStatusWaiting {
+		t.Errorf("second customer is %s, want WAITING", second.Status)
+	}
+	place, err := f.bookings.GetWaitlistPlace(context.Background(), f.second.ID, f.showtime.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if place.Position != 1 {
+		t.Errorf("second customer is at position %d, want 1", place.Position)
+	}
+
+	// The next seat goes to the second customer
+	if _, err := f.bookings.CancelBookingSeats(context.Background(), f.booking.ID, f.owner.ID, f.seats[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if second := f.entry(t, f.second); second.Status != models.WaitlistStatusOffered || !equalUints(f.heldFor(t, second), f.seats[1:]) {
+		t.Errorf("second customer: status %s, held seats %v", second.Status, f.heldFor(t, second))
+	}
+}
+
+func TestExpiredWaitlistOfferMovesOn(t *testing.T) {
+	f := newWaitlistFixture(t)
+	f.release(t)
+
+	first := f.entry(t, f.first)
+	if err := f.db.Model(first).Update("offer_expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.bookings.expireWaitlist(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if first := f.entry(t, f.first); first.Status != models.WaitlistStatusExpired || len(f.heldFor(t, first)) != 0 {
+		t.Errorf("first customer: status %s, held seats %v, want EXPIRED without seats", first.Status, f.heldFor(t, first))
+	}
+	second := f.entry(t, f.second)
+	if second.Status != models.WaitlistStatusOffered || !equalUints(f.heldFor(t, second), f.seats[:1]) {
+		t.Errorf("second customer: status %s, held seats %v", second.Status, f.heldFor(t, second))
+	}
+	if f.offersSent(t, f.second) != 1 {
+		t.Error("second customer was not told about the offer")
+	}
+}
+
+func TestLeavingWaitlistReleasesHeldSeats(t *testing.T) {
+	ctx := context.Background()
+	f := newWaitlistFixture(t)
+	f.release(t)
+
+	if err := f.bookings.LeaveWaitlist(ctx, f.first.ID, f.showtime.ID); err != nil {
+		t.Fatal(err)
+	}
+	if first := f.entry(t, f.first); first.Status != models.WaitlistStatusLeft || len(f.heldFor(t, first)) != 0 {
+		t.Errorf("first customer: status %s, held seats %v, want LEFT without seats", first.Status, f.heldFor(t, first))
+	}
+	if second := f.entry(t, f.second); second.Status != models.WaitlistStatusOffered || !equalUints(f.heldFor(t, second), f.seats[:1]) {
+		t.Errorf("second customer: status %s, held seats %v", second.Status, f.heldFor(t, second))
+	}
+
+	if err := f.bookings.LeaveWaitlist(ctx, f.first.ID, f.showtime.ID); apperrors.CodeOf(err) != apperrors.CodeNotFound {
+		t.Errorf("leaving twice: got %v, want not found", err)
+	}
+}
+
+func TestOnlyOfferHolderBooksHeldSeats(t *testing.T) {
+	ctx := context.Background()
+	f := newWaitlistFixture(t)
+	f.release(t)
+
+	stranger := createTestUser(t, f.db, "stranger")
+	for _, user := range []*models.User{f.second, stranger} {
+		if _, err := f.bookings.CreateBooking(ctx, user.ID, f.showtime.ID, f.seats[:1], false); apperrors.CodeOf(err) != apperrors.CodeSeatTaken {
+			t.Errorf("%s booking the held seat: got %v, want seat taken", user.Name, err)
+		}
+	}
+
+	booking, err := f.bookings.CreateBooking(ctx, f.first.ID, f.showtime.ID, f.seats[:1], false)
+	if err != nil {
+		t.Fatalf("offer holder booking the held seat: %v", err)
+	}
+	first := f.entry(t, f.first)
+	if first.Status != models.WaitlistStatusBooked || first.BookingID == nil || *first.BookingID != booking.ID {
+		t.Errorf("first customer: status %s, booking %v, want BOOKED with booking %d", first.Status, first.BookingID, booking.ID)
+	}
+	var seat models.Seat
+	if err := f.db.First(&seat, f.seats[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if seat.Status != models.SeatStatusBooked || seat.HeldForID != nil {
+		t.Errorf("seat: status %s, held for %v, want BOOKED and no longer held", seat.Status, seat.HeldForID)
+	}
+}
+
+func equalUints(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/metrics"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/webhook"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// webhookEventTypes are the domain events partners can subscribe to
+var webhookEventTypes = map[string]bool{
+	events.TypeBookingCreated:    true,
+	events.TypeBookingCancelled:  true,
+	events.TypeSeatsReleased:     true,
+	events.TypeShowtimeCancelled: true,
+	events.TypePaymentSucceeded:  true,
+}
+
+// WebhookService delivers domain events to partner webhooks. It is a sink of
+// the event relay: every event becomes a delivery per matching subscription,
+// which is posted in the background and retried with exponential backoff.
+type WebhookService struct {
+	db          *gorm.DB
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+func NewWebhookService(db *gorm.DB, timeout time.Duration, maxAttempts int, retryDelay time.Duration) *WebhookService {
+	return &WebhookService{
+		db:          db,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+	}
+}
+
+// WebhookSubscriptionChanges holds the fields of a subscription to update;
+// nil fields are left unchanged
+type WebhookSubscriptionChanges struct {
+	URL          *string
+	EventTypes   []string
+	Active       *bool
+	RotateSecret bool
+}
+
+// GetSubscriptions lists every webhook subscription
+func (s *WebhookService) GetSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+	if err := s.db.WithContext(ctx).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// CreateSubscription subscribes a URL to events. A secret is generated if
+// none is given.
+func (s *WebhookService) CreateSubscription(ctx context.Context, rawURL string, eventTypes []string, secret string) (*models.WebhookSubscription, error) {
+	if err := validateWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+	types, err := joinEventTypes(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		if secret, err = webhook.NewSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:        rawURL,
+		EventTypes: types,
+		Secret:     secret,
+		Active:     true,
+	}
+	if err := s.db.WithContext(ctx).Create(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// UpdateSubscription changes a subscription. Deliveries already queued are
+// sent to the new URL.
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id uint, changes WebhookSubscriptionChanges) (*models.WebhookSubscription, error) {
+	subscription, err := s.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if changes.URL != nil {
+		if err := validateWebhookURL(*changes.URL); err != nil {
+			return nil, err
+		}
+		updates["url"] = *changes.URL
+	}
+	if changes.EventTypes != nil {
+		types, err := joinEventTypes(changes.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+		updates["event_types"] = types
+	}
+	if changes.Active != nil {
+		updates["active"] = *changes.Active
+	}
+	if changes.RotateSecret {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			return nil, err
+		}
+		updates["secret"] = secret
+	}
+	if len(updates) == 0 {
+		return subscription, nil
+	}
+
+	if err := s.db.WithContext(ctx).Model(subscription).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.getSubscription(ctx, id)
+}
+
+// DeleteSubscription removes a subscription; its pending deliveries are dropped
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WebhookSubscription{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.NotFound("webhook subscription not found")
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", id, models.WebhookDeliveryPending).
+			Updates(map[string]interface{}{
+				"status":     models.WebhookDeliveryFailed,
+				"last_error": "subscription deleted",
+			}).Error
+	})
+}
+
+// GetDeliveries lists the most recent deliveries to a subscription
+func (s *WebhookService) GetDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := s.getSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var deliveries []*models.WebhookDelivery
+	if err := s.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery sends the payload of a past delivery again as a new delivery
+func (s *WebhookService) ReplayDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var original models.WebhookDelivery
+	if err := s.db.WithContext(ctx).Preload("Subscription").First(&original, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("webhook delivery not found")
+		}
+		return nil, err
+	}
+	if original.Subscription.ID == 0 {
+		return nil, apperrors.Validation("webhook subscription was deleted")
+	}
+
+	replay := &models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+		ReplayOfID:     &original.ID,
+	}
+	if err := s.db.WithContext(ctx).Create(replay).Error; err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Name identifies the webhook service among the sinks of the event relay
+func (s *WebhookService) Name() string {
+	return "partner_webhooks"
+}
+
+// Publish queues an event for every active subscription to its type. Events
+// the relay publishes again are only queued once.
+func (s *WebhookService) Publish(ctx context.Context, event events.Event) error {
+	var subscriptions []models.WebhookSubscription
+	if err := s.db.WithContext(ctx).Where("active").Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var deliveries []models.WebhookDelivery
+	for i := range subscriptions {
+		if !subscribedTo(&subscriptions[i], event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscriptions[i].ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(body),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "replay_of_id IS NULL"}}},
+		DoNothing:   true,
+	}).Create(&deliveries).Error
+}
+
+// Run sends due deliveries every interval until ctx is cancelled. beat is
+// called after every round to signal the worker is alive.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.deliverDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to deliver webhooks", "error", err)
+		}
+		beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends due deliveries in batches. Rows are locked with SKIP LOCKED
+// so that several replicas never send the same delivery at once.
+func (s *WebhookService) deliverDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		var sent int
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var due []models.WebhookDelivery
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED", Table: clause.Table{Name: "webhook_deliveries"}}).
+				Joins("Subscription").
+				Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+				Order("webhook_deliveries.id").Limit(20).Find(&due).Error; err != nil {
+				return err
+			}
+			for i := range due {
+				if err := s.deliver(ctx, tx, &due[i]); err != nil {
+					return err
+				}
+			}
+			sent = len(due)
+			return nil
+		})
+		if err != nil || sent == 0 {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver posts a delivery to its subscription and records the outcome
+func (s *WebhookService) deliver(ctx context.Context, tx *gorm.DB, delivery *models.WebhookDelivery) error {
+	attempt := delivery.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempt}
+	var status int
+	var err error
+	if delivery.Subscription.Active {
+		status, err = s.post(ctx, delivery)
+	} else {
+		// Deliveries queued before the subscription was paused are dropped
+		err = fmt.Errorf("subscription is inactive")
+	}
+	if status != 0 {
+		updates["response_status"] = status
+	}
+	switch {
+	case err == nil:
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = time.Now()
+		updates["last_error"] = ""
+		metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, "succeeded").Inc()
+	case attempt >= s.maxAttempts || !delivery.Subscription.Active:
+		slog.Error("giving up on webhook delivery", "delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "error", err)
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = err.Error()
+		metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, "failed").Inc()
+	default:
+		slog.Warn("failed to deliver webhook, will retry", "delivery_id", delivery.ID, "subscription_id", delivery.SubscriptionID, "attempt", attempt, "error", err)
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = time.Now().Add(s.retryDelay << (attempt - 1))
+		metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, "retry").Inc()
+	}
+	return tx.Model(delivery).Updates(updates).Error
+}
+
+// post sends a signed delivery and returns the HTTP status of the response.
+// Any response other than 2xx is an error.
+func (s *WebhookService) post(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	subscription := &delivery.Subscription
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(subscription.Secret, now, body))
+	req.Header.Set(webhook.HeaderEvent, delivery.EventType)
+	req.Header.Set(webhook.HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *WebhookService) getSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := s.db.WithContext(ctx).First(&subscription, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, apperrors.NotFound("webhook subscription not found")
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// SubscriptionEventTypes returns the event types a subscription receives
+func SubscriptionEventTypes(subscription *models.WebhookSubscription) []string {
+	return strings.Split(subscription.EventTypes, ",")
+}
+
+func subscribedTo(subscription *models.WebhookSubscription, eventType string) bool {
+	for _, t := range SubscriptionEventTypes(subscription) {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// joinEventTypes validates event types and joins them for storage
+func joinEventTypes(eventTypes []string) (string, error) {
+	if len(eventTypes) == 0 {
+		return "", apperrors.Validation("at least one event type is required")
+	}
+	seen := make(map[string]bool, len(eventTypes))
+	var unique []string
+	for _, t := range eventTypes {
+		if !webhookEventTypes[t] {
+			return "", apperrors.Validation("unknown event type %q", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			unique = append(unique, t)
+		}
+	}
+	return strings.Join(unique, ","), nil
+}
+
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return apperrors.Validation("webhook URL must be an absolute http or https URL")
+	}
+	return nil
+}
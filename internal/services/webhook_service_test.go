@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"movie-ticket-booking/internal/apperrors"
+	"movie-ticket-booking/internal/database/dbtest"
+	"movie-ticket-booking/internal/events"
+	"movie-ticket-booking/internal/models"
+	"movie-ticket-booking/internal/webhook"
+
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "partner-secret"
+
+// webhookReceiver is a partner endpoint answering with a configurable status
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []received
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	p := &webhookReceiver{status: http.StatusOK}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.requests = append(p.requests, received{header: r.Header.Clone(), body: body})
+		w.WriteHeader(p.status)
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *webhookReceiver) respondWith(status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+}
+
+func (p *webhookReceiver) received() []received {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]received(nil), p.requests...)
+}
+
+// publishTestEvent records a domain event and hands it to the webhook service
+func publishTestEvent(t *testing.T, db *gorm.DB, service *WebhookService) {
+	t.Helper()
+	row := &models.OutboxEvent{AggregateType: events.AggregateBooking, AggregateID: 1, Type: events.TypeBookingCreated, Payload: `{"bookingId":1}`}
+	if err := db.Create(row).Error; err != nil {
+		t.Fatal(err)
+	}
+	event := events.Event{
+		ID:            row.ID,
+		Type:          row.Type,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		OccurredAt:    row.CreatedAt,
+		Payload:       []byte(row.Payload),
+	}
+	if err := service.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func onlyDelivery(t *testing.T, db *gorm.DB) models.WebhookDelivery {
+	t.Helper()
+	var deliveries []models.WebhookDelivery
+	if err := db.Order("id").Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// makeDue moves the next attempt of every pending delivery to the past
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.Model(&models.WebhookDelivery{}).Where("status = ?", models.WebhookDeliveryPending).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	partner := newWebhookReceiver(t)
+	service := NewWebhookService(db, 5*time.Second, 3, time.Hour)
+
+	subscription, err := service.CreateSubscription(ctx, partner.URL, []string{events.TypeBookingCreated, events.TypeBookingCancelled}, testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishTestEvent(t, db, service)
+	if err := service.deliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := partner.received()
+	if len(requests) != 1 {
+		t.Fatalf("partner received %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	err = webhook.Verify(testWebhookSecret, request.header.Get(webhook.HeaderTimestamp), request.header.Get(webhook.HeaderSignature), request.body, time.Minute)
+	if err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if err := webhook.Verify("other-secret", request.header.Get(webhook.HeaderTimestamp), request.header.Get(webhook.HeaderSignature), request.body, time.Minute); err == nil {
+		t.Error("signature verifies with another secret")
+	}
+
+	delivery := onlyDelivery(t, db)
+	if delivery.SubscriptionID != subscription.ID || delivery.Status != models.WebhookDeliverySucceeded || delivery.DeliveredAt == nil {
+		t.Errorf("delivery: subscription %d, status %s, delivered at %v", delivery.SubscriptionID, delivery.Status, delivery.DeliveredAt)
+	}
+	if got := request.header.Get(webhook.HeaderEvent); got != events.TypeBookingCreated {
+		t.Errorf("event header %q, want %q", got, events.TypeBookingCreated)
+	}
+	if got := request.header.Get(webhook.HeaderDelivery); got != strconv.FormatUint(uint64(delivery.ID), 10) {
+		t.Errorf("delivery header %q, want %d", got, delivery.ID)
+	}
+	if string(request.body) != delivery.Payload {
+		t.Errorf("body %s, want the stored payload %s", request.body, delivery.Payload)
+	}
+}
+
+func TestWebhookDeliveryRetriesAndGivesUp(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	partner := newWebhookReceiver(t)
+	partner.respondWith(http.StatusServiceUnavailable)
+	const maxAttempts, retryDelay = 3, time.Hour
+	service := NewWebhookService(db, 5*time.Second, maxAttempts, retryDelay)
+
+	if _, err := service.CreateSubscription(ctx, partner.URL, []string{events.TypeBookingCreated}, testWebhookSecret); err != nil {
+		t.Fatal(err)
+	}
+	publishTestEvent(t, db, service)
+
+	// The wait before a retry doubles after every attempt
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		before := time.Now()
+		if err := service.deliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		delivery := onlyDelivery(t, db)
+		if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != attempt {
+			t.Fatalf("after attempt %d: status %s, attempts %d", attempt, delivery.Status, delivery.Attempts)
+		}
+		if delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusServiceUnavailable {
+			t.Errorf("after attempt %d: response status %v, want 503", attempt, delivery.ResponseStatus)
+		}
+		wait := retryDelay << (attempt - 1)
+		if delivery.NextAttemptAt.Before(before.Add(wait)) || delivery.NextAttemptAt.After(time.Now().Add(wait)) {
+			t.Errorf("after attempt %d: next attempt at %v, want %v from now", attempt, delivery.NextAttemptAt, wait)
+		}
+
+		// Not retried before it is due
+		if err := service.deliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(partner.received()); got != attempt {
+			t.Fatalf("partner received %d requests after attempt %d", got, attempt)
+		}
+		makeDue(t, db)
+	}
+
+	if err := service.deliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	delivery := onlyDelivery(t, db)
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.Attempts != maxAttempts || delivery.LastError == "" {
+		t.Errorf("after the last attempt: status %s, attempts %d, last error %q", delivery.Status, delivery.Attempts, delivery.LastError)
+	}
+
+	makeDue(t, db)
+	if err := service.deliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(partner.received()); got != maxAttempts {
+		t.Errorf("partner received %d requests, want %d", got, maxAttempts)
+	}
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New(t)
+	partner := newWebhookReceiver(t)
+	partner.respondWith(http.StatusInternalServerError)
+	service := NewWebhookService(db, 5*time.Second, 1, time.Hour)
+
+	if _, err := service.CreateSubscription(ctx, partner.URL, []string{events.TypeBookingCreated}, testWebhookSecret); err != nil {
+		t.Fatal(err)
+	}
+	publishTestEvent(t, db, service)
+	if err := service.deliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	original := onlyDelivery(t, db)
+	if original.Status != models.WebhookDeliveryFailed {
+		t.Fatalf("original delivery is %s, want FAILED", original.Status)
+	}
+
+	partner.respondWith(http.StatusOK)
+	replay, err := service.ReplayDelivery(ctx, original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ReplayOfID == nil || *replay.ReplayOfID != original.ID || replay.Status != models.WebhookDeliveryPending {
+		t.Errorf("replay: replay of %v, status %s", replay.ReplayOfID, replay.Status)
+	}
+	if err := service.deliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := partner.received()
+	if len(requests) != 2 {
+		t.Fatalf("partner received %d requests, want 2", len(requests))
+	}
+	if string(requests[1].body) != original.Payload {
+		t.Errorf("replayed body %s, want %s", requests[1].body, original.Payload)
+	}
+	if got := requests[1].header.Get(webhook.HeaderDelivery); got != strconv.FormatUint(uint64(replay.ID), 10) {
+		t.Errorf("replay delivery header %q, want %d", got, replay.ID)
+	}
+
+	var delivered models.WebhookDelivery
+	if err := db.First(&delivered, replay.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Status != models.WebhookDeliverySucceeded {
+		t.Errorf("replay is %s, want SUCCEEDED", delivered.Status)
+	}
+	if err := db.First(&original, original.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if original.Status != models.WebhookDeliveryFailed {
+		t.Errorf("original delivery changed to %s", original.Status)
+	}
+
+	if _, err := service.ReplayDelivery(ctx, replay.ID+100); apperrors.CodeOf(err) != apperrors.CodeNotFound {
+		t.Errorf("replaying an unknown delivery: got %v, want not found", err)
+	}
+}
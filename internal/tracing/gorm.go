@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// InstrumentGORM creates a span for every query run with a context that
+// already carries a span, e.g. db.WithContext(ctx) inside a request
+func InstrumentGORM(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startGORMSpan("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endGORMSpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startGORMSpan("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endGORMSpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startGORMSpan("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endGORMSpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startGORMSpan("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endGORMSpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startGORMSpan("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endGORMSpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startGORMSpan("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endGORMSpan),
+	)
+}
+
+func startGORMSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+
+		ctx, span := tracer().Start(ctx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.operation", operation),
+			))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endGORMSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		recordError(span, db.Error)
+	}
+}
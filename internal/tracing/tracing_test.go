@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	incomingTraceID = "0af7651916cd43dd8448eb211c80319c"
+	incomingSpanID  = "b7ad6b7169203331"
+)
+
+// offlineRedis answers every command without a server
+type offlineRedis struct{}
+
+func (offlineRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (offlineRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		cmd.SetErr(redis.Nil)
+		return redis.Nil
+	}
+}
+
+func (offlineRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return nil
+	}
+}
+
+type movie struct {
+	ID    uint
+	Title string
+}
+
+// newTestGraphQLServer serves a schema with a movies query whose resolver
+// queries the database and Redis like the real resolvers do
+func newTestGraphQLServer(db *gorm.DB, rdb *redis.Client) *handler.Server {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `type Query { movies: [String!]! }`})
+
+	executable := &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return childComplexity, false
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			done := false
+			return func(ctx context.Context) *graphql.Response {
+				if done {
+					return nil
+				}
+				done = true
+
+				// Generated code runs resolvers through the field middleware
+				opCtx := graphql.GetOperationContext(ctx)
+				ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+					Object:     "Query",
+					IsResolver: true,
+					Field: graphql.CollectedField{Field: &ast.Field{
+						Name:       "movies",
+						Alias:      "movies",
+						Definition: schema.Query.Fields.ForName("movies"),
+					}},
+				})
+				_, err := opCtx.ResolverMiddleware(ctx, func(ctx context.Context) (interface{}, error) {
+					var movies []movie
+					if err := db.WithContext(ctx).Find(&movies).Error; err != nil {
+						return nil, err
+					}
+					if err := rdb.Get(ctx, "seat_lock:1:1").Err(); err != nil && err != redis.Nil {
+						return nil, err
+					}
+					return movies, nil
+				})
+				if err != nil {
+					return graphql.ErrorResponse(ctx, "%v", err)
+				}
+				return &graphql.Response{Data: json.RawMessage(`{"movies":[]}`)}
+			}
+		},
+	}
+
+	srv := handler.New(executable)
+	srv.AddTransport(transport.POST{})
+	srv.Use(GraphQLExtension{})
+	return srv
+}
+
+func TestSpanTree(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider(exporter, "test", 1)
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	// Queries are built but not sent to a database
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InstrumentGORM(db); err != nil {
+		t.Fatal(err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:0"})
+	InstrumentRedis(rdb)
+	rdb.AddHook(offlineRedis{})
+
+	body := strings.NewReader(`{"query":"query Movies { movies }","operationName":"Movies"}`)
+	req := httptest.NewRequest(http.MethodPost, "/query", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-"+incomingSpanID+"-01")
+	rec := httptest.NewRecorder()
+	Middleware(newTestGraphQLServer(db, rdb)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	// Each span is the child of the one before it
+	tree := []struct {
+		name   string
+		parent string
+	}{
+		{"POST /query", ""},
+		{"graphql.query Movies", "POST /query"},
+		{"resolver Query.movies", "graphql.query Movies"},
+		{"gorm.query", "resolver Query.movies"},
+		{"redis.get", "resolver Query.movies"},
+	}
+	for _, node := range tree {
+		span, ok := spans[node.name]
+		if !ok {
+			t.Errorf("span %q was not recorded", node.name)
+			continue
+		}
+		if got := span.SpanContext.TraceID().String(); got != incomingTraceID {
+			t.Errorf("span %q has trace ID %s, want the incoming %s", node.name, got, incomingTraceID)
+		}
+
+		wantParent := incomingSpanID
+		if node.parent != "" {
+			wantParent = spans[node.parent].SpanContext.SpanID().String()
+		}
+		if got := span.Parent.SpanID().String(); got != wantParent {
+			t.Errorf("span %q has parent %s, want %s (%s)", node.name, got, wantParent, node.parent)
+		}
+	}
+
+	if server := spans["POST /query"]; !server.Parent.IsRemote() {
+		t.Error("HTTP span does not continue the remote traceparent")
+	}
+	if statement := spans["gorm.query"].Attributes; !hasAttribute(statement, "db.statement", `SELECT * FROM "movies"`) {
+		t.Errorf("gorm span attributes %v lack the statement", statement)
+	}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, key, value string) bool {
+	for _, attr := range attributes {
+		if string(attr.Key) == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
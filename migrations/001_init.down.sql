@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS booking_seats;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS seats;
+DROP TABLE IF EXISTS show_times;
+DROP TABLE IF EXISTS halls;
+DROP TABLE IF EXISTS movies;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -107,46 +107,6 @@ CREATE INDEX idx_show_times_start_time ON show_times(start_time);
 CREATE INDEX idx_tickets_booking_code ON tickets(booking_code);
 CREATE INDEX idx_tickets_user_id ON tickets(user_id);
 
--- Insert sample movie data
-INSERT INTO movies (title, description, duration, genre, release_date, poster_url) VALUES
-('The Shawshank Redemption', 'Two imprisoned men bond over a number of years, finding solace and eventual redemption through acts of common decency.', 142, 'Drama', '1994-09-23', 'https://example.com/shawshank.jpg'),
-('The Godfather', 'The aging patriarch of an organized crime dynasty transfers control of his clandestine empire to his reluctant son.', 175, 'Crime', '1972-03-24', 'https://example.com/godfather.jpg'),
-('Inception', 'A thief who steals corporate secrets through the use of dream-sharing technology is given the inverse task of planting an idea into the mind of a C.E.O.', 148, 'Sci-Fi', '2010-07-16', 'https://example.com/inception.jpg');
-
--- Insert sample halls
-INSERT INTO halls (name, capacity) VALUES
-('Hall A', 100),
-('Hall B', 150),
-('Hall C', 200);
-
--- Insert sample seats for Hall A
-INSERT INTO seats (hall_id, row_number, seat_number, status) 
-SELECT 1, chr(64 + row_num), seat_num, 'AVAILABLE'
-FROM generate_series(1, 10) row_num,
-     generate_series(1, 10) seat_num;
-
--- Insert sample seats for Hall B
-INSERT INTO seats (hall_id, row_number, seat_number, status)
-SELECT 2, chr(64 + row_num), seat_num, 'AVAILABLE'
-FROM generate_series(1, 10) row_num,
-     generate_series(1, 15) seat_num;
-
--- Insert sample seats for Hall C
-INSERT INTO seats (hall_id, row_number, seat_number, status)
-SELECT 3, chr(64 + row_num), seat_num, 'AVAILABLE'
-FROM generate_series(1, 10) row_num,
-     generate_series(1, 20) seat_num;
-
--- Insert sample showtimes
-INSERT INTO show_times (movie_id, hall_id, start_time, end_time, price) VALUES
-(1, 1, CURRENT_TIMESTAMP + interval '1 day', CURRENT_TIMESTAMP + interval '1 day 3 hours', 15.00),
-(2, 2, CURRENT_TIMESTAMP + interval '1 day', CURRENT_TIMESTAMP + interval '1 day 3 hours', 18.00),
-(3, 3, CURRENT_TIMESTAMP + interval '1 day', CURRENT_TIMESTAMP + interval '1 day 3 hours', 20.00);
-
--- Insert sample user
-INSERT INTO users (email, password, name, phone) VALUES
-('user@example.com', '$2a$10$3QxDjD1ylgPnRgQLhBrTaeGxZHhN9.DpBTPv6L9pB.HxQv2RHllMa', 'Test User', '1234567890');
-
 -- Add triggers for updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
@@ -0,0 +1,4 @@
+-- Track pending email changes awaiting verification
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(255);
+ALTER TABLE users ADD COLUMN email_change_token VARCHAR(64);
+ALTER TABLE users ADD COLUMN email_change_expires_at TIMESTAMP WITH TIME ZONE;
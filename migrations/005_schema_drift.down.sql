@@ -0,0 +1,27 @@
+DROP INDEX IF EXISTS idx_booking_seats_booking_id;
+DROP INDEX IF EXISTS idx_bookings_user_id;
+DROP INDEX IF EXISTS idx_seats_show_time_id;
+
+ALTER TABLE bookings DROP CONSTRAINT bookings_user_id_fkey;
+ALTER TABLE bookings ADD CONSTRAINT bookings_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE bookings ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE tickets DROP CONSTRAINT tickets_user_id_fkey;
+ALTER TABLE tickets ADD CONSTRAINT tickets_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE tickets ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE booking_seats ALTER COLUMN seat_id DROP NOT NULL;
+ALTER TABLE booking_seats ALTER COLUMN booking_id DROP NOT NULL;
+ALTER TABLE bookings ALTER COLUMN show_time_id DROP NOT NULL;
+ALTER TABLE tickets ALTER COLUMN seat_id DROP NOT NULL;
+ALTER TABLE tickets ALTER COLUMN show_time_id DROP NOT NULL;
+ALTER TABLE seats ALTER COLUMN show_time_id DROP NOT NULL;
+ALTER TABLE seats ALTER COLUMN hall_id DROP NOT NULL;
+ALTER TABLE show_times ALTER COLUMN hall_id DROP NOT NULL;
+ALTER TABLE show_times ALTER COLUMN movie_id DROP NOT NULL;
+
+ALTER TABLE seats DROP CONSTRAINT seats_show_time_id_row_number_seat_number_key;
+ALTER TABLE seats ADD CONSTRAINT seats_hall_id_row_number_seat_number_key
+    UNIQUE (hall_id, row_number, seat_number);
@@ -0,0 +1,35 @@
+-- Seats are copied for every showtime, so a seat position is unique per
+-- showtime rather than per hall
+ALTER TABLE seats DROP CONSTRAINT seats_hall_id_row_number_seat_number_key;
+ALTER TABLE seats ADD CONSTRAINT seats_show_time_id_row_number_seat_number_key
+    UNIQUE (show_time_id, row_number, seat_number);
+
+-- Seats without a showtime cannot be booked and would violate the constraint below
+DELETE FROM seats WHERE show_time_id IS NULL;
+
+-- References required by the application models
+ALTER TABLE show_times ALTER COLUMN movie_id SET NOT NULL;
+ALTER TABLE show_times ALTER COLUMN hall_id SET NOT NULL;
+ALTER TABLE seats ALTER COLUMN hall_id SET NOT NULL;
+ALTER TABLE seats ALTER COLUMN show_time_id SET NOT NULL;
+ALTER TABLE tickets ALTER COLUMN show_time_id SET NOT NULL;
+ALTER TABLE tickets ALTER COLUMN seat_id SET NOT NULL;
+ALTER TABLE bookings ALTER COLUMN show_time_id SET NOT NULL;
+ALTER TABLE booking_seats ALTER COLUMN booking_id SET NOT NULL;
+ALTER TABLE booking_seats ALTER COLUMN seat_id SET NOT NULL;
+
+-- Users are anonymised and soft deleted, never removed, so their bookings and
+-- tickets always keep an owner
+ALTER TABLE tickets ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE tickets DROP CONSTRAINT tickets_user_id_fkey;
+ALTER TABLE tickets ADD CONSTRAINT tickets_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE bookings ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE bookings DROP CONSTRAINT bookings_user_id_fkey;
+ALTER TABLE bookings ADD CONSTRAINT bookings_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+CREATE INDEX idx_seats_show_time_id ON seats(show_time_id);
+CREATE INDEX idx_bookings_user_id ON bookings(user_id);
+CREATE INDEX idx_booking_seats_booking_id ON booking_seats(booking_id);
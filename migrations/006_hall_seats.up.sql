@@ -0,0 +1,17 @@
+-- Create hall_seats table holding the seat layout of each hall. Seats are
+-- copied from the layout when a showtime is scheduled.
+CREATE TABLE hall_seats (
+    id SERIAL PRIMARY KEY,
+    hall_id INTEGER NOT NULL REFERENCES halls(id) ON DELETE CASCADE,
+    row_number VARCHAR(2) NOT NULL,
+    seat_number INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(hall_id, row_number, seat_number)
+);
+
+CREATE TRIGGER update_hall_seats_updated_at
+    BEFORE UPDATE ON hall_seats
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
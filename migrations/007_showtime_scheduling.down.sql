@@ -0,0 +1,4 @@
+ALTER TABLE show_times DROP CONSTRAINT IF EXISTS show_times_no_overlap;
+DROP INDEX IF EXISTS idx_show_times_hall_id;
+ALTER TABLE show_times DROP COLUMN IF EXISTS status;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,13 @@
+-- Roles allow staff to manage the programme
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'CUSTOMER';
+
+-- Cancelled showtimes are kept for bookings and reporting
+ALTER TABLE show_times ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'SCHEDULED';
+CREATE INDEX idx_show_times_hall_id ON show_times(hall_id);
+
+-- Backstop for the application's overlap check: two scheduled showtimes in
+-- the same hall may not overlap. end_time already includes the cleaning buffer.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+ALTER TABLE show_times ADD CONSTRAINT show_times_no_overlap
+    EXCLUDE USING gist (hall_id WITH =, tstzrange(start_time, end_time) WITH &&)
+    WHERE (status = 'SCHEDULED' AND deleted_at IS NULL);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_show_times_schedule_id;
+ALTER TABLE show_times DROP COLUMN IF EXISTS schedule_id;
+DROP TABLE IF EXISTS showtime_schedules;
@@ -0,0 +1,24 @@
+-- Create showtime_schedules table holding recurring programme rules
+CREATE TABLE showtime_schedules (
+    id SERIAL PRIMARY KEY,
+    movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    hall_id INTEGER NOT NULL REFERENCES halls(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    weekdays VARCHAR(27) NOT NULL,
+    times VARCHAR(255) NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Showtimes generated from a schedule belong to its series
+ALTER TABLE show_times ADD COLUMN schedule_id INTEGER REFERENCES showtime_schedules(id) ON DELETE SET NULL;
+CREATE INDEX idx_show_times_schedule_id ON show_times(schedule_id);
+
+CREATE TRIGGER update_showtime_schedules_updated_at
+    BEFORE UPDATE ON showtime_schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_bookings_show_time_id_status;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS payments;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancel_reason;
+ALTER TABLE show_times DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE show_times DROP COLUMN IF EXISTS cancellation_reason;
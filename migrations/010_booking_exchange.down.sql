@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bookings_exchanged_from_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS exchanged_from_id;
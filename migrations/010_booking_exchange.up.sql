@@ -0,0 +1,3 @@
+-- Link exchanged bookings to the booking they replaced; a booking can be exchanged once
+ALTER TABLE bookings ADD COLUMN exchanged_from_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX idx_bookings_exchanged_from_id ON bookings(exchanged_from_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_showtime_seat;
+DELETE FROM tickets WHERE status = 'cancelled' OR deleted_at IS NOT NULL;
+ALTER TABLE tickets ADD CONSTRAINT tickets_show_time_id_seat_id_key UNIQUE (show_time_id, seat_id);
+DROP INDEX IF EXISTS idx_tickets_booking_id;
+ALTER TABLE tickets DROP COLUMN IF EXISTS booking_id;
@@ -0,0 +1,8 @@
+-- Tickets are issued per booked seat and reissued when a booking changes
+ALTER TABLE tickets ADD COLUMN booking_id INTEGER REFERENCES bookings(id) ON DELETE CASCADE;
+CREATE INDEX idx_tickets_booking_id ON tickets(booking_id);
+
+-- A seat may have any number of cancelled tickets but only one valid ticket
+ALTER TABLE tickets DROP CONSTRAINT tickets_show_time_id_seat_id_key;
+CREATE UNIQUE INDEX idx_showtime_seat ON tickets(show_time_id, seat_id)
+    WHERE status <> 'cancelled' AND deleted_at IS NULL;
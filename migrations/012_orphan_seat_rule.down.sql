@@ -0,0 +1 @@
+ALTER TABLE halls DROP COLUMN IF EXISTS allow_orphan_seats;
@@ -0,0 +1,2 @@
+-- Halls can opt out of the rule against leaving single empty seats
+ALTER TABLE halls ADD COLUMN allow_orphan_seats BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE seats DROP COLUMN IF EXISTS kind;
+ALTER TABLE hall_seats DROP COLUMN IF EXISTS kind;
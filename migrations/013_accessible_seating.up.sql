@@ -0,0 +1,3 @@
+-- Wheelchair spaces and companion seats are reserved for customers who need them
+ALTER TABLE hall_seats ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'STANDARD';
+ALTER TABLE seats ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'STANDARD';
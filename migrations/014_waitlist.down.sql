@@ -0,0 +1,2 @@
+ALTER TABLE seats DROP COLUMN IF EXISTS held_for_id;
+DROP TABLE IF EXISTS waitlist_entries;
@@ -0,0 +1,30 @@
+-- Customers waiting for seats of sold-out showtimes, served first come first served
+CREATE TABLE waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    show_time_id INTEGER NOT NULL REFERENCES show_times(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seat_count INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'WAITING',
+    offered_at TIMESTAMP WITH TIME ZONE,
+    offer_expires_at TIMESTAMP WITH TIME ZONE,
+    booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_waitlist_entries_show_time_id_status ON waitlist_entries(show_time_id, status);
+CREATE INDEX idx_waitlist_entries_offer_expires_at ON waitlist_entries(offer_expires_at);
+
+-- A customer waits at most once per showtime
+CREATE UNIQUE INDEX idx_waitlist_active ON waitlist_entries(show_time_id, user_id)
+    WHERE status = 'WAITING' OR status = 'OFFERED';
+
+-- Seats offered to a waitlisted customer are RESERVED for their entry
+ALTER TABLE seats ADD COLUMN held_for_id INTEGER REFERENCES waitlist_entries(id) ON DELETE SET NULL;
+CREATE INDEX idx_seats_held_for_id ON seats(held_for_id);
+
+CREATE TRIGGER update_waitlist_entries_updated_at
+    BEFORE UPDATE ON waitlist_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
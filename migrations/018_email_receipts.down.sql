@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_attachments;
+ALTER TABLE notifications DROP COLUMN IF EXISTS html_body;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
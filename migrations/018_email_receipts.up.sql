@@ -0,0 +1,25 @@
+-- Language customer emails are written in
+ALTER TABLE users ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT 'en';
+
+-- HTML version of queued emails
+ALTER TABLE notifications ADD COLUMN html_body TEXT NOT NULL DEFAULT '';
+
+-- Files sent with queued emails, such as QR codes and calendar invites
+CREATE TABLE notification_attachments (
+    id SERIAL PRIMARY KEY,
+    notification_id INTEGER NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    content_id VARCHAR(100),
+    content BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_notification_attachments_notification_id ON notification_attachments(notification_id);
+
+CREATE TRIGGER update_notification_attachments_updated_at
+    BEFORE UPDATE ON notification_attachments
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,37 @@
+-- Last number issued in every invoice series, such as INV-2026, so that
+-- invoice numbers are sequential without gaps
+CREATE TABLE invoice_sequences (
+    series VARCHAR(30) PRIMARY KEY,
+    last_number INTEGER NOT NULL DEFAULT 0
+);
+
+-- VAT invoices for charges and credit notes for refunds
+CREATE TABLE invoices (
+    id SERIAL PRIMARY KEY,
+    number VARCHAR(40) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id),
+    payment_id INTEGER NOT NULL REFERENCES payments(id),
+    corrects_id INTEGER REFERENCES invoices(id),
+    buyer_name VARCHAR(255) NOT NULL,
+    buyer_email VARCHAR(255) NOT NULL,
+    lines TEXT NOT NULL,
+    net DECIMAL(10,2) NOT NULL,
+    tax DECIMAL(10,2) NOT NULL,
+    gross DECIMAL(10,2) NOT NULL,
+    tax_percent DECIMAL(5,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_invoices_number ON invoices(number);
+CREATE UNIQUE INDEX idx_invoices_payment_id ON invoices(payment_id);
+CREATE INDEX idx_invoices_booking_id ON invoices(booking_id);
+
+CREATE TRIGGER update_invoices_updated_at
+    BEFORE UPDATE ON invoices
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
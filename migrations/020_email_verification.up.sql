@@ -0,0 +1,13 @@
+-- When the user proved to own their email address. Social logins are only
+-- linked by email to accounts with a verified address.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Addresses confirmed by an identity provider count as verified
+UPDATE users SET email_verified_at = CURRENT_TIMESTAMP
+WHERE EXISTS (
+    SELECT 1 FROM user_identities
+    WHERE user_identities.user_id = users.id AND user_identities.email = users.email
+);
+
+-- Logged-in user a pending social login links the provider account to
+ALTER TABLE oidc_login_states ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+-- Social-only accounts keep an empty password, which never matches on login
+SELECT 1;
@@ -0,0 +1,13 @@
+-- Accounts created by a social login have no password. They were given an
+-- unusable random one, recognisable by the identity created together with
+-- the user and by never having logged in with a password.
+UPDATE users SET password = ''
+WHERE EXISTS (
+    SELECT 1 FROM user_identities
+    WHERE user_identities.user_id = users.id
+      AND user_identities.created_at - users.created_at < INTERVAL '1 second'
+)
+AND NOT EXISTS (
+    SELECT 1 FROM login_events
+    WHERE login_events.user_id = users.id AND login_events.method = 'password'
+);
@@ -0,0 +1,8 @@
+-- Failed events are retried with backoff and set aside after too many attempts
+ALTER TABLE outbox_events ADD COLUMN next_attempt_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE outbox_events ADD COLUMN dead_at TIMESTAMP WITH TIME ZONE;
+
+DROP INDEX idx_outbox_events_unpublished;
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL AND dead_at IS NULL;
+CREATE INDEX idx_outbox_events_pending_aggregate ON outbox_events(aggregate_type, aggregate_id, id)
+    WHERE published_at IS NULL AND dead_at IS NULL;
@@ -0,0 +1,10 @@
+-- Sinks every event has been published to, so that an event failing in one
+-- sink is only published again to the sinks that haven't received it
+CREATE TABLE outbox_deliveries (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    sink VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_outbox_deliveries_event_sink ON outbox_deliveries(event_id, sink);
@@ -0,0 +1,8 @@
+// Package migrations embeds the versioned SQL migrations. Every version has an
+// NNN_name.up.sql file and a matching NNN_name.down.sql file.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS